@@ -1,15 +1,22 @@
 package app
 
 import (
+	"fmt"
 	"github.com/gin-gonic/gin"
+	"github.com/ilooky/go-layout/pkg/auth"
 	"github.com/ilooky/go-layout/pkg/config"
 	"github.com/ilooky/go-layout/pkg/database"
+	"github.com/ilooky/go-layout/pkg/errno"
+	"github.com/ilooky/go-layout/pkg/guava"
+	"github.com/ilooky/go-layout/pkg/guava/conv"
+	"github.com/ilooky/go-layout/pkg/guava/json"
+	"github.com/ilooky/go-layout/pkg/middleware"
+	"github.com/ilooky/go-layout/pkg/tenant"
 	"github.com/ilooky/logger"
 	"go.uber.org/zap"
 	"net/http"
 	"os"
 	"os/signal"
-	"strconv"
 	"syscall"
 	"time"
 )
@@ -21,18 +28,25 @@ type app struct {
 	port   int
 }
 
-func newApp(conf *config.Config, api func(ctx *gin.Engine)) *app {
-	port, _ := strconv.Atoi(conf.Port)
+func newApp(conf *config.Config, api func(ctx *gin.Engine), handlers ...gin.HandlerFunc) *app {
+	port := conv.ToIntOr(conf.Port, 0)
 	if conf.Log.Release {
 		gin.SetMode("release")
 	}
+	errno.ShowStacks = !conf.Log.Release
+	database.ExplainSlow = conf.Mysql.Explain && !conf.Log.Release
+	if conf.Lang != "" {
+		errno.DefaultLang = conf.Lang
+	}
 	h := gin.New()
 	h.RemoveExtraSlash = true
 	h.RedirectFixedPath = true
-	h.Use(gin.Recovery(), logMiddleware())
+	h.Use(middleware.RequestID(), logMiddleware(), middleware.ErrorHandler())
+	// 健康检查在认证、租户等中间件之前注册，不受其影响，避免被 Consul 摘除
 	h.GET("/health", func(ctx *gin.Context) {
 		ctx.JSON(http.StatusOK, "SUCCESS")
 	})
+	h.Use(handlers...)
 	api(h)
 	a := app{
 		server: &http.Server{
@@ -80,13 +94,39 @@ func Run(serverName string, server func(g *gin.Engine), inits ...func(cnf *confi
 		Path:  conf.Log.Path,
 	})
 	logger.InfoKV("Read Config", conf.Name, conf)
+	if conf.Json != (config.Json{}) {
+		c := conf.Json
+		codec, ok := json.New(guava.GetStr(c.Codec, json.Current().Name()), json.Options{
+			UseNumber:             c.UseNumber,
+			DisallowUnknownFields: c.DisallowUnknownFields,
+			CaseSensitive:         c.CaseSensitive,
+		})
+		if !ok {
+			return fmt.Errorf("unknown json codec %q", c.Codec)
+		}
+		middleware.UseCodec(codec)
+	}
 	if db, err := database.InitOrm(conf.Mysql); err != nil {
 		logger.Panic(err)
 		return err
 	} else {
 		defer db.Close()
 	}
-	app := newApp(conf, server)
+	var handlers []gin.HandlerFunc
+	if conf.Auth.Enable {
+		a, err := auth.Init(conf.Auth)
+		if err != nil {
+			return err
+		}
+		defer a.Close()
+		handlers = append(handlers, a.Middleware())
+	}
+	if conf.Tenant.Enable {
+		pool := tenant.Init(conf)
+		defer pool.Close()
+		handlers = append(handlers, tenant.Middleware(conf.Tenant))
+	}
+	app := newApp(conf, server, handlers...)
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
 	app.start()
@@ -121,11 +161,13 @@ func logMiddleware() gin.HandlerFunc {
 				StatusCode int
 				ERROR      string
 				Latency    time.Duration
+				RequestID  string
 			}{
 				Method:     method,
 				StatusCode: statusCode,
 				ERROR:      errMsg,
 				Latency:    time.Now().Sub(start),
+				RequestID:  middleware.GetRequestID(c),
 			}
 			logger.InfoKv("Request", zap.Any(path, msg))
 		}
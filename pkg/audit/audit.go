@@ -0,0 +1,316 @@
+// Package audit 通过 database 的变更钩子记录实体变更历史：谁、在哪个请求中、
+// 对哪个实体做了什么修改，记录写入 audit 表或发送到 MQ。
+package audit
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ilooky/go-layout/pkg/auth"
+	"github.com/ilooky/go-layout/pkg/database"
+	"github.com/ilooky/go-layout/pkg/guava"
+	"github.com/ilooky/go-layout/pkg/guava/json"
+	"github.com/ilooky/go-layout/pkg/middleware"
+	"github.com/ilooky/go-layout/pkg/mq"
+	"github.com/ilooky/logger"
+	"gopkg.in/yaml.v2"
+)
+
+// Ignore 比较差异时忽略的字段（json 名）
+var Ignore = map[string]bool{"created": true, "updated": true}
+
+type FieldChange struct {
+	Field  string      `json:"field"`
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+type Record struct {
+	Id         int64         `json:"id"`
+	EntityType string        `json:"entityType" xorm:"varchar(64) index(idx_audit_entity)"`
+	EntityId   string        `json:"entityId"   xorm:"varchar(64) index(idx_audit_entity)"`
+	Op         string        `json:"op"         xorm:"varchar(16)"`
+	Changes    []FieldChange `json:"changes"    xorm:"json"`
+	Actor      string        `json:"actor"      xorm:"varchar(128)"`
+	RequestId  string        `json:"requestId"  xorm:"varchar(64)"`
+	Created    time.Time     `json:"created"    xorm:"created"`
+}
+
+// Sink 审计记录的写入目标
+type Sink interface {
+	Write(ctx context.Context, r *Record) error
+}
+
+// DbSink 写入 ctx 对应数据库（多租户时为租户库）的 audit 表，需先调用 Sync 建表
+type DbSink struct{}
+
+func (DbSink) Write(ctx context.Context, r *Record) error {
+	// 直接使用 engine 写入，避免再次触发变更钩子
+	db, err := database.Engine(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = db.Context(ctx).InsertOne(r)
+	return err
+}
+
+// MqSink 以 JSON 发送到 MQ，由独立的审计服务持久化
+type MqSink struct {
+	Broker     mq.Broker
+	Exchange   string
+	RoutingKey string
+}
+
+func (s MqSink) Write(ctx context.Context, r *Record) error {
+	body, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	return s.Broker.Publish(ctx, s.Exchange, s.RoutingKey, mq.Message{
+		ContentType: "application/json",
+		Body:        body,
+		Timestamp:   r.Created,
+	})
+}
+
+// Sync 在 ctx 对应的数据库中创建或同步 audit 表
+func Sync(ctx context.Context) error {
+	return database.SyncTableContext(ctx, new(Record))
+}
+
+type actorKey struct{}
+
+// WithActor 为没有认证信息的后台任务指定操作者
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+func actor(ctx context.Context) string {
+	if claims, ok := auth.FromContext(ctx); ok && claims.Subject != "" {
+		return claims.Subject
+	}
+	a, _ := ctx.Value(actorKey{}).(string)
+	return a
+}
+
+// Enable 注册 database 变更钩子，之后通过 database 的 Save/Update/Delete 系列函数
+// 产生的变更都会写入 sink；写入失败只记录日志，不影响业务操作
+func Enable(sink Sink) {
+	database.AddHook(func(ctx context.Context, c database.Change) {
+		r := NewRecord(ctx, c)
+		if r == nil {
+			return
+		}
+		if err := sink.Write(ctx, r); err != nil {
+			logger.Errorf("audit: write %s %s#%s: %v", r.Op, r.EntityType, r.EntityId, err)
+		}
+	})
+}
+
+// NewRecord 由变更构造审计记录，update 没有字段变化时返回 nil
+func NewRecord(ctx context.Context, c database.Change) *Record {
+	if _, ok := c.After.(*Record); ok {
+		return nil
+	}
+	changes := Diff(c.Before, c.After)
+	if c.Op == database.OpUpdate && len(changes) == 0 {
+		return nil
+	}
+	id := ""
+	if c.Id != nil {
+		id = fmt.Sprint(c.Id)
+	}
+	return &Record{
+		EntityType: c.Table,
+		EntityId:   id,
+		Op:         string(c.Op),
+		Changes:    changes,
+		Actor:      actor(ctx),
+		RequestId:  middleware.RequestIDFrom(ctx),
+		Created:    time.Now(),
+	}
+}
+
+// Diff 按 json 字段比较两个实体，任意一方为 nil 时列出另一方的全部字段；
+// 与 ConfigChange 一样按 Secrets 以 ****** 代替敏感字段的值
+func Diff(before, after interface{}) []FieldChange {
+	b, a := fields(before), fields(after)
+	keys := make([]string, 0, len(a)+len(b))
+	for k := range b {
+		keys = append(keys, k)
+	}
+	for k := range a {
+		if _, ok := b[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	var changes []FieldChange
+	for _, k := range keys {
+		if Ignore[k] || reflect.DeepEqual(b[k], a[k]) {
+			continue
+		}
+		secret := isSecret(k)
+		changes = append(changes, FieldChange{Field: k, Before: redact(b[k], secret), After: redact(a[k], secret)})
+	}
+	return changes
+}
+
+func fields(v interface{}) map[string]interface{} {
+	m := map[string]interface{}{}
+	if v == nil {
+		return m
+	}
+	data, err := json.Marshal(v)
+	if err == nil {
+		err = json.Unmarshal(data, &m)
+	}
+	if err != nil {
+		logger.Errorf("audit: diff %T: %v", v, err)
+	}
+	return m
+}
+
+// ConfigEntity 配置变更记录的 EntityType
+const ConfigEntity = "config"
+
+// Secrets 实体和配置中的敏感键（忽略大小写、- 和 _），其变更值记录为 ******
+var Secrets = []string{"password", "secret", "privatekey", "token"}
+
+const masked = "******"
+
+// ConfigChange 记录配置的变更，before、after 为配置结构体或 JSON 文档（[]byte、json.RawMessage）；
+// 结构体按 yaml 键转换，变化的字段按 JSON Pointer 路径列出（如 /mysql/max-open），
+// 敏感字段的值以 ****** 代替，没有变化时不写入
+func ConfigChange(ctx context.Context, sink Sink, name string, before, after interface{}) error {
+	old, err := configDoc(before)
+	if err != nil {
+		return err
+	}
+	cur, err := configDoc(after)
+	if err != nil {
+		return err
+	}
+	patch, err := json.DiffValues(old, cur)
+	if err != nil || len(patch) == 0 {
+		return err
+	}
+	changes := make([]FieldChange, 0, len(patch))
+	for _, op := range patch {
+		c := FieldChange{Field: op.Path}
+		path, _ := json.ParsePointer(op.Path)
+		if op.Op != "add" {
+			c.Before, _ = path.Get(old)
+		}
+		if op.Op != "remove" {
+			c.After, _ = json.Get(op.Value, "")
+		}
+		secret := false
+		for _, key := range path {
+			secret = secret || isSecret(key)
+		}
+		c.Before, c.After = redact(c.Before, secret), redact(c.After, secret)
+		changes = append(changes, c)
+	}
+	return sink.Write(ctx, &Record{
+		EntityType: ConfigEntity,
+		EntityId:   name,
+		Op:         string(database.OpUpdate),
+		Changes:    changes,
+		Actor:      actor(ctx),
+		RequestId:  middleware.RequestIDFrom(ctx),
+		Created:    time.Now(),
+	})
+}
+
+// configDoc 将配置转换为 JSON 通用结构，结构体经 yaml 序列化，键与配置文件一致
+func configDoc(v interface{}) (interface{}, error) {
+	var data []byte
+	switch d := v.(type) {
+	case nil:
+		return nil, nil
+	case []byte:
+		data = d
+	case json.RawMessage:
+		data = d
+	default:
+		y, err := yaml.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		var doc interface{}
+		if err = yaml.Unmarshal(y, &doc); err != nil {
+			return nil, err
+		}
+		if data, err = json.Marshal(stringKeys(doc)); err != nil {
+			return nil, err
+		}
+	}
+	return json.Get(data, "")
+}
+
+// stringKeys 将 yaml 解码得到的 map[interface{}]interface{} 转换为 map[string]interface{}
+func stringKeys(v interface{}) interface{} {
+	switch n := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(n))
+		for k, e := range n {
+			m[fmt.Sprint(k)] = stringKeys(e)
+		}
+		return m
+	case []interface{}:
+		for i, e := range n {
+			n[i] = stringKeys(e)
+		}
+	}
+	return v
+}
+
+func isSecret(key string) bool {
+	key = strings.NewReplacer("-", "", "_", "").Replace(strings.ToLower(key))
+	for _, s := range Secrets {
+		if strings.Contains(key, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// redact secret 为 true 时隐藏整个值，否则隐藏对象中的敏感键
+func redact(v interface{}, secret bool) interface{} {
+	if v == nil || v == "" {
+		return v
+	}
+	if secret {
+		return masked
+	}
+	switch n := v.(type) {
+	case map[string]interface{}:
+		for k, e := range n {
+			n[k] = redact(e, isSecret(k))
+		}
+	case []interface{}:
+		for i, e := range n {
+			n[i] = redact(e, false)
+		}
+	}
+	return v
+}
+
+// History 按时间倒序查询实体的变更历史，bean 用于确定表名
+func History(ctx context.Context, bean interface{}, id interface{}, p guava.Paged) ([]Record, error) {
+	db, err := database.Engine(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var list []Record
+	session := db.Context(ctx).
+		Where("entity_type=? and entity_id=?", db.TableName(bean), fmt.Sprint(id)).
+		Desc("id")
+	session.Limit(p.Lim(), p.Offset())
+	return list, session.Find(&list)
+}
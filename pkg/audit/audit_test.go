@@ -0,0 +1,121 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/ilooky/go-layout/pkg/auth"
+	"github.com/ilooky/go-layout/pkg/config"
+	"github.com/ilooky/go-layout/pkg/database"
+	"github.com/ilooky/go-layout/pkg/guava/json"
+	"github.com/ilooky/go-layout/pkg/middleware"
+)
+
+type station struct {
+	Id      int64  `json:"id"`
+	Name    string `json:"name"`
+	Code    string `json:"code"`
+	Updated string `json:"updated"`
+}
+
+func TestDiff(t *testing.T) {
+	before := &station{Id: 1, Name: "a", Code: "ST1", Updated: "t1"}
+	after := &station{Id: 1, Name: "b", Code: "ST1", Updated: "t2"}
+	changes := Diff(before, after)
+	if len(changes) != 1 || changes[0].Field != "name" || changes[0].Before != "a" || changes[0].After != "b" {
+		t.Errorf("update diff = %+v", changes)
+	}
+	if changes = Diff(nil, after); len(changes) != 3 {
+		t.Errorf("insert diff = %+v", changes)
+	}
+	if changes = Diff(before, before); len(changes) != 0 {
+		t.Errorf("unchanged diff = %+v", changes)
+	}
+
+	type user struct {
+		Name         string            `json:"name"`
+		PasswordHash string            `json:"passwordHash"`
+		Extra        map[string]string `json:"extra"`
+	}
+	changes = Diff(&user{Name: "a", PasswordHash: "h1"}, &user{Name: "a", PasswordHash: "h2", Extra: map[string]string{"api_token": "t"}})
+	if len(changes) != 2 || changes[0].Field != "extra" || changes[1].Field != "passwordHash" {
+		t.Fatalf("secret diff = %+v", changes)
+	}
+	if extra := changes[0].After.(map[string]interface{}); extra["api_token"] != masked {
+		t.Errorf("nested secret = %+v", extra)
+	}
+	if changes[1].Before != masked || changes[1].After != masked {
+		t.Errorf("password diff = %+v", changes[1])
+	}
+}
+
+func TestNewRecord(t *testing.T) {
+	ctx := middleware.WithRequestID(context.Background(), "req-1")
+	ctx = auth.WithClaims(ctx, &auth.Claims{StandardClaims: jwt.StandardClaims{Subject: "u1"}})
+	ctx = WithActor(ctx, "job")
+	r := NewRecord(ctx, database.Change{Op: database.OpInsert, Table: "us_station", Id: int64(1), After: &station{Id: 1}})
+	if r == nil || r.EntityId != "1" || r.RequestId != "req-1" || r.Op != "insert" {
+		t.Fatalf("record = %+v", r)
+	}
+	if r.Actor != "u1" {
+		t.Errorf("claims should take precedence, actor = %q", r.Actor)
+	}
+	if r = NewRecord(WithActor(context.Background(), "job"), database.Change{Op: database.OpDelete, Id: 1, Before: &station{}}); r.Actor != "job" {
+		t.Errorf("actor = %q", r.Actor)
+	}
+	same := &station{Id: 1, Updated: "t1"}
+	if r = NewRecord(ctx, database.Change{Op: database.OpUpdate, Id: 1, Before: same, After: &station{Id: 1, Updated: "t2"}}); r != nil {
+		t.Errorf("update without changes recorded: %+v", r)
+	}
+}
+
+type memSink []*Record
+
+func (s *memSink) Write(_ context.Context, r *Record) error {
+	*s = append(*s, r)
+	return nil
+}
+
+func TestConfigChange(t *testing.T) {
+	var before config.Config
+	before.Mysql.MaxOpen, before.Mysql.Password, before.Tag = 10, "old", []string{"line"}
+	after := before
+	after.Mysql.MaxOpen, after.Mysql.Password, after.Tag = 20, "new", []string{"line", "global"}
+	after.Auth.Secret = "jwt"
+	var sink memSink
+	ctx := WithActor(context.Background(), "ops")
+	if err := ConfigChange(ctx, &sink, "us-diagram", before, after); err != nil {
+		t.Fatal(err)
+	}
+	if len(sink) != 1 || sink[0].EntityType != ConfigEntity || sink[0].EntityId != "us-diagram" || sink[0].Actor != "ops" {
+		t.Fatalf("records = %+v", sink)
+	}
+	got := map[string]string{}
+	for _, c := range sink[0].Changes {
+		got[c.Field] = fmt.Sprintf("%v -> %v", c.Before, c.After)
+	}
+	want := map[string]string{
+		"/auth/secret":    " -> ******",
+		"/mysql/max-open": "10 -> 20",
+		"/mysql/password": "****** -> ******",
+		"/tag/-":          "<nil> -> global",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("changes = %v, want %v", got, want)
+	}
+	if err := ConfigChange(ctx, &sink, "us-diagram", after, after); err != nil || len(sink) != 1 {
+		t.Errorf("unchanged config recorded: %d records, %v", len(sink), err)
+	}
+
+	sink = nil
+	if err := ConfigChange(ctx, &sink, "flags", []byte(`{"a":1,"db":{"password":"x"}}`), json.RawMessage(`{"a":2,"db":{"password":"y"}}`)); err != nil {
+		t.Fatal(err)
+	}
+	changes := sink[0].Changes
+	if len(changes) != 2 || changes[0].Field != "/a" || changes[1].Field != "/db/password" || changes[1].After != "******" {
+		t.Errorf("document changes = %+v", changes)
+	}
+}
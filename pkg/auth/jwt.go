@@ -0,0 +1,197 @@
+// Package auth 提供 JWT 认证中间件和服务间调用的 token 签发
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/ilooky/go-layout/pkg/config"
+	"github.com/ilooky/go-layout/pkg/errno"
+	"github.com/ilooky/go-layout/pkg/middleware"
+)
+
+// ClaimsKey 认证通过后 claims 在 gin.Context 中的键
+const ClaimsKey = "claims"
+
+type ctxKey struct{}
+
+type Claims struct {
+	jwt.StandardClaims
+	Roles  []string `json:"roles,omitempty"`
+	Tenant string   `json:"tenant,omitempty"`
+}
+
+// Default 由 Init 初始化，供签发服务间调用的 token 使用
+var Default *Authenticator
+
+// Init 创建 Default，配置了 Roles 时同时创建 DefaultPolicy
+func Init(c config.Auth) (*Authenticator, error) {
+	a, err := New(c)
+	if err != nil {
+		return nil, err
+	}
+	Default = a
+	if len(c.Roles) > 0 {
+		DefaultPolicy = NewPolicy(ConfigRoles(c.Roles), time.Minute)
+	}
+	return a, nil
+}
+
+type Authenticator struct {
+	conf   config.Auth
+	keys   KeySource
+	signer interface{}
+	parser *jwt.Parser
+}
+
+// New 根据配置选择密钥来源：Jwks 文件、Consul KV 或配置中的静态密钥
+func New(c config.Auth) (*Authenticator, error) {
+	var keys KeySource
+	var err error
+	switch {
+	case c.Jwks != "":
+		keys, err = FileKeys(c.Jwks, c.Refresh)
+	case c.ConsulKey != "":
+		if config.Client == nil {
+			return nil, errors.New("auth: consul client is not initialized")
+		}
+		keys, err = ConsulKeys(config.Client, c.ConsulKey)
+	default:
+		keys, err = StaticKey(c)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return NewWithKeys(c, keys)
+}
+
+// NewWithKeys 使用自定义的密钥来源
+func NewWithKeys(c config.Auth, keys KeySource) (*Authenticator, error) {
+	a := &Authenticator{
+		conf:   c,
+		keys:   keys,
+		parser: &jwt.Parser{ValidMethods: []string{c.Alg}},
+	}
+	var err error
+	switch {
+	case c.Alg == "HS256" && c.Secret != "":
+		a.signer = []byte(c.Secret)
+	case c.Alg == "RS256" && c.PrivateKey != "":
+		a.signer, err = jwt.ParseRSAPrivateKeyFromPEM([]byte(c.PrivateKey))
+	case c.Alg == "ES256" && c.PrivateKey != "":
+		a.signer, err = jwt.ParseECPrivateKeyFromPEM([]byte(c.PrivateKey))
+	}
+	if err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// Close 停止密钥来源的后台重新加载
+func (a *Authenticator) Close() error {
+	if c, ok := a.keys.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// Verify 校验 token 签名、有效期以及配置的 issuer 和 audience，没有 exp 的 token 视为无效
+func (a *Authenticator) Verify(token string) (*Claims, error) {
+	claims := &Claims{}
+	_, err := a.parser.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		return a.keys.Key(kid, t.Method.Alg())
+	})
+	if err != nil {
+		return nil, err
+	}
+	// jwt 的 StandardClaims.Valid 在 exp 为 0 时不检查过期
+	if claims.ExpiresAt == 0 {
+		return nil, errors.New("auth: token has no expiration")
+	}
+	if a.conf.Issuer != "" && !claims.VerifyIssuer(a.conf.Issuer, true) {
+		return nil, fmt.Errorf("auth: unexpected issuer %s", claims.Issuer)
+	}
+	if a.conf.Audience != "" && !claims.VerifyAudience(a.conf.Audience, true) {
+		return nil, fmt.Errorf("auth: unexpected audience %s", claims.Audience)
+	}
+	return claims, nil
+}
+
+// Issue 签发服务间调用使用的 token，需要配置 Secret 或 PrivateKey
+func (a *Authenticator) Issue(subject string, roles ...string) (string, error) {
+	now := time.Now()
+	claims := Claims{
+		StandardClaims: jwt.StandardClaims{
+			Subject:   subject,
+			Issuer:    a.conf.Issuer,
+			Audience:  a.conf.Audience,
+			IssuedAt:  now.Unix(),
+			NotBefore: now.Unix(),
+			ExpiresAt: now.Add(a.conf.Expire).Unix(),
+		},
+		Roles: roles,
+	}
+	return a.Sign(claims)
+}
+
+// Sign 使用配置的密钥签名任意 claims
+func (a *Authenticator) Sign(claims Claims) (string, error) {
+	if a.signer == nil {
+		return "", errors.New("auth: no signing key configured")
+	}
+	return jwt.NewWithClaims(jwt.GetSigningMethod(a.conf.Alg), claims).SignedString(a.signer)
+}
+
+// Middleware 校验 Authorization: Bearer 头，Skip 中的路径前缀不校验；
+// 认证通过后 claims 同时写入 gin.Context 和 request context
+func (a *Authenticator) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		path := c.Request.URL.Path
+		for _, prefix := range a.conf.Skip {
+			if path == prefix || strings.HasPrefix(path, strings.TrimSuffix(prefix, "/")+"/") {
+				c.Next()
+				return
+			}
+		}
+		header := c.GetHeader("Authorization")
+		if !strings.HasPrefix(header, "Bearer ") {
+			middleware.Abort(c, errno.Unauthorized.Errorf("missing bearer token"))
+			return
+		}
+		claims, err := a.Verify(strings.TrimPrefix(header, "Bearer "))
+		if err != nil {
+			middleware.Abort(c, errno.TokenInvalid.Wrap(err))
+			return
+		}
+		c.Set(ClaimsKey, claims)
+		c.Request = c.Request.WithContext(WithClaims(c.Request.Context(), claims))
+		c.Next()
+	}
+}
+
+func WithClaims(ctx context.Context, claims *Claims) context.Context {
+	return context.WithValue(ctx, ctxKey{}, claims)
+}
+
+// FromContext 从 request context 中取出 claims
+func FromContext(ctx context.Context) (*Claims, bool) {
+	claims, ok := ctx.Value(ctxKey{}).(*Claims)
+	return claims, ok
+}
+
+// GetClaims 从 gin.Context 中取出 claims
+func GetClaims(c *gin.Context) (*Claims, bool) {
+	v, ok := c.Get(ClaimsKey)
+	if !ok {
+		return nil, false
+	}
+	claims, ok := v.(*Claims)
+	return claims, ok
+}
@@ -0,0 +1,171 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/ilooky/go-layout/pkg/config"
+)
+
+func testConfig() config.Auth {
+	return config.Auth{
+		Enable: true,
+		Alg:    "HS256",
+		Secret: "secret",
+		Issuer: "us",
+		Expire: time.Minute,
+		Skip:   []string{"/health", "/metrics"},
+	}
+}
+
+func request(a *Authenticator, path, token string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	h := gin.New()
+	h.Use(a.Middleware())
+	handler := func(c *gin.Context) {
+		claims, _ := GetClaims(c)
+		subject := ""
+		if claims != nil {
+			subject = claims.Subject
+		}
+		c.String(http.StatusOK, subject)
+	}
+	h.GET("/health", handler)
+	h.GET("/api/items", handler)
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, path, nil)
+	if token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func TestMiddlewareHS256(t *testing.T) {
+	a, err := New(testConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := a.Issue("us-diagram", "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w := request(a, "/api/items", token); w.Code != http.StatusOK || w.Body.String() != "us-diagram" {
+		t.Errorf("valid token: %d %s", w.Code, w.Body.String())
+	}
+	if w := request(a, "/api/items", ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("missing token: %d", w.Code)
+	}
+	if w := request(a, "/health", ""); w.Code != http.StatusOK {
+		t.Errorf("skipped path: %d", w.Code)
+	}
+	other := testConfig()
+	other.Secret = "other"
+	forged, _ := NewWithKeys(other, staticKey{alg: "HS256", key: []byte("other")})
+	token, _ = forged.Issue("intruder")
+	if w := request(a, "/api/items", token); w.Code != http.StatusUnauthorized {
+		t.Errorf("forged token: %d", w.Code)
+	}
+	token, _ = a.Sign(Claims{StandardClaims: jwt.StandardClaims{Subject: "forever", Issuer: "us"}})
+	if w := request(a, "/api/items", token); w.Code != http.StatusUnauthorized {
+		t.Errorf("token without exp: %d", w.Code)
+	}
+	other = testConfig()
+	other.Issuer = "someone-else"
+	wrongIssuer, _ := New(other)
+	token, _ = wrongIssuer.Issue("us-diagram")
+	if _, err = a.Verify(token); err == nil {
+		t.Error("token with unexpected issuer accepted")
+	}
+}
+
+func TestKeySetRS256(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	enc := base64.RawURLEncoding.EncodeToString
+	jwks := fmt.Sprintf(`{"keys":[{"kid":"k1","kty":"RSA","alg":"RS256","n":"%s","e":"%s"}]}`,
+		enc(key.N.Bytes()), enc(big.NewInt(int64(key.E)).Bytes()))
+	set := &KeySet{}
+	if err = set.Load([]byte(jwks)); err != nil {
+		t.Fatal(err)
+	}
+	c := testConfig()
+	c.Alg = "RS256"
+	a, err := NewWithKeys(c, set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sign := func(kid string) string {
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, Claims{
+			StandardClaims: jwt.StandardClaims{Subject: "svc", Issuer: "us", ExpiresAt: time.Now().Add(time.Minute).Unix()},
+		})
+		token.Header["kid"] = kid
+		s, _ := token.SignedString(key)
+		return s
+	}
+	if claims, err := a.Verify(sign("k1")); err != nil || claims.Subject != "svc" {
+		t.Errorf("verify with known kid: %v", err)
+	}
+	if _, err = a.Verify(sign("k2")); err == nil {
+		t.Error("unknown kid accepted")
+	}
+}
+
+type kvCloud struct {
+	config.Cloud
+	calls int32
+}
+
+func (c *kvCloud) GetKV(key string, index uint64) ([]byte, uint64, error) {
+	atomic.AddInt32(&c.calls, 1)
+	time.Sleep(time.Millisecond)
+	return []byte(`{"keys":[{"kid":"k1","kty":"oct","k":"c2VjcmV0"}]}`), index + 1, nil
+}
+
+func TestKeySetClose(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, []byte(`{"keys":[{"kid":"k1","kty":"oct","k":"c2VjcmV0"}]}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	set, err := FileKeys(path, 5*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = set.Close()
+	_ = set.Close()
+	later := time.Now().Add(time.Second)
+	_ = os.WriteFile(path, []byte(`{"keys":[{"kid":"k2","kty":"oct","k":"c2VjcmV0"}]}`), 0o600)
+	_ = os.Chtimes(path, later, later)
+	time.Sleep(30 * time.Millisecond)
+	if _, err = set.Key("k2", "HS256"); err == nil {
+		t.Error("closed file key set still reloads")
+	}
+
+	cloud := &kvCloud{}
+	set, err = ConsulKeys(cloud, "jwks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(10 * time.Millisecond)
+	_ = set.Close()
+	time.Sleep(5 * time.Millisecond)
+	calls := atomic.LoadInt32(&cloud.calls)
+	time.Sleep(20 * time.Millisecond)
+	if n := atomic.LoadInt32(&cloud.calls); n != calls {
+		t.Errorf("consul watch still running after Close: %d -> %d calls", calls, n)
+	}
+}
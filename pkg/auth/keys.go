@@ -0,0 +1,261 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/ilooky/go-layout/pkg/config"
+	"github.com/ilooky/go-layout/pkg/guava/json"
+	"github.com/ilooky/logger"
+)
+
+var ErrKeyNotFound = errors.New("auth: signing key not found")
+
+// KeySource 根据 token 头中的 kid 和 alg 提供验签密钥
+type KeySource interface {
+	Key(kid, alg string) (interface{}, error)
+}
+
+// staticKey 配置中的单个密钥，忽略 kid
+type staticKey struct {
+	alg string
+	key interface{}
+}
+
+func (s staticKey) Key(kid, alg string) (interface{}, error) {
+	if alg != s.alg {
+		return nil, fmt.Errorf("auth: unexpected alg %s", alg)
+	}
+	return s.key, nil
+}
+
+// StaticKey 由配置的 Secret（HS256）或 PEM 公钥（RS256/ES256）构造密钥来源
+func StaticKey(c config.Auth) (KeySource, error) {
+	switch c.Alg {
+	case "HS256":
+		if c.Secret == "" {
+			return nil, errors.New("auth: secret is required for HS256")
+		}
+		return staticKey{alg: c.Alg, key: []byte(c.Secret)}, nil
+	case "RS256":
+		key, err := jwt.ParseRSAPublicKeyFromPEM([]byte(c.PublicKey))
+		if err != nil {
+			return nil, err
+		}
+		return staticKey{alg: c.Alg, key: key}, nil
+	case "ES256":
+		key, err := jwt.ParseECPublicKeyFromPEM([]byte(c.PublicKey))
+		if err != nil {
+			return nil, err
+		}
+		return staticKey{alg: c.Alg, key: key}, nil
+	}
+	return nil, fmt.Errorf("auth: unsupported alg %s", c.Alg)
+}
+
+// KeySet 按 kid 索引的 JWKS 密钥集，可在运行时整体替换以支持密钥轮换
+type KeySet struct {
+	mu   sync.RWMutex
+	keys map[string]jwk
+	stop chan struct{}
+	once sync.Once
+}
+
+func newKeySet() *KeySet {
+	return &KeySet{stop: make(chan struct{})}
+}
+
+// Close 停止 FileKeys、ConsulKeys 启动的重新加载，可重复调用
+func (s *KeySet) Close() error {
+	s.once.Do(func() {
+		if s.stop != nil {
+			close(s.stop)
+		}
+	})
+	return nil
+}
+
+type jwk struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Alg string `json:"alg"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+	K   string `json:"k"`
+
+	key interface{}
+}
+
+func (s *KeySet) Key(kid, alg string) (interface{}, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	k, ok := s.keys[kid]
+	if !ok && kid == "" && len(s.keys) == 1 {
+		for _, only := range s.keys {
+			k, ok = only, true
+		}
+	}
+	if !ok {
+		return nil, ErrKeyNotFound
+	}
+	if k.Alg != "" && k.Alg != alg {
+		return nil, fmt.Errorf("auth: key %s is for %s, not %s", kid, k.Alg, alg)
+	}
+	return k.key, nil
+}
+
+// Load 解析 JWKS 文档并替换当前密钥集
+func (s *KeySet) Load(data []byte) error {
+	var doc struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	keys := make(map[string]jwk, len(doc.Keys))
+	for _, k := range doc.Keys {
+		key, err := k.parse()
+		if err != nil {
+			return fmt.Errorf("auth: jwk %s: %w", k.Kid, err)
+		}
+		k.key = key
+		keys[k.Kid] = k
+	}
+	s.mu.Lock()
+	s.keys = keys
+	s.mu.Unlock()
+	return nil
+}
+
+func (k jwk) parse() (interface{}, error) {
+	switch k.Kty {
+	case "oct":
+		return decode(k.K)
+	case "RSA":
+		n, err := decode(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decode(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		if k.Crv != "P-256" {
+			return nil, fmt.Errorf("unsupported curve %s", k.Crv)
+		}
+		x, err := decode(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decode(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	}
+	return nil, fmt.Errorf("unsupported kty %s", k.Kty)
+}
+
+func decode(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(s)
+}
+
+// FileKeys 从本地 JWKS 文件加载密钥，并按 refresh 间隔检查文件修改后重新加载，直到 Close
+func FileKeys(path string, refresh time.Duration) (*KeySet, error) {
+	set := newKeySet()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if err = set.Load(data); err != nil {
+		return nil, err
+	}
+	info, _ := os.Stat(path)
+	ticker := time.NewTicker(refresh)
+	go func(modified time.Time) {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-set.stop:
+				return
+			case <-ticker.C:
+			}
+			info, err := os.Stat(path)
+			if err != nil || !info.ModTime().After(modified) {
+				continue
+			}
+			modified = info.ModTime()
+			data, err := os.ReadFile(path)
+			if err == nil {
+				err = set.Load(data)
+			}
+			if err != nil {
+				logger.Errorf("auth: reload jwks %s: %v", path, err)
+				continue
+			}
+			logger.Infof("auth: reloaded jwks %s", path)
+		}
+	}(info.ModTime())
+	return set, nil
+}
+
+// ConsulKeys 从 Consul KV 加载 JWKS，并通过阻塞查询在键更新时立即轮换；
+// Close 后在当前阻塞查询返回时停止
+func ConsulKeys(cloud config.Cloud, key string) (*KeySet, error) {
+	set := newKeySet()
+	data, index, err := cloud.GetKV(key, 0)
+	if err != nil {
+		return nil, err
+	}
+	if err = set.Load(data); err != nil {
+		return nil, err
+	}
+	go func() {
+		for {
+			select {
+			case <-set.stop:
+				return
+			default:
+			}
+			data, next, err := cloud.GetKV(key, index)
+			if err != nil {
+				logger.Errorf("auth: watch jwks %s: %v", key, err)
+				select {
+				case <-set.stop:
+					return
+				case <-time.After(10 * time.Second):
+				}
+				continue
+			}
+			if next < index {
+				// Consul 的 index 可能被重置，此时重新开始等待
+				index = 0
+				continue
+			}
+			if next == index {
+				continue
+			}
+			index = next
+			if err = set.Load(data); err != nil {
+				logger.Errorf("auth: reload jwks %s: %v", key, err)
+				continue
+			}
+			logger.Infof("auth: rotated jwks from consul %s", key)
+		}
+	}()
+	return set, nil
+}
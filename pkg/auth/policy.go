@@ -0,0 +1,222 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ilooky/go-layout/pkg/cnts"
+	"github.com/ilooky/go-layout/pkg/database"
+	"github.com/ilooky/go-layout/pkg/errno"
+	"github.com/ilooky/go-layout/pkg/middleware"
+	"github.com/ilooky/logger"
+	"go.uber.org/zap"
+)
+
+// Decision 授权结果，Effect 为 cnts.PERMIT 或 cnts.FORBID
+type Decision struct {
+	Effect string `json:"effect"`
+	Reason string `json:"reason"`
+}
+
+func (d Decision) Permitted() bool {
+	return d.Effect == cnts.PERMIT
+}
+
+// RoleStore 提供角色拥有的权限，权限格式为 "resource:action"，支持 * 通配，
+// 以 ! 开头表示显式禁止，优先于任何授予；ctx 为请求的 context，可据此区分租户
+type RoleStore interface {
+	Permissions(ctx context.Context, role string) ([]string, error)
+}
+
+// ConfigRoles 配置文件中的角色权限，如 admin: ["*:*"]
+type ConfigRoles map[string][]string
+
+func (r ConfigRoles) Permissions(_ context.Context, role string) ([]string, error) {
+	return r[role], nil
+}
+
+// RolePermission 角色权限表
+type RolePermission struct {
+	Id         int64  `json:"id"`
+	Role       string `json:"role"       xorm:"varchar(64) index"`
+	Permission string `json:"permission" xorm:"varchar(128)"`
+}
+
+// DbRoles 从 ctx 对应数据库（多租户时为租户库）的 RolePermission 表读取角色权限，
+// 此时需设置 Policy.Scope 按租户区分缓存
+type DbRoles struct{}
+
+func (DbRoles) Permissions(ctx context.Context, role string) ([]string, error) {
+	var list []RolePermission
+	if err := database.FindListByFieldContext(ctx, "role", role, &list); err != nil {
+		return nil, err
+	}
+	perms := make([]string, 0, len(list))
+	for _, p := range list {
+		perms = append(perms, p.Permission)
+	}
+	return perms, nil
+}
+
+type cached struct {
+	decision Decision
+	expire   time.Time
+}
+
+// DefaultPolicy 由 Init 根据 config.Auth.Roles 创建，未配置角色时为 nil
+var DefaultPolicy *Policy
+
+// Policy 根据角色权限做出授权决定，结果按 ttl 缓存，每次决定都会记录审计日志
+type Policy struct {
+	// Scope 返回缓存键的前缀，角色权限按租户存储时设为 tenant.ID，避免租户间共用缓存
+	Scope func(ctx context.Context) string
+	// MaxEntries 缓存的最大条目数，写满时先删除过期条目，仍然写满则随机淘汰
+	MaxEntries int
+	store      RoleStore
+	ttl        time.Duration
+	mu         sync.RWMutex
+	cache      map[string]cached
+}
+
+func NewPolicy(store RoleStore, ttl time.Duration) *Policy {
+	return &Policy{MaxEntries: 10000, store: store, ttl: ttl, cache: map[string]cached{}}
+}
+
+// Decide 判断 roles 是否可以对 resource 执行 action
+func (p *Policy) Decide(ctx context.Context, roles []string, action, resource string) Decision {
+	sorted := append([]string(nil), roles...)
+	sort.Strings(sorted)
+	key := strings.Join(sorted, ",") + "|" + resource + ":" + action
+	if p.Scope != nil {
+		key = p.Scope(ctx) + "|" + key
+	}
+	p.mu.RLock()
+	c, ok := p.cache[key]
+	p.mu.RUnlock()
+	if ok && time.Now().Before(c.expire) {
+		return c.decision
+	}
+	d, err := p.decide(ctx, sorted, action, resource)
+	if err != nil {
+		// 权限加载失败时拒绝但不缓存，恢复后立即生效
+		return Decision{Effect: cnts.FORBID, Reason: err.Error()}
+	}
+	p.mu.Lock()
+	if _, ok := p.cache[key]; !ok && p.MaxEntries > 0 && len(p.cache) >= p.MaxEntries {
+		p.evict()
+	}
+	p.cache[key] = cached{decision: d, expire: time.Now().Add(p.ttl)}
+	p.mu.Unlock()
+	return d
+}
+
+// evict 删除过期条目，仍然写满时随机淘汰到 MaxEntries 以下，调用方需持有写锁
+func (p *Policy) evict() {
+	now := time.Now()
+	for k, c := range p.cache {
+		if !now.Before(c.expire) {
+			delete(p.cache, k)
+		}
+	}
+	for k := range p.cache {
+		if len(p.cache) < p.MaxEntries {
+			break
+		}
+		delete(p.cache, k)
+	}
+}
+
+func (p *Policy) decide(ctx context.Context, roles []string, action, resource string) (Decision, error) {
+	granted := ""
+	for _, role := range roles {
+		perms, err := p.store.Permissions(ctx, role)
+		if err != nil {
+			return Decision{}, fmt.Errorf("load permissions of %s: %w", role, err)
+		}
+		for _, perm := range perms {
+			deny := strings.HasPrefix(perm, "!")
+			if !match(strings.TrimPrefix(perm, "!"), action, resource) {
+				continue
+			}
+			if deny {
+				return Decision{Effect: cnts.FORBID, Reason: "forbidden by role " + role + " (" + perm + ")"}, nil
+			}
+			if granted == "" {
+				granted = "granted by role " + role + " (" + perm + ")"
+			}
+		}
+	}
+	if granted != "" {
+		return Decision{Effect: cnts.PERMIT, Reason: granted}, nil
+	}
+	return Decision{Effect: cnts.FORBID, Reason: "no role grants " + resource + ":" + action}, nil
+}
+
+func match(perm, action, resource string) bool {
+	i := strings.LastIndex(perm, ":")
+	if i < 0 {
+		return false
+	}
+	okRes, _ := path.Match(perm[:i], resource)
+	okAct, _ := path.Match(perm[i+1:], action)
+	return okRes && okAct
+}
+
+// Invalidate 清空缓存，角色权限变更后调用
+func (p *Policy) Invalidate() {
+	p.mu.Lock()
+	p.cache = map[string]cached{}
+	p.mu.Unlock()
+}
+
+// Can 使用 ctx 中认证得到的角色做授权决定，并记录审计日志
+func (p *Policy) Can(ctx context.Context, action, resource string) Decision {
+	claims, ok := FromContext(ctx)
+	if !ok {
+		d := Decision{Effect: cnts.FORBID, Reason: "unauthenticated"}
+		audit("", action, resource, d)
+		return d
+	}
+	d := p.Decide(ctx, claims.Roles, action, resource)
+	audit(claims.Subject, action, resource, d)
+	return d
+}
+
+// Require 使用 DefaultPolicy 声明路由需要的权限，未配置角色时拒绝所有请求
+func Require(action, resource string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if DefaultPolicy == nil {
+			middleware.Abort(c, errno.Forbidden.Errorf("no roles configured"))
+			return
+		}
+		DefaultPolicy.Require(action, resource)(c)
+	}
+}
+
+// Require 声明路由需要的权限，未授权时返回 errno.Forbidden
+func (p *Policy) Require(action, resource string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		d := p.Can(c.Request.Context(), action, resource)
+		if !d.Permitted() {
+			middleware.Abort(c, errno.Forbidden.Errorf("%s", d.Reason))
+			return
+		}
+		c.Next()
+	}
+}
+
+func audit(subject, action, resource string, d Decision) {
+	logger.InfoKv("Authorize",
+		zap.String("subject", subject),
+		zap.String("action", action),
+		zap.String("resource", resource),
+		zap.String("effect", d.Effect),
+		zap.String("reason", d.Reason),
+	)
+}
@@ -0,0 +1,123 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ilooky/go-layout/pkg/cnts"
+)
+
+type countingRoles struct {
+	ConfigRoles
+	calls int
+	err   error
+}
+
+func (r *countingRoles) Permissions(ctx context.Context, role string) ([]string, error) {
+	r.calls++
+	if r.err != nil {
+		return nil, r.err
+	}
+	return r.ConfigRoles.Permissions(ctx, role)
+}
+
+func TestPolicyDecide(t *testing.T) {
+	p := NewPolicy(ConfigRoles{
+		"admin":  {"*:*"},
+		"viewer": {"diagram:read", "station:read"},
+		"guest":  {"!station:*"},
+	}, time.Minute)
+	tests := []struct {
+		roles    []string
+		action   string
+		resource string
+		effect   string
+	}{
+		{[]string{"admin"}, "delete", "diagram", cnts.PERMIT},
+		{[]string{"viewer"}, "read", "diagram", cnts.PERMIT},
+		{[]string{"viewer"}, "write", "diagram", cnts.FORBID},
+		{[]string{"viewer", "guest"}, "read", "station", cnts.FORBID},
+		{[]string{"admin", "guest"}, "read", "station", cnts.FORBID},
+		{nil, "read", "diagram", cnts.FORBID},
+	}
+	for _, tt := range tests {
+		if d := p.Decide(context.Background(), tt.roles, tt.action, tt.resource); d.Effect != tt.effect {
+			t.Errorf("%v %s:%s = %s (%s), want %s", tt.roles, tt.resource, tt.action, d.Effect, d.Reason, tt.effect)
+		}
+	}
+}
+
+func TestPolicyCache(t *testing.T) {
+	store := &countingRoles{ConfigRoles: ConfigRoles{"viewer": {"diagram:read"}}}
+	p := NewPolicy(store, time.Minute)
+	ctx := context.Background()
+	p.Decide(ctx, []string{"viewer"}, "read", "diagram")
+	p.Decide(ctx, []string{"viewer"}, "read", "diagram")
+	if store.calls != 1 {
+		t.Errorf("store called %d times, want 1", store.calls)
+	}
+	p.Invalidate()
+	p.Decide(ctx, []string{"viewer"}, "read", "diagram")
+	if store.calls != 2 {
+		t.Errorf("store called %d times after invalidate, want 2", store.calls)
+	}
+
+	store.err = errors.New("db down")
+	p.Invalidate()
+	if d := p.Decide(ctx, []string{"viewer"}, "read", "diagram"); d.Permitted() {
+		t.Error("permitted while store is failing")
+	}
+	store.err = nil
+	if d := p.Decide(ctx, []string{"viewer"}, "read", "diagram"); !d.Permitted() {
+		t.Errorf("failed decision was cached: %s", d.Reason)
+	}
+
+	type scopeKey struct{}
+	p.Scope = func(ctx context.Context) string {
+		s, _ := ctx.Value(scopeKey{}).(string)
+		return s
+	}
+	calls := store.calls
+	p.Decide(context.WithValue(ctx, scopeKey{}, "t1"), []string{"viewer"}, "read", "diagram")
+	p.Decide(context.WithValue(ctx, scopeKey{}, "t2"), []string{"viewer"}, "read", "diagram")
+	p.Decide(context.WithValue(ctx, scopeKey{}, "t1"), []string{"viewer"}, "read", "diagram")
+	if store.calls-calls != 2 {
+		t.Errorf("store called %d times for two scopes, want 2", store.calls-calls)
+	}
+}
+
+func TestPolicyCan(t *testing.T) {
+	p := NewPolicy(ConfigRoles{"admin": {"*:*"}}, time.Minute)
+	if d := p.Can(context.Background(), "read", "diagram"); d.Permitted() {
+		t.Error("unauthenticated request permitted")
+	}
+	ctx := WithClaims(context.Background(), &Claims{Roles: []string{"admin"}})
+	if d := p.Can(ctx, "read", "diagram"); !d.Permitted() {
+		t.Errorf("admin forbidden: %s", d.Reason)
+	}
+}
+
+func TestPolicyEvict(t *testing.T) {
+	p := NewPolicy(ConfigRoles{"viewer": {"*:read"}}, time.Minute)
+	p.MaxEntries = 3
+	ctx := context.Background()
+	for _, res := range []string{"a", "b", "c", "d", "e"} {
+		p.Decide(ctx, []string{"viewer"}, "read", res)
+	}
+	if n := len(p.cache); n > 3 {
+		t.Errorf("cache has %d entries, want at most 3", n)
+	}
+
+	p.Invalidate()
+	p.ttl = -time.Second
+	for _, res := range []string{"a", "b", "c"} {
+		p.Decide(ctx, []string{"viewer"}, "read", res)
+	}
+	p.ttl = time.Minute
+	p.Decide(ctx, []string{"viewer"}, "read", "d")
+	if _, ok := p.cache["viewer|d:read"]; len(p.cache) != 1 || !ok {
+		t.Errorf("expired entries were kept: %v", p.cache)
+	}
+}
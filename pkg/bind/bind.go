@@ -0,0 +1,395 @@
+// Package bind 绑定并校验请求参数，校验失败时返回携带字段错误列表的 errno.Param，
+// 交给 middleware.ErrorHandler 或 errno.FromError 渲染后 Content 即为 []FieldError。
+package bind
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"github.com/ilooky/go-layout/pkg/database"
+	"github.com/ilooky/go-layout/pkg/errno"
+	"github.com/ilooky/go-layout/pkg/guava"
+	"github.com/ilooky/go-layout/pkg/guava/json"
+)
+
+const maxMemory = 32 << 20
+
+var (
+	// MaxBodySize Bind 读取的 JSON 请求体的最大字节数
+	MaxBodySize int64 = 4 << 20
+	// MaxPatchSize PATCH 请求体的最大字节数
+	MaxPatchSize int64 = 1 << 20
+	// ReadOnly Patch 不允许修改的 JSON Pointer 路径，调用时可追加
+	ReadOnly = []string{"/id", "/version", "/created", "/updated"}
+)
+
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Param   string `json:"param,omitempty"`
+	Message string `json:"message"`
+}
+
+var (
+	validate = newValidator()
+	mu       sync.RWMutex
+	// messages 语言 -> 校验规则 -> 提示模板，{field}、{param} 会被替换
+	messages = map[string]map[string]string{
+		"zh": {
+			"required": "{field}不能为空",
+			"min":      "{field}不能小于{param}",
+			"max":      "{field}不能大于{param}",
+			"gte":      "{field}不能小于{param}",
+			"lte":      "{field}不能大于{param}",
+			"gt":       "{field}必须大于{param}",
+			"lt":       "{field}必须小于{param}",
+			"len":      "{field}长度必须为{param}",
+			"oneof":    "{field}必须是[{param}]中的一个",
+			"email":    "{field}不是有效的邮箱",
+			"numeric":  "{field}必须是数字",
+			"type":     "{field}类型错误",
+			"readonly": "{field}不允许修改",
+			"default":  "{field}校验失败({rule})",
+		},
+		"en": {
+			"required": "{field} is required",
+			"min":      "{field} must be at least {param}",
+			"max":      "{field} must be at most {param}",
+			"gte":      "{field} must be at least {param}",
+			"lte":      "{field} must be at most {param}",
+			"gt":       "{field} must be greater than {param}",
+			"lt":       "{field} must be less than {param}",
+			"len":      "{field} must have length {param}",
+			"oneof":    "{field} must be one of [{param}]",
+			"email":    "{field} must be a valid email",
+			"numeric":  "{field} must be numeric",
+			"type":     "{field} has invalid type",
+			"readonly": "{field} is read-only",
+			"default":  "{field} failed on {rule}",
+		},
+	}
+)
+
+func newValidator() *validator.Validate {
+	v := validator.New()
+	v.SetTagName("binding")
+	v.RegisterTagNameFunc(func(sf reflect.StructField) string {
+		return fieldName(sf, "form")
+	})
+	v.RegisterCustomTypeFunc(func(field reflect.Value) interface{} {
+		switch t := field.Interface().(type) {
+		case database.JsonTime:
+			return time.Time(t)
+		case database.JsonDate:
+			return time.Time(t)
+		case database.NullJsonTime:
+			if t.Valid {
+				return t.Time
+			}
+		}
+		return nil
+	}, database.JsonTime{}, database.JsonDate{}, database.NullJsonTime{})
+	v.RegisterStructValidation(validatePaged, guava.Paged{})
+	return v
+}
+
+// validatePaged 分页参数不能为负数，limit 不能超过 guava.MaxLimit；排序字段由查询时按白名单校验
+func validatePaged(sl validator.StructLevel) {
+	p := sl.Current().Interface().(guava.Paged)
+	for _, f := range []struct {
+		name  string
+		value int
+	}{{"page", p.Page}, {"start", p.Start}, {"limit", p.Limit}} {
+		if f.value < 0 {
+			sl.ReportError(f.value, f.name, f.name, "min", "0")
+		}
+	}
+	if p.Limit > guava.MaxLimit {
+		sl.ReportError(p.Limit, "limit", "limit", "max", strconv.Itoa(guava.MaxLimit))
+	}
+}
+
+// RegisterValidation 注册项目自定义的校验规则，messages 为语言到提示模板的映射
+func RegisterValidation(tag string, fn validator.Func, msgs map[string]string) error {
+	if err := validate.RegisterValidation(tag, fn); err != nil {
+		return err
+	}
+	for lang, msg := range msgs {
+		SetMessage(lang, tag, msg)
+	}
+	return nil
+}
+
+// SetMessage 设置或覆盖校验规则的提示模板
+func SetMessage(lang, rule, msg string) {
+	mu.Lock()
+	defer mu.Unlock()
+	if messages[lang] == nil {
+		messages[lang] = map[string]string{}
+	}
+	messages[lang][rule] = msg
+}
+
+var pagedType = reflect.TypeOf(guava.Paged{})
+
+// hasPaged t 是否为 guava.Paged 或匿名嵌入了 guava.Paged
+func hasPaged(t reflect.Type) bool {
+	if t == pagedType {
+		return true
+	}
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		ft := sf.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if sf.Anonymous && ft.Kind() == reflect.Struct && hasPaged(ft) {
+			return true
+		}
+	}
+	return false
+}
+
+// pagedNumbers 兼容旧版客户端以字符串传递的分页参数，将 JSON 请求体中 "page": "2" 形式的
+// page、start、limit 转换为数字；不在 Paged 上实现 UnmarshalJSON，以免嵌入它的结构体整体被其接管
+func pagedNumbers(body []byte) []byte {
+	var fields map[string]json.RawMessage
+	if json.Unmarshal(body, &fields) != nil {
+		return body
+	}
+	changed := false
+	for _, key := range []string{"page", "start", "limit"} {
+		raw, ok := fields[key]
+		if !ok || len(raw) == 0 || raw[0] != '"' {
+			continue
+		}
+		var s string
+		if json.Unmarshal(raw, &s) != nil {
+			continue
+		}
+		if s = strings.TrimSpace(s); s == "" {
+			s = "0"
+		}
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			continue
+		}
+		fields[key] = json.RawMessage(strconv.Itoa(n))
+		changed = true
+	}
+	if !changed {
+		return body
+	}
+	if data, err := json.Marshal(fields); err == nil {
+		return data
+	}
+	return body
+}
+
+// Bind 依次绑定 JSON/form 请求体、query 参数和 path 参数（后者覆盖前者），然后执行校验；
+// JSON 请求体超过 MaxBodySize 时返回 errno.Param
+func Bind(c *gin.Context, obj interface{}) error {
+	lang := errno.Lang(c.GetHeader("Accept-Language"))
+	v := reflect.ValueOf(obj)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return errno.Internal.Errorf("bind: %T is not a pointer to struct", obj)
+	}
+	var errs []FieldError
+	if c.Request.Body != nil && c.Request.Method != http.MethodGet {
+		switch c.ContentType() {
+		case binding.MIMEJSON:
+			body, err := readBody(c.Request.Body, MaxBodySize, "request")
+			if err != nil {
+				return err
+			}
+			if len(body) > 0 {
+				if hasPaged(v.Type().Elem()) {
+					body = pagedNumbers(body)
+				}
+				if err = json.Unmarshal(body, obj); err != nil {
+					return errno.Param.Wrap(err)
+				}
+			}
+		case binding.MIMEPOSTForm:
+			if err := c.Request.ParseForm(); err != nil {
+				return errno.Param.Wrap(err)
+			}
+			mapValues(v.Elem(), c.Request.PostForm, "form", &errs)
+		case binding.MIMEMultipartPOSTForm:
+			if err := c.Request.ParseMultipartForm(maxMemory); err != nil {
+				return errno.Param.Wrap(err)
+			}
+			mapValues(v.Elem(), c.Request.MultipartForm.Value, "form", &errs)
+		}
+	}
+	mapValues(v.Elem(), c.Request.URL.Query(), "form", &errs)
+	if len(c.Params) > 0 {
+		params := make(map[string][]string, len(c.Params))
+		for _, p := range c.Params {
+			params[p.Key] = []string{p.Value}
+		}
+		mapValues(v.Elem(), params, "uri", &errs)
+	}
+	if len(errs) > 0 {
+		return fieldsError(errs, lang)
+	}
+	return Validate(obj, lang)
+}
+
+const (
+	MIMEJSONPatch  = "application/json-patch+json"
+	MIMEMergePatch = "application/merge-patch+json"
+)
+
+// readBody 读取不超过 limit 字节的请求体，超过时返回 errno.Param
+func readBody(r io.Reader, limit int64, kind string) ([]byte, error) {
+	body, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, errno.Param.Wrap(err)
+	}
+	if int64(len(body)) > limit {
+		return nil, errno.Param.Errorf("%s body exceeds %d bytes", kind, limit)
+	}
+	return body, nil
+}
+
+// Patch 将 PATCH 请求体应用到已加载的 obj 上并校验：application/json-patch+json 按 RFC 6902 执行，
+// application/merge-patch+json 和 application/json 按 RFC 7386 合并；修改了 ReadOnly 或 readOnly 中的路径、
+// 请求体超过 MaxPatchSize、失败或校验不通过时 obj 保持不变
+func Patch(c *gin.Context, obj interface{}, readOnly ...string) error {
+	lang := errno.Lang(c.GetHeader("Accept-Language"))
+	v := reflect.ValueOf(obj)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return errno.Internal.Errorf("bind: %T is not a pointer to struct", obj)
+	}
+	if c.Request.Body == nil {
+		return errno.Param.Errorf("empty patch")
+	}
+	body, err := readBody(c.Request.Body, MaxPatchSize, "patch")
+	if err != nil {
+		return err
+	}
+	patched := reflect.New(v.Elem().Type())
+	patched.Elem().Set(v.Elem())
+	switch c.ContentType() {
+	case MIMEJSONPatch:
+		var p json.Patch
+		if p, err = json.DecodePatch(body); err == nil {
+			err = p.ApplyTo(patched.Interface())
+		}
+	case MIMEMergePatch, binding.MIMEJSON:
+		err = json.MergePatchTo(patched.Interface(), body)
+	default:
+		return errno.Param.Errorf("unsupported patch content type %q", c.ContentType())
+	}
+	if err != nil {
+		return errno.Param.Wrap(err)
+	}
+	if err = checkReadOnly(obj, patched.Interface(), append(ReadOnly, readOnly...), lang); err != nil {
+		return err
+	}
+	if err = Validate(patched.Interface(), lang); err != nil {
+		return err
+	}
+	v.Elem().Set(patched.Elem())
+	return nil
+}
+
+// checkReadOnly 比较补丁前后的 JSON，修改了只读路径或其子路径时返回字段错误
+func checkReadOnly(before, after interface{}, paths []string, lang string) error {
+	diff, err := json.DiffValues(before, after)
+	if err != nil {
+		return errno.Internal.Wrap(err)
+	}
+	var errs []FieldError
+	for _, op := range diff {
+		for _, p := range paths {
+			if op.Path == p || strings.HasPrefix(op.Path, p+"/") {
+				errs = append(errs, FieldError{Field: strings.ReplaceAll(strings.TrimPrefix(p, "/"), "/", "."), Rule: "readonly"})
+				break
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return fieldsError(errs, lang)
+	}
+	return nil
+}
+
+// Validate 按 binding 标签校验结构体
+func Validate(obj interface{}, lang string) error {
+	err := validate.Struct(obj)
+	if err == nil {
+		return nil
+	}
+	var ves validator.ValidationErrors
+	if !errors.As(err, &ves) {
+		return errno.Internal.Wrap(err)
+	}
+	root := reflect.TypeOf(obj)
+	errs := make([]FieldError, 0, len(ves))
+	for _, fe := range ves {
+		errs = append(errs, FieldError{Field: fieldPath(root, fe.StructNamespace()), Rule: fe.Tag(), Param: fe.Param()})
+	}
+	return fieldsError(errs, lang)
+}
+
+// fieldPath 将 Query.Paged.Limit 形式的结构体路径转换为请求中的参数路径 limit，
+// 匿名嵌入的结构体不出现在路径中
+func fieldPath(t reflect.Type, ns string) string {
+	segments := strings.Split(ns, ".")[1:]
+	path := make([]string, 0, len(segments))
+	for _, seg := range segments {
+		for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice || t.Kind() == reflect.Array || t.Kind() == reflect.Map {
+			t = t.Elem()
+		}
+		name, index := seg, ""
+		if i := strings.Index(seg, "["); i >= 0 {
+			name, index = seg[:i], seg[i:]
+		}
+		if t.Kind() != reflect.Struct {
+			path = append(path, seg)
+			continue
+		}
+		sf, ok := t.FieldByName(name)
+		if !ok {
+			path = append(path, seg)
+			continue
+		}
+		t = sf.Type
+		if !sf.Anonymous {
+			path = append(path, fieldName(sf, "form")+index)
+		}
+	}
+	return strings.Join(path, ".")
+}
+
+func fieldsError(errs []FieldError, lang string) error {
+	for i := range errs {
+		errs[i].Message = message(lang, errs[i])
+	}
+	return errno.Param.Errorf("%d invalid fields, first: %s", len(errs), errs[0].Message).WithData(errs)
+}
+
+func message(lang string, fe FieldError) string {
+	mu.RLock()
+	bundle, ok := messages[lang]
+	if !ok {
+		bundle = messages[errno.DefaultLang]
+	}
+	tpl, ok := bundle[fe.Rule]
+	if !ok {
+		tpl = bundle["default"]
+	}
+	mu.RUnlock()
+	return strings.NewReplacer("{field}", fe.Field, "{param}", fe.Param, "{rule}", fe.Rule).Replace(tpl)
+}
@@ -0,0 +1,217 @@
+package bind
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/ilooky/go-layout/pkg/database"
+	"github.com/ilooky/go-layout/pkg/errno"
+	"github.com/ilooky/go-layout/pkg/guava"
+)
+
+type query struct {
+	guava.Paged
+	Id    int64             `uri:"id"   binding:"required"`
+	Name  string            `json:"name" binding:"required,max=5"`
+	Kind  string            `form:"kind" binding:"omitempty,oneof=a b"`
+	Since database.JsonTime `form:"since"`
+	Code  string            `json:"code" binding:"omitempty,station"`
+}
+
+func init() {
+	err := RegisterValidation("station", func(fl validator.FieldLevel) bool {
+		return strings.HasPrefix(fl.Field().String(), "ST")
+	}, map[string]string{"en": "{field} must start with ST"})
+	if err != nil {
+		panic(err)
+	}
+}
+
+func bindRequest(t *testing.T, target, body string, dest interface{}) error {
+	gin.SetMode(gin.TestMode)
+	var err error
+	h := gin.New()
+	h.POST("/items/:id", func(c *gin.Context) { err = Bind(c, dest) })
+	r := httptest.NewRequest(http.MethodPost, target, strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	r.Header.Set("Accept-Language", "en")
+	h.ServeHTTP(httptest.NewRecorder(), r)
+	return err
+}
+
+func fieldErrors(t *testing.T, err error) []FieldError {
+	var app *errno.AppError
+	if !errors.As(err, &app) || app.Code != errno.Param.Code {
+		t.Fatalf("expected errno.Param, got %v", err)
+	}
+	fields, _ := app.Data().([]FieldError)
+	return fields
+}
+
+func TestBind(t *testing.T) {
+	var q query
+	err := bindRequest(t, "/items/7?page=2&limit=10&kind=a&since=2021-05-01", `{"name":"abc"}`, &q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	since := time.Time(q.Since)
+	if q.Id != 7 || q.Name != "abc" || q.Page != 2 || q.Limit != 10 || q.Kind != "a" || since.Day() != 1 {
+		t.Errorf("unexpected binding %+v", q)
+	}
+}
+
+func TestBindValidation(t *testing.T) {
+	var q query
+	err := bindRequest(t, "/items/7?limit=-1&kind=c", `{"name":"abcdefg"}`, &q)
+	fields := fieldErrors(t, err)
+	want := map[string]string{"name": "max", "kind": "oneof", "limit": "min"}
+	if len(fields) != len(want) {
+		t.Fatalf("got %+v", fields)
+	}
+	for _, f := range fields {
+		if want[f.Field] != f.Rule {
+			t.Errorf("unexpected field error %+v", f)
+		}
+		if f.Field == "name" && f.Message != "name must be at most 5" {
+			t.Errorf("message = %q", f.Message)
+		}
+	}
+}
+
+func TestBindPagedBounds(t *testing.T) {
+	var q query
+	fields := fieldErrors(t, bindRequest(t, "/items/7?limit=100000&sort=-name", `{"name":"a"}`, &q))
+	if len(fields) != 1 || fields[0].Field != "limit" || fields[0].Rule != "max" || fields[0].Message != "limit must be at most 500" {
+		t.Errorf("got %+v", fields)
+	}
+	if q.Sort != "-name" {
+		t.Errorf("sort = %q", q.Sort)
+	}
+}
+
+func TestBindBodySize(t *testing.T) {
+	defer func(old int64) { MaxBodySize = old }(MaxBodySize)
+	MaxBodySize = 64
+	var q query
+	err := bindRequest(t, "/items/7", `{"name":"`+strings.Repeat("x", int(MaxBodySize))+`"}`, &q)
+	if !errors.Is(err, errno.Param) || q.Name != "" {
+		t.Errorf("oversized body: %v, name %d bytes", err, len(q.Name))
+	}
+	if err = bindRequest(t, "/items/7", `{"name":"abc"}`, &q); err != nil || q.Name != "abc" {
+		t.Errorf("small body: %v, %+v", err, q)
+	}
+}
+
+func TestBindTypeAndCustomRule(t *testing.T) {
+	var q query
+	fields := fieldErrors(t, bindRequest(t, "/items/x", `{"name":"a"}`, &q))
+	if len(fields) != 1 || fields[0].Field != "id" || fields[0].Rule != "type" {
+		t.Errorf("got %+v", fields)
+	}
+	fields = fieldErrors(t, bindRequest(t, "/items/1", `{"name":"a","code":"X1"}`, &q))
+	if len(fields) != 1 || fields[0].Message != "code must start with ST" {
+		t.Errorf("got %+v", fields)
+	}
+}
+
+type device struct {
+	Id      int64    `json:"id"`
+	Secret  string   `json:"-"`
+	Name    string   `json:"name"    binding:"required,max=5"`
+	Voltage int      `json:"voltage" binding:"gte=0"`
+	Tags    []string `json:"tags,omitempty"`
+}
+
+func patchRequest(t *testing.T, contentType, body string, dest interface{}, readOnly ...string) error {
+	gin.SetMode(gin.TestMode)
+	var err error
+	h := gin.New()
+	h.PATCH("/devices/1", func(c *gin.Context) { err = Patch(c, dest, readOnly...) })
+	r := httptest.NewRequest(http.MethodPatch, "/devices/1", strings.NewReader(body))
+	r.Header.Set("Content-Type", contentType)
+	r.Header.Set("Accept-Language", "en")
+	h.ServeHTTP(httptest.NewRecorder(), r)
+	return err
+}
+
+func TestPatch(t *testing.T) {
+	d := device{Name: "T1", Voltage: 110, Tags: []string{"a"}}
+	if err := patchRequest(t, MIMEJSONPatch, `[{"op":"replace","path":"/voltage","value":220},{"op":"add","path":"/tags/-","value":"b"}]`, &d); err != nil {
+		t.Fatal(err)
+	}
+	if d.Voltage != 220 || len(d.Tags) != 2 {
+		t.Errorf("json patch = %+v", d)
+	}
+	if err := patchRequest(t, MIMEMergePatch, `{"name":"T2","tags":null}`, &d); err != nil {
+		t.Fatal(err)
+	}
+	if d.Name != "T2" || d.Tags != nil || d.Voltage != 220 {
+		t.Errorf("merge patch = %+v", d)
+	}
+	fields := fieldErrors(t, patchRequest(t, "application/json", `{"name":"too-long-name"}`, &d))
+	if len(fields) != 1 || fields[0].Field != "name" || fields[0].Rule != "max" {
+		t.Errorf("got %+v", fields)
+	}
+	if d.Name != "T2" {
+		t.Errorf("invalid patch changed target: %+v", d)
+	}
+	before := d
+	if err := patchRequest(t, MIMEJSONPatch, `[{"op":"test","path":"/name","value":"X"}]`, &d); err == nil {
+		t.Error("failed test op accepted")
+	}
+	if err := patchRequest(t, "text/plain", `name=x`, &d); err == nil {
+		t.Error("unsupported content type accepted")
+	}
+	if d.Name == "X" || before.Voltage != d.Voltage {
+		t.Errorf("rejected patch changed target: %+v", d)
+	}
+}
+
+func TestPatchProtected(t *testing.T) {
+	d := device{Id: 1, Secret: "hash", Name: "T1", Voltage: 110, Tags: []string{"a"}}
+	if err := patchRequest(t, MIMEMergePatch, `{"name":"T2"}`, &d); err != nil || d.Secret != "hash" || d.Id != 1 {
+		t.Errorf("hidden field lost: %+v, %v", d, err)
+	}
+	fields := fieldErrors(t, patchRequest(t, MIMEMergePatch, `{"id":99}`, &d))
+	if len(fields) != 1 || fields[0].Field != "id" || fields[0].Rule != "readonly" || d.Id != 1 {
+		t.Errorf("got %+v, %+v", fields, d)
+	}
+	fields = fieldErrors(t, patchRequest(t, MIMEJSONPatch, `[{"op":"add","path":"/tags/-","value":"b"}]`, &d, "/tags"))
+	if len(fields) != 1 || fields[0].Field != "tags" || len(d.Tags) != 1 {
+		t.Errorf("got %+v, %+v", fields, d)
+	}
+	if err := patchRequest(t, MIMEMergePatch, `{"name":"`+strings.Repeat("x", int(MaxPatchSize))+`"}`, &d); !errors.Is(err, errno.Param) {
+		t.Errorf("oversized patch: %v", err)
+	}
+}
+
+func TestBindPagedStrings(t *testing.T) {
+	tests := []struct {
+		body string
+		want guava.Paged
+	}{
+		{`{"name":"a","page":"2","limit":" 10 "}`, guava.Paged{Page: 2, Limit: 10}},
+		{`{"name":"a","start":"","limit":15}`, guava.Paged{Limit: 15}},
+		{`{"name":"a","page":3,"sort":"-name"}`, guava.Paged{Page: 3, Sort: "-name"}},
+	}
+	for _, tt := range tests {
+		var q query
+		if err := bindRequest(t, "/items/7", tt.body, &q); err != nil {
+			t.Fatalf("%s: %v", tt.body, err)
+		}
+		if q.Paged != tt.want || q.Name != "a" {
+			t.Errorf("%s: got %+v", tt.body, q)
+		}
+	}
+	var q query
+	fields := fieldErrors(t, bindRequest(t, "/items/7", `{"name":"a","page":"x"}`, &q))
+	if len(fields) != 0 {
+		t.Errorf("got %+v", fields)
+	}
+}
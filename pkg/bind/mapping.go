@@ -0,0 +1,158 @@
+package bind
+
+import (
+	"encoding"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	durationType = reflect.TypeOf(time.Duration(0))
+	textType     = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	jsonType     = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+)
+
+// mapValues 将 query、form、path 参数写入结构体，参数名依次取 tag、json 标签和字段名，
+// 匿名嵌入的结构体（如 guava.Paged）展开处理，无法转换的参数记为 type 错误
+func mapValues(v reflect.Value, values map[string][]string, tag string, errs *[]FieldError) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.PkgPath != "" && !sf.Anonymous {
+			continue
+		}
+		fv := v.Field(i)
+		if sf.Anonymous && isNested(sf.Type) {
+			if fv.Kind() == reflect.Ptr {
+				if fv.IsNil() {
+					fv.Set(reflect.New(sf.Type.Elem()))
+				}
+				fv = fv.Elem()
+			}
+			mapValues(fv, values, tag, errs)
+			continue
+		}
+		name := fieldName(sf, tag)
+		if name == "-" {
+			continue
+		}
+		vals, ok := values[name]
+		if !ok || len(vals) == 0 {
+			continue
+		}
+		if err := setField(fv, vals); err != nil {
+			*errs = append(*errs, FieldError{Field: name, Rule: "type", Param: sf.Type.String()})
+		}
+	}
+}
+
+func fieldName(sf reflect.StructField, tag string) string {
+	for _, key := range []string{tag, "json"} {
+		if name := strings.Split(sf.Tag.Get(key), ",")[0]; name != "" {
+			return name
+		}
+	}
+	return sf.Name
+}
+
+// isNested 是否为需要展开的普通结构体，time.Time 等可直接解析的类型除外
+func isNested(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Struct && !isScalar(t)
+}
+
+func isScalar(t reflect.Type) bool {
+	p := reflect.PtrTo(t)
+	return t == timeType || p.Implements(textType) || p.Implements(jsonType)
+}
+
+func setField(fv reflect.Value, vals []string) error {
+	if fv.Kind() == reflect.Ptr {
+		if fv.IsNil() {
+			fv.Set(reflect.New(fv.Type().Elem()))
+		}
+		return setField(fv.Elem(), vals)
+	}
+	if fv.Kind() == reflect.Slice && fv.Type().Elem().Kind() != reflect.Uint8 {
+		slice := reflect.MakeSlice(fv.Type(), len(vals), len(vals))
+		for i, s := range vals {
+			if err := setScalar(slice.Index(i), s); err != nil {
+				return err
+			}
+		}
+		fv.Set(slice)
+		return nil
+	}
+	return setScalar(fv, vals[0])
+}
+
+func setScalar(fv reflect.Value, s string) error {
+	s = strings.TrimSpace(s)
+	if fv.CanAddr() {
+		addr := fv.Addr()
+		if addr.Type().Implements(textType) {
+			return addr.Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s))
+		}
+		if addr.Type().Implements(jsonType) {
+			return addr.Interface().(json.Unmarshaler).UnmarshalJSON([]byte(strconv.Quote(s)))
+		}
+	}
+	if s == "" {
+		return nil
+	}
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		fv.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if fv.Type() == durationType {
+			d, err := time.ParseDuration(s)
+			if err != nil {
+				return err
+			}
+			fv.SetInt(int64(d))
+			return nil
+		}
+		n, err := strconv.ParseInt(s, 10, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(s, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetFloat(n)
+	case reflect.Struct:
+		if fv.Type() != timeType {
+			return errors.New("unsupported type " + fv.Type().String())
+		}
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			return err
+		}
+		fv.Set(reflect.ValueOf(t))
+	default:
+		return errors.New("unsupported type " + fv.Type().String())
+	}
+	return nil
+}
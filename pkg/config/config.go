@@ -6,19 +6,73 @@ import (
 	"gopkg.in/yaml.v2"
 	"os"
 	"path/filepath"
+	"strconv"
+	"time"
 )
 
 type Config struct {
-	Host  string
-	Port  string
-	Name  string
-	Tag   []string
-	Mysql Mysql
-	DM    DM
-	Redis Redis
-	Mq    Mq
-	Feign Feign
-	Log   Log
+	Host   string
+	Port   string
+	Name   string
+	Tag    []string
+	Lang   string // 默认的提示语言，请求未携带 Accept-Language 时使用
+	Mysql  Mysql
+	DM     DM
+	Redis  Redis
+	Mq     Mq
+	Feign  Feign
+	Log    Log
+	Auth   Auth
+	Tenant Tenant
+	Json   Json
+	// Prefix 环境变量 DB_PREFIX，已加在 Mysql/DM 库名和 MQ vhost 前
+	Prefix string `yaml:"-"`
+}
+
+// Json JSON 编解码配置，作用于 guava/json、middleware.Render、middleware.BindJSON 和 bind.Bind；
+// gin 自身的 c.JSON 和 ShouldBindJSON 始终使用构建标签选择的实现，仅 UseNumber、DisallowUnknownFields 对其生效
+type Json struct {
+	Codec                 string // std 或 jsoniter，为空时使用默认实现
+	UseNumber             bool   `yaml:"use-number"`
+	DisallowUnknownFields bool   `yaml:"disallow-unknown-fields"`
+	CaseSensitive         bool   `yaml:"case-sensitive"` // 仅 jsoniter 支持
+}
+
+// Tenant 多租户配置，租户 ID 依次从 JWT 的 tenant 声明、请求头和子域名解析
+type Tenant struct {
+	Enable    bool
+	Header    string // 携带租户 ID 的请求头，默认 X-Tenant-Id
+	Subdomain bool   // 是否从 Host 的第一段解析租户
+	Default   string // 未解析到租户时使用，为空则拒绝请求
+	// Tenants 已知租户白名单，非空时拒绝未配置的租户；未配置的字段按租户 ID 推导。
+	// 请求头和子域名可由调用方任意指定，为空时只接受 JWT 的 tenant 声明和 Default
+	Tenants map[string]TenantConf
+}
+
+type TenantConf struct {
+	DbPrefix    string `yaml:"db-prefix"`    // 库名前缀，默认 "<id>_"
+	TablePrefix string `yaml:"table-prefix"` // 表名前缀，默认使用 mysql.table-prefix
+	RedisPrefix string `yaml:"redis-prefix"` // Redis 键前缀，默认 "<id>:"
+	VirtualHost string `yaml:"virtual-host"` // MQ vhost，默认为库名前缀加配置的 vhost
+}
+
+// Auth JWT 认证配置，验签公钥按 Jwks、ConsulKey、PublicKey/Secret 的顺序选取
+type Auth struct {
+	Enable     bool
+	Alg        string        // HS256、RS256 或 ES256
+	Secret     string        // HS256 密钥
+	PublicKey  string        `yaml:"public-key"`  // RS256/ES256 的 PEM 公钥
+	PrivateKey string        `yaml:"private-key"` // 签发 token 使用的 PEM 私钥
+	Jwks       string        // 本地 JWKS 文件路径
+	ConsulKey  string        `yaml:"consul-key"` // 存放 JWKS 的 Consul KV 键
+	Refresh    time.Duration // JWKS 文件的重新加载间隔
+	Issuer     string
+	Audience   string
+	Expire     time.Duration // 签发 token 的有效期
+	Skip       []string      // 不需要认证的路径前缀
+	// Roles 角色到权限的映射，权限格式为 resource:action，如 admin: ["*:*"]；
+	// 配置后 auth.Init 据此创建 auth.DefaultPolicy，路由通过 auth.Require 声明权限
+	Roles map[string][]string
 }
 type Log struct {
 	Level   string
@@ -42,6 +96,19 @@ type Mysql struct {
 	Password string
 	Database string
 	ShowSql  bool
+	// TablePrefix 表名前缀，默认 us_，设为 - 表示不加前缀
+	TablePrefix string `yaml:"table-prefix"`
+	Mapper      string // 表名和列名映射：snake（默认）、same 或 gonic
+	Timezone    string // 连接和读写时间使用的时区，默认 Local
+	Charset     string // 默认 utf8mb4
+	Collation   string
+	MaxIdle     int               `yaml:"max-idle"`
+	MaxOpen     int               `yaml:"max-open"`
+	MaxLifetime time.Duration     `yaml:"max-lifetime"`
+	Params      map[string]string // 追加到 DSN 的参数
+	// SlowThreshold 慢查询阈值，默认 200ms
+	SlowThreshold time.Duration `yaml:"slow-threshold"`
+	Explain       bool          // 非 release 模式下对慢 SELECT 执行 EXPLAIN
 }
 type DM struct {
 	Host     string
@@ -60,13 +127,23 @@ type Redis struct {
 }
 
 type Mq struct {
-	Host        string
-	Port        string
-	Username    string
-	Password    string
-	VirtualHost string `yaml:"virtual-host"`
-	Queues      []string
-	Exchange    string
+	Host         string
+	Port         string
+	Username     string
+	Password     string
+	VirtualHost  string `yaml:"virtual-host"`
+	Queues       []string
+	Exchange     string
+	ExchangeType string `yaml:"exchange-type"`
+	// Policies 按队列名配置消费失败后的重试策略，未配置的队列使用默认策略
+	Policies map[string]MqPolicy
+}
+
+type MqPolicy struct {
+	MaxRetries int           `yaml:"max-retries"`
+	Delay      time.Duration // 首次重试延迟，如 1s
+	MaxDelay   time.Duration `yaml:"max-delay"`
+	Multiplier float64
 }
 
 func ParseConfig(value string) (setting *Config, err error) {
@@ -81,7 +158,11 @@ func ParseConfig(value string) (setting *Config, err error) {
 	if set.Host == "" {
 		set.Host = guava.GetEnv("SERVER_HOST", "127.0.0.1")
 	}
+	if set.Lang == "" {
+		set.Lang = guava.GetEnv("SERVER_LANG", "zh")
+	}
 	prefix := guava.GetEnv("DB_PREFIX", "")
+	set.Prefix = prefix
 	if set.Mysql.Host == "" {
 		set.Mysql.Host = guava.GetEnv("MYSQL_HOST", "127.0.0.1")
 	}
@@ -98,6 +179,30 @@ func ParseConfig(value string) (setting *Config, err error) {
 		set.Mysql.Database = "us_diagram"
 	}
 	set.Mysql.Database = prefix + set.Mysql.Database
+	if set.Mysql.TablePrefix == "" {
+		set.Mysql.TablePrefix = guava.GetEnv("MYSQL_TABLE_PREFIX", "us_")
+	}
+	if set.Mysql.Mapper == "" {
+		set.Mysql.Mapper = "snake"
+	}
+	if set.Mysql.Timezone == "" {
+		set.Mysql.Timezone = guava.GetEnv("MYSQL_TIMEZONE", "Local")
+	}
+	if set.Mysql.Charset == "" {
+		set.Mysql.Charset = "utf8mb4"
+	}
+	if set.Mysql.MaxIdle <= 0 {
+		set.Mysql.MaxIdle = 10
+	}
+	if set.Mysql.MaxOpen <= 0 {
+		set.Mysql.MaxOpen = 10
+	}
+	if set.Mysql.MaxLifetime <= 0 {
+		set.Mysql.MaxLifetime = time.Hour
+	}
+	if set.Mysql.SlowThreshold <= 0 {
+		set.Mysql.SlowThreshold = 200 * time.Millisecond
+	}
 
 	if set.DM.Host == "" {
 		set.DM.Host = guava.GetEnv("DM_HOST", "127.0.0.1")
@@ -150,6 +255,9 @@ func ParseConfig(value string) (setting *Config, err error) {
 	if set.Mq.Exchange == "" {
 		set.Mq.Exchange = guava.GetEnv("RABBIT_EXCHANGE", "push")
 	}
+	if set.Mq.ExchangeType == "" {
+		set.Mq.ExchangeType = guava.GetEnv("RABBIT_EXCHANGE_TYPE", "topic")
+	}
 	if set.Mq.Queues == nil || len(set.Mq.Queues) == 0 {
 		set.Mq.Queues = []string{"line", "diagram", "global"}
 	}
@@ -168,15 +276,58 @@ func ParseConfig(value string) (setting *Config, err error) {
 	if set.Feign.Diagram == "" {
 		set.Feign.Diagram = "us-diagram"
 	}
+	if set.Auth.Alg == "" {
+		set.Auth.Alg = "HS256"
+	}
+	if set.Auth.Secret == "" {
+		set.Auth.Secret = guava.GetEnv("JWT_SECRET", "")
+	}
+	if set.Auth.Refresh <= 0 {
+		set.Auth.Refresh = 5 * time.Minute
+	}
+	if set.Auth.Expire <= 0 {
+		set.Auth.Expire = time.Hour
+	}
+	if set.Auth.Skip == nil {
+		set.Auth.Skip = []string{"/health", "/metrics"}
+	}
+	if set.Tenant.Header == "" {
+		set.Tenant.Header = "X-Tenant-Id"
+	}
 	if set.Log.Level == "" {
 		set.Log.Level = "info"
 	}
 	if set.Log.Path == "" {
 		set.Log.Path = "/root/projects/us-edps.log"
 	}
+	if err = set.validate(); err != nil {
+		return nil, err
+	}
 	return set, nil
 }
 
+// validate 检查以字符串保存的端口等数字配置，只接受十进制数字，避免使用时被静默转换为 0
+func (c *Config) validate() error {
+	for _, f := range []struct{ name, value string }{
+		{"port", c.Port},
+		{"mysql.port", c.Mysql.Port},
+		{"dm.port", c.DM.Port},
+		{"redis.port", c.Redis.Port},
+		{"redis.database", c.Redis.Database},
+		{"mq.port", c.Mq.Port},
+	} {
+		if f.value == "" {
+			continue
+		}
+		if n, err := strconv.ParseUint(f.value, 10, 16); err != nil {
+			return fmt.Errorf("config %s: %q is not a number in 0-65535", f.name, f.value)
+		} else if n == 0 && f.name != "redis.database" {
+			return fmt.Errorf("config %s: port must be positive", f.name)
+		}
+	}
+	return nil
+}
+
 func NewConfig(configPath string) (*Config, error) {
 	if err := validateConfigPath(configPath); err != nil {
 		return nil, err
@@ -204,3 +355,27 @@ func validateConfigPath(path string) error {
 	}
 	return nil
 }
+
+// Policy 返回队列的重试策略，未配置的字段使用默认值；MaxRetries 为负数表示不重试
+func (m Mq) Policy(queue string) MqPolicy {
+	p := m.Policies[queue]
+	if p.MaxRetries == 0 {
+		p.MaxRetries = 3
+	}
+	if p.MaxRetries < 0 {
+		p.MaxRetries = 0
+	}
+	if p.Delay <= 0 {
+		p.Delay = time.Second
+	}
+	if p.Multiplier < 1 {
+		p.Multiplier = 2
+	}
+	if p.MaxDelay == 0 {
+		p.MaxDelay = time.Minute
+	}
+	if p.MaxDelay < p.Delay {
+		p.MaxDelay = p.Delay
+	}
+	return p
+}
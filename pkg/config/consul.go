@@ -22,6 +22,8 @@ type Cloud interface {
 	Register(serverName string, serverIp string, serverPort int) error
 	UnRegister(serverName string, serverIp string, serverPort int) error
 	GetServerUri(name string) (string, error)
+	// GetKV 读取 KV，waitIndex 大于 0 时阻塞到值变化或超时，返回新的 index 用于下次等待
+	GetKV(key string, waitIndex uint64) ([]byte, uint64, error)
 }
 
 var Client Cloud
@@ -111,6 +113,17 @@ func (c *cloud) GetServerUri(serviceName string) (uri string, err error) {
 	return urls[i], err
 }
 
+func (c *cloud) GetKV(key string, waitIndex uint64) ([]byte, uint64, error) {
+	kvp, meta, err := c.consul.KV().Get(key, &consul.QueryOptions{WaitIndex: waitIndex})
+	if err != nil {
+		return nil, waitIndex, err
+	}
+	if kvp == nil {
+		return nil, meta.LastIndex, fmt.Errorf("consul key %s not found", key)
+	}
+	return kvp.Value, meta.LastIndex, nil
+}
+
 func ServerId(serverName string, serverIp string, serverPort int) string {
 	return serverName + "-" + serverIp + "-" + strconv.Itoa(serverPort)
 }
@@ -0,0 +1,349 @@
+package database
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/ilooky/go-layout/pkg/guava/json"
+	"xorm.io/builder"
+	"xorm.io/xorm"
+	"xorm.io/xorm/schemas"
+)
+
+// BatchSize 批量写入时每条语句的行数，避免超过 MySQL 的占位符数量限制
+var BatchSize = 500
+
+// ErrStop 由 Iterate 的回调返回以提前结束遍历，Iterate 返回 nil
+var ErrStop = errors.New("stop iteration")
+
+// SaveBatch 在一个事务中按 size 分批插入 beans（[]T 或 []*T），size <= 0 时使用 BatchSize
+func SaveBatch(ctx context.Context, beans interface{}, size int) (int64, error) {
+	db, err := Engine(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if reflect.Indirect(reflect.ValueOf(beans)).Kind() != reflect.Slice {
+		return 0, fmt.Errorf("SaveBatch: expect slice, got %T", beans)
+	}
+	if size <= 0 {
+		size = BatchSize
+	}
+	total, err := db.Transaction(func(session *xorm.Session) (interface{}, error) {
+		session.Context(ctx)
+		return insertChunks(session, beans, size)
+	})
+	if err != nil {
+		return 0, err
+	}
+	notifyInsert(ctx, db, beans)
+	return total.(int64), nil
+}
+
+// insertChunks 切片按 size 分批插入，其他实体直接插入
+func insertChunks(session *xorm.Session, beans interface{}, size int) (int64, error) {
+	v := reflect.Indirect(reflect.ValueOf(beans))
+	if v.Kind() != reflect.Slice {
+		n, err := session.Insert(beans)
+		return n, wrapErr(err)
+	}
+	var total int64
+	for i := 0; i < v.Len(); i += size {
+		end := i + size
+		if end > v.Len() {
+			end = v.Len()
+		}
+		n, err := session.Insert(v.Slice(i, end).Interface())
+		if err != nil {
+			return total, wrapErr(err)
+		}
+		total += n
+	}
+	return total, nil
+}
+
+func notifyInsert(ctx context.Context, db *xorm.Engine, beans interface{}) {
+	if len(hooks) == 0 {
+		return
+	}
+	v := reflect.Indirect(reflect.ValueOf(beans))
+	if v.Kind() != reflect.Slice {
+		if table, id, ok := identify(db, beans); ok {
+			notify(ctx, Change{Op: OpInsert, Table: table, Id: id, After: beans})
+		}
+		return
+	}
+	for i := 0; i < v.Len(); i++ {
+		e := elem(v.Index(i))
+		if table, id, ok := identify(db, e); ok {
+			notify(ctx, Change{Op: OpInsert, Table: table, Id: id, After: e})
+		}
+	}
+}
+
+func elem(v reflect.Value) interface{} {
+	if v.Kind() != reflect.Ptr && v.CanAddr() {
+		return v.Addr().Interface()
+	}
+	return v.Interface()
+}
+
+// Upsert 按主键或唯一键插入或更新 beans（单个实体指针或切片），updateCols 为冲突时更新的列，
+// 为空时更新除主键、创建时间、版本号和软删除外的全部列；有版本号时更新后版本号加 1。
+// MySQL 使用 ON DUPLICATE KEY UPDATE，达梦和 Oracle 使用按主键匹配的 MERGE INTO；
+// 自增主键为 0 的行总是插入并由数据库生成主键。无法区分插入和更新，因此不触发变更钩子
+func Upsert(ctx context.Context, beans interface{}, updateCols ...string) (int64, error) {
+	db, err := Engine(ctx)
+	if err != nil {
+		return 0, err
+	}
+	v := reflect.Indirect(reflect.ValueOf(beans))
+	rows := make([]interface{}, 0)
+	if v.Kind() == reflect.Slice {
+		for i := 0; i < v.Len(); i++ {
+			rows = append(rows, elem(v.Index(i)))
+		}
+	} else {
+		rows = append(rows, beans)
+	}
+	if len(rows) == 0 {
+		return 0, nil
+	}
+	table, err := db.TableInfo(rows[0])
+	if err != nil {
+		return 0, err
+	}
+	var total int64
+	_, err = db.Transaction(func(session *xorm.Session) (interface{}, error) {
+		session.Context(ctx)
+		for i := 0; i < len(rows); i += BatchSize {
+			end := i + BatchSize
+			if end > len(rows) {
+				end = len(rows)
+			}
+			n, err := upsert(session, db, table, rows[i:end], updateCols)
+			if err != nil {
+				return nil, wrapErr(err)
+			}
+			total += n
+		}
+		return nil, nil
+	})
+	return total, err
+}
+
+func upsert(session *xorm.Session, db *xorm.Engine, table *schemas.Table, rows []interface{}, updateCols []string) (int64, error) {
+	cols := upsertCols(table)
+	updateCols = conflictCols(table, cols, updateCols)
+	names := make([]string, len(cols))
+	for i, col := range cols {
+		names[i] = db.Quote(col.Name)
+	}
+	switch dbType := db.Dialect().URI().DBType; dbType {
+	case schemas.MYSQL:
+		var sb strings.Builder
+		args := make([]interface{}, 0, len(rows)*len(cols))
+		sb.WriteString("INSERT INTO " + db.Quote(table.Name) + " (" + strings.Join(names, ",") + ") VALUES ")
+		marks := "(" + strings.TrimSuffix(strings.Repeat("?,", len(cols)), ",") + ")"
+		for i, row := range rows {
+			if i > 0 {
+				sb.WriteString(",")
+			}
+			sb.WriteString(marks)
+			values, err := rowValues(db, cols, row)
+			if err != nil {
+				return 0, err
+			}
+			args = append(args, values...)
+		}
+		sets := make([]string, 0, len(updateCols)+1)
+		for _, c := range updateCols {
+			sets = append(sets, db.Quote(c)+"=VALUES("+db.Quote(c)+")")
+		}
+		if table.Version != "" {
+			sets = append(sets, db.Quote(table.Version)+"="+db.Quote(table.Version)+"+1")
+		}
+		sb.WriteString(" ON DUPLICATE KEY UPDATE " + strings.Join(sets, ","))
+		res, err := session.Exec(append([]interface{}{sb.String()}, args...)...)
+		if err != nil {
+			return 0, err
+		}
+		return res.RowsAffected()
+	case schemas.ORACLE, "dm":
+		merge := mergeSQL(db, table, cols, updateCols)
+		var total int64
+		for _, row := range rows {
+			values, err := rowValues(db, cols, row)
+			if err != nil {
+				return total, err
+			}
+			res, err := session.Exec(append([]interface{}{merge}, values...)...)
+			if err != nil {
+				return total, err
+			}
+			n, _ := res.RowsAffected()
+			total += n
+		}
+		return total, nil
+	default:
+		return 0, fmt.Errorf("upsert is not supported for %s", dbType)
+	}
+}
+
+// mergeSQL 源数据包含主键用于匹配，自增主键不出现在 INSERT 的列中，由数据库生成
+func mergeSQL(db *xorm.Engine, table *schemas.Table, cols []*schemas.Column, updateCols []string) string {
+	selects := make([]string, 0, len(cols))
+	names := make([]string, 0, len(cols))
+	values := make([]string, 0, len(cols))
+	for _, col := range cols {
+		name := db.Quote(col.Name)
+		selects = append(selects, "? "+name)
+		if col.IsAutoIncrement {
+			continue
+		}
+		names = append(names, name)
+		values = append(values, "s."+name)
+	}
+	on := make([]string, 0, len(table.PrimaryKeys))
+	for _, pk := range table.PrimaryKeys {
+		on = append(on, "t."+db.Quote(pk)+"=s."+db.Quote(pk))
+	}
+	sets := make([]string, 0, len(updateCols)+1)
+	for _, c := range updateCols {
+		sets = append(sets, "t."+db.Quote(c)+"=s."+db.Quote(c))
+	}
+	if table.Version != "" {
+		sets = append(sets, "t."+db.Quote(table.Version)+"=t."+db.Quote(table.Version)+"+1")
+	}
+	return "MERGE INTO " + db.Quote(table.Name) + " t USING (SELECT " + strings.Join(selects, ",") + " FROM DUAL) s" +
+		" ON (" + strings.Join(on, " AND ") + ")" +
+		" WHEN MATCHED THEN UPDATE SET " + strings.Join(sets, ",") +
+		" WHEN NOT MATCHED THEN INSERT (" + strings.Join(names, ",") + ") VALUES (" + strings.Join(values, ",") + ")"
+}
+
+// upsertCols 在 insertCols 前加上自增主键，按主键匹配已有的行；主键为 0 时 MySQL 仍会自动生成
+func upsertCols(table *schemas.Table) []*schemas.Column {
+	var cols []*schemas.Column
+	for _, col := range table.PKColumns() {
+		if col.IsAutoIncrement {
+			cols = append(cols, col)
+		}
+	}
+	return append(cols, insertCols(table)...)
+}
+
+// conflictCols 冲突时更新的列，始终排除版本号，由语句自增；
+// updateCols 为空时取除主键、创建时间、版本号和软删除外的全部列
+func conflictCols(table *schemas.Table, cols []*schemas.Column, updateCols []string) []string {
+	var list []string
+	if len(updateCols) == 0 {
+		for _, col := range cols {
+			if !col.IsPrimaryKey && !col.IsCreated && !col.IsVersion && !col.IsDeleted {
+				list = append(list, col.Name)
+			}
+		}
+		return list
+	}
+	for _, c := range updateCols {
+		if !strings.EqualFold(c, table.Version) {
+			list = append(list, c)
+		}
+	}
+	return list
+}
+
+// insertCols 写入时使用的列，排除自增主键、软删除列和只读列
+func insertCols(table *schemas.Table) []*schemas.Column {
+	cols := make([]*schemas.Column, 0, len(table.Columns()))
+	for _, col := range table.Columns() {
+		if col.IsAutoIncrement || col.IsDeleted || col.MapType == schemas.ONLYFROMDB {
+			continue
+		}
+		cols = append(cols, col)
+	}
+	return cols
+}
+
+func rowValues(db *xorm.Engine, cols []*schemas.Column, row interface{}) ([]interface{}, error) {
+	now := time.Now().In(db.TZLocation)
+	values := make([]interface{}, 0, len(cols))
+	for _, col := range cols {
+		switch {
+		case col.IsCreated || col.IsUpdated:
+			values = append(values, now)
+			continue
+		case col.IsVersion:
+			values = append(values, 1)
+			continue
+		}
+		fv, err := col.ValueOf(row)
+		if err != nil {
+			return nil, err
+		}
+		value, err := toDriver(col, *fv)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// toDriver 将字段值转换为驱动可以接受的类型
+func toDriver(col *schemas.Column, v reflect.Value) (interface{}, error) {
+	if valuer, ok := v.Interface().(driver.Valuer); ok {
+		return valuer.Value()
+	}
+	if col.IsJSON {
+		return json.Marshal(v.Interface())
+	}
+	if v.Kind() == reflect.Struct && v.Type().ConvertibleTo(timeType) {
+		t := v.Convert(timeType).Interface().(time.Time)
+		if t.IsZero() {
+			return nil, nil
+		}
+		return t, nil
+	}
+	return v.Interface(), nil
+}
+
+// Iterate 使用游标逐行读取满足 cond 的实体并交给 fn 处理，内存中只保留当前行；
+// fn 处理完成后才读取下一行，fn 返回 ErrStop 时提前结束，返回其他错误时中止并返回该错误
+func Iterate(ctx context.Context, bean interface{}, cond builder.Cond, fn func(bean interface{}) error) error {
+	db, err := Engine(ctx)
+	if err != nil {
+		return err
+	}
+	session := db.Context(ctx)
+	if cond != nil {
+		session.Where(cond)
+	}
+	rows, err := session.Rows(bean)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	typ := reflect.Indirect(reflect.ValueOf(bean)).Type()
+	for rows.Next() {
+		if err = ctx.Err(); err != nil {
+			return err
+		}
+		row := reflect.New(typ).Interface()
+		if err = rows.Scan(row); err != nil {
+			return err
+		}
+		if err = fn(row); err != nil {
+			if errors.Is(err, ErrStop) {
+				return nil
+			}
+			return err
+		}
+	}
+	return rows.Err()
+}
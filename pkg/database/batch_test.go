@@ -0,0 +1,74 @@
+package database
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/ilooky/go-layout/pkg/database/dbtest"
+)
+
+var (
+	assignValues = regexp.MustCompile("^`(\\w+)`=VALUES\\(`(\\w+)`\\)$")
+	assignIncr   = regexp.MustCompile("^`(\\w+)`=`(\\w+)`\\+1$")
+)
+
+// applyUpsert 按 MySQL 从左到右的赋值顺序，将单行 INSERT ... ON DUPLICATE KEY UPDATE 应用到已有的行
+func applyUpsert(t *testing.T, s dbtest.Stmt, row map[string]interface{}) {
+	head := s.SQL[strings.Index(s.SQL, "(")+1 : strings.Index(s.SQL, ") VALUES")]
+	inserted := map[string]interface{}{}
+	for i, col := range strings.Split(head, ",") {
+		inserted[strings.Trim(col, "`")] = s.Args[i]
+	}
+	sets := s.SQL[strings.Index(s.SQL, "ON DUPLICATE KEY UPDATE ")+len("ON DUPLICATE KEY UPDATE "):]
+	for _, set := range strings.Split(sets, ",") {
+		if m := assignValues.FindStringSubmatch(set); m != nil {
+			row[m[1]] = inserted[m[2]]
+		} else if m = assignIncr.FindStringSubmatch(set); m != nil {
+			row[m[1]] = row[m[2]].(int64) + 1
+		} else {
+			t.Fatalf("unexpected assignment %s", set)
+		}
+	}
+}
+
+func TestUpsertVersion(t *testing.T) {
+	d := &dbtest.Driver{}
+	useEngine(t, d)
+	for _, cols := range [][]string{nil, {"name", "version"}} {
+		d.Reset()
+		if _, err := Upsert(context.Background(), &versioned{Id: 7, Name: "b", Version: 5}, cols...); err != nil {
+			t.Fatal(err)
+		}
+		execs := d.Execs()
+		if len(execs) != 3 {
+			t.Fatalf("executed %d statements, want BEGIN, INSERT, COMMIT", len(execs))
+		}
+		row := map[string]interface{}{"id": int64(7), "name": "a", "version": int64(5)}
+		applyUpsert(t, execs[1], row)
+		if row["version"] != int64(6) || row["name"] != "b" {
+			t.Errorf("update cols %v: row = %v, want version 6", cols, row)
+		}
+	}
+}
+
+func TestMergeSQL(t *testing.T) {
+	db := useEngine(t, &dbtest.Driver{})
+	table, err := db.TableInfo(new(versioned))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cols := upsertCols(table)
+	sql := mergeSQL(db, table, cols, conflictCols(table, cols, nil))
+	for _, want := range []string{
+		"SELECT ? `id`,? `name`,? `version` FROM DUAL",
+		"ON (t.`id`=s.`id`)",
+		"UPDATE SET t.`name`=s.`name`,t.`version`=t.`version`+1",
+		"INSERT (`name`,`version`) VALUES (s.`name`,s.`version`)",
+	} {
+		if !strings.Contains(sql, want) {
+			t.Errorf("merge %s\nmissing %s", sql, want)
+		}
+	}
+}
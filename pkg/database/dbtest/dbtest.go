@@ -0,0 +1,173 @@
+// Package dbtest 提供内存中的 database/sql 驱动，用于在没有数据库的测试中构造 xorm.Engine：
+// 查询返回预设的行，执行语句按 Affected 返回影响行数，执行过的语句和事务边界按顺序记录。
+package dbtest
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"strconv"
+	"sync"
+	"testing"
+
+	"xorm.io/xorm"
+	"xorm.io/xorm/core"
+	"xorm.io/xorm/dialects"
+)
+
+// Stmt 一条执行过的语句，事务边界记录为 BEGIN、COMMIT 和 ROLLBACK
+type Stmt struct {
+	SQL   string
+	Args  []driver.Value
+	Query bool
+}
+
+// Driver 测试用驱动，字段需在 Open 之前设置
+type Driver struct {
+	Columns []string
+	Rows    [][]driver.Value
+	// Query 非 nil 时按语句返回查询结果，否则返回 Columns 和 Rows
+	Query func(sql string, args []driver.Value) ([]string, [][]driver.Value)
+	// Affected 返回执行语句的影响行数，为 nil 时为 0
+	Affected func(sql string, args []driver.Value) int64
+
+	mu  sync.Mutex
+	log []Stmt
+}
+
+var (
+	mu  sync.Mutex
+	seq int
+)
+
+// Open 注册 d 并返回使用 MySQL 方言的 engine
+func Open(t testing.TB, d *Driver) *xorm.Engine {
+	mu.Lock()
+	seq++
+	name := "dbtest" + strconv.Itoa(seq)
+	mu.Unlock()
+	sql.Register(name, d)
+	db, err := core.Open(name, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dialect, err := dialects.OpenDialect("mysql", "root:@tcp(localhost:3306)/test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, err := xorm.NewEngineWithDialectAndDB(name, "", dialect, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = e.Close() })
+	return e
+}
+
+// Log 按顺序返回执行过的语句，包括查询
+func (d *Driver) Log() []Stmt {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]Stmt(nil), d.log...)
+}
+
+// Execs 返回执行过的非查询语句
+func (d *Driver) Execs() []Stmt {
+	var execs []Stmt
+	for _, s := range d.Log() {
+		if !s.Query {
+			execs = append(execs, s)
+		}
+	}
+	return execs
+}
+
+// Queries 返回执行过的查询语句
+func (d *Driver) Queries() []Stmt {
+	var queries []Stmt
+	for _, s := range d.Log() {
+		if s.Query {
+			queries = append(queries, s)
+		}
+	}
+	return queries
+}
+
+// Reset 清空记录
+func (d *Driver) Reset() {
+	d.mu.Lock()
+	d.log = nil
+	d.mu.Unlock()
+}
+
+func (d *Driver) record(s Stmt) {
+	d.mu.Lock()
+	d.log = append(d.log, s)
+	d.mu.Unlock()
+}
+
+func (d *Driver) Open(string) (driver.Conn, error) { return conn{d}, nil }
+
+type conn struct{ d *Driver }
+
+func (c conn) Prepare(query string) (driver.Stmt, error) { return stmt{c.d, query}, nil }
+func (c conn) Close() error                              { return nil }
+
+func (c conn) Begin() (driver.Tx, error) {
+	c.d.record(Stmt{SQL: "BEGIN"})
+	return tx(c), nil
+}
+
+type tx struct{ d *Driver }
+
+func (t tx) Commit() error {
+	t.d.record(Stmt{SQL: "COMMIT"})
+	return nil
+}
+
+func (t tx) Rollback() error {
+	t.d.record(Stmt{SQL: "ROLLBACK"})
+	return nil
+}
+
+type stmt struct {
+	d   *Driver
+	sql string
+}
+
+func (s stmt) Close() error  { return nil }
+func (s stmt) NumInput() int { return -1 }
+
+func (s stmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.d.record(Stmt{SQL: s.sql, Args: args})
+	var n int64
+	if s.d.Affected != nil {
+		n = s.d.Affected(s.sql, args)
+	}
+	return driver.RowsAffected(n), nil
+}
+
+func (s stmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.d.record(Stmt{SQL: s.sql, Args: args, Query: true})
+	cols, data := s.d.Columns, s.d.Rows
+	if s.d.Query != nil {
+		cols, data = s.d.Query(s.sql, args)
+	}
+	return &rows{cols: cols, data: data}, nil
+}
+
+type rows struct {
+	cols []string
+	data [][]driver.Value
+}
+
+func (r *rows) Columns() []string { return r.cols }
+func (r *rows) Close() error      { return nil }
+
+func (r *rows) Next(dest []driver.Value) error {
+	if len(r.data) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.data[0])
+	r.data = r.data[1:]
+	return nil
+}
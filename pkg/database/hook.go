@@ -0,0 +1,77 @@
+package database
+
+import (
+	"context"
+	"reflect"
+
+	"xorm.io/xorm"
+	"xorm.io/xorm/schemas"
+)
+
+type Op string
+
+const (
+	OpInsert Op = "insert"
+	OpUpdate Op = "update"
+	OpDelete Op = "delete"
+)
+
+// Change 一次成功的实体变更，Before 在 insert 时为 nil，After 在 delete 时为 nil
+type Change struct {
+	Op     Op
+	Table  string
+	Id     interface{}
+	Before interface{}
+	After  interface{}
+}
+
+// Hook 在 Save/Update/Delete 成功后同步调用，ctx 为调用方传入的 context
+type Hook func(ctx context.Context, c Change)
+
+var hooks []Hook
+
+// AddHook 注册变更钩子，需在服务启动时调用
+func AddHook(h Hook) {
+	hooks = append(hooks, h)
+}
+
+func notify(ctx context.Context, c Change) {
+	for _, h := range hooks {
+		h(ctx, c)
+	}
+}
+
+// identify 返回实体的表名和主键值，复合主键返回 schemas.PK
+func identify(db *xorm.Engine, entity interface{}) (string, interface{}, bool) {
+	table, err := db.TableInfo(entity)
+	if err != nil {
+		return "", nil, false
+	}
+	pk := schemas.PK{}
+	for _, col := range table.PKColumns() {
+		v, err := col.ValueOf(entity)
+		if err != nil || v.IsZero() {
+			return table.Name, nil, true
+		}
+		pk = append(pk, v.Interface())
+	}
+	if len(pk) == 1 {
+		return table.Name, pk[0], true
+	}
+	if len(pk) == 0 {
+		return table.Name, nil, true
+	}
+	return table.Name, pk, true
+}
+
+// snapshot 按主键重新读取一份实体，读取失败时返回 nil
+func snapshot(ctx context.Context, db *xorm.Engine, entity interface{}, id interface{}) interface{} {
+	if id == nil {
+		return nil
+	}
+	bean := reflect.New(reflect.Indirect(reflect.ValueOf(entity)).Type()).Interface()
+	if get, err := db.Context(ctx).ID(id).Get(bean); err != nil || !get {
+		return nil
+	}
+	return bean
+}
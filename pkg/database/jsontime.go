@@ -0,0 +1,215 @@
+package database
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ilooky/go-layout/pkg/guava"
+)
+
+// Zone 解析和输出 JSON 时间使用的时区，InitOrm 时设为 mysql.timezone
+var Zone = time.Local
+
+// JsonTime、NullJsonTime 和 JsonDate 的输出格式
+var (
+	TimeFormat guava.DateStyle = guava.YYYY_MM_DD_HH_MM_SS
+	DateFormat guava.DateStyle = guava.YYYY_MM_DD
+)
+
+// Formats 解析时依次尝试的格式；RFC3339 总是最先尝试，纯数字最后按毫秒时间戳解析。
+// 为 guava.Styles 的副本，修改时不影响 guava.ParseAny
+var Formats = append([]guava.DateStyle(nil), guava.Styles...)
+
+// ParseTime 按 Formats 在 Zone 中解析时间，空串和 null 返回零值
+func ParseTime(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if s == "" || s == "null" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return t.In(Zone), nil
+	}
+	for _, style := range Formats {
+		if t, err := guava.ParseDate(s, style, Zone); err == nil {
+			return t, nil
+		}
+	}
+	if ms, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.Unix(0, ms*int64(time.Millisecond)).In(Zone), nil
+	}
+	return time.Time{}, fmt.Errorf("unrecognized time %q", s)
+}
+
+func marshalTime(t time.Time, style guava.DateStyle) []byte {
+	if t.IsZero() {
+		return []byte("null")
+	}
+	return []byte(strconv.Quote(guava.FormatDate(t.In(Zone), style)))
+}
+
+func unmarshalTime(b []byte) (time.Time, error) {
+	return ParseTime(string(bytes.Trim(b, `"`)))
+}
+
+func scanTime(src interface{}) (time.Time, error) {
+	switch v := src.(type) {
+	case nil:
+		return time.Time{}, nil
+	case time.Time:
+		return v, nil
+	case []byte:
+		return ParseTime(string(v))
+	case string:
+		return ParseTime(v)
+	case int64:
+		return time.Unix(0, v*int64(time.Millisecond)).In(Zone), nil
+	}
+	return time.Time{}, fmt.Errorf("cannot scan %T into time", src)
+}
+
+func timeValue(t time.Time) (driver.Value, error) {
+	if t.IsZero() {
+		return nil, nil
+	}
+	return t, nil
+}
+
+// JsonTime 以 TimeFormat 输出，零值输出为 null；xorm 按 time.Time 读写
+type JsonTime time.Time
+
+func (j JsonTime) Time() time.Time {
+	return time.Time(j)
+}
+
+func (j JsonTime) IsZero() bool {
+	return time.Time(j).IsZero()
+}
+
+func (j JsonTime) String() string {
+	return guava.FormatDate(time.Time(j).In(Zone), TimeFormat)
+}
+
+func (j JsonTime) MarshalJSON() ([]byte, error) {
+	return marshalTime(time.Time(j), TimeFormat), nil
+}
+
+func (j *JsonTime) UnmarshalJSON(b []byte) error {
+	t, err := unmarshalTime(b)
+	if err != nil {
+		return err
+	}
+	*j = JsonTime(t)
+	return nil
+}
+
+func (j *JsonTime) UnmarshalText(b []byte) error {
+	return j.UnmarshalJSON(b)
+}
+
+func (j JsonTime) Value() (driver.Value, error) {
+	return timeValue(time.Time(j))
+}
+
+func (j *JsonTime) Scan(src interface{}) error {
+	t, err := scanTime(src)
+	*j = JsonTime(t)
+	return err
+}
+
+// JsonDate 只保留日期，以 DateFormat 输出，解析时截断到 Zone 的零点
+type JsonDate time.Time
+
+func (d JsonDate) Time() time.Time {
+	return time.Time(d)
+}
+
+func (d JsonDate) IsZero() bool {
+	return time.Time(d).IsZero()
+}
+
+func (d JsonDate) String() string {
+	return guava.FormatDate(time.Time(d).In(Zone), DateFormat)
+}
+
+func (d JsonDate) MarshalJSON() ([]byte, error) {
+	return marshalTime(time.Time(d), DateFormat), nil
+}
+
+func (d *JsonDate) UnmarshalJSON(b []byte) error {
+	t, err := unmarshalTime(b)
+	if err != nil {
+		return err
+	}
+	*d = JsonDate(truncateDay(t))
+	return nil
+}
+
+func (d *JsonDate) UnmarshalText(b []byte) error {
+	return d.UnmarshalJSON(b)
+}
+
+func (d JsonDate) Value() (driver.Value, error) {
+	return timeValue(time.Time(d))
+}
+
+func (d *JsonDate) Scan(src interface{}) error {
+	t, err := scanTime(src)
+	*d = JsonDate(truncateDay(t))
+	return err
+}
+
+func truncateDay(t time.Time) time.Time {
+	if t.IsZero() {
+		return t
+	}
+	t = t.In(Zone)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, Zone)
+}
+
+// NullJsonTime 可为空的时间，Valid 为 false 时 JSON 和数据库中均为 null；
+// 字段需声明 xorm:"datetime"，否则 xorm 建表时会使用文本类型
+type NullJsonTime struct {
+	Time  time.Time
+	Valid bool
+}
+
+func NewNullJsonTime(t time.Time) NullJsonTime {
+	return NullJsonTime{Time: t, Valid: !t.IsZero()}
+}
+
+func (n NullJsonTime) MarshalJSON() ([]byte, error) {
+	if !n.Valid {
+		return []byte("null"), nil
+	}
+	return marshalTime(n.Time, TimeFormat), nil
+}
+
+func (n *NullJsonTime) UnmarshalJSON(b []byte) error {
+	t, err := unmarshalTime(b)
+	if err != nil {
+		return err
+	}
+	*n = NewNullJsonTime(t)
+	return nil
+}
+
+func (n *NullJsonTime) UnmarshalText(b []byte) error {
+	return n.UnmarshalJSON(b)
+}
+
+func (n NullJsonTime) Value() (driver.Value, error) {
+	if !n.Valid {
+		return nil, nil
+	}
+	return n.Time, nil
+}
+
+func (n *NullJsonTime) Scan(src interface{}) error {
+	t, err := scanTime(src)
+	*n = NewNullJsonTime(t)
+	return err
+}
@@ -0,0 +1,90 @@
+package database
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/ilooky/go-layout/pkg/guava"
+)
+
+func TestParseTime(t *testing.T) {
+	shanghai, _ := time.LoadLocation("Asia/Shanghai")
+	Zone = shanghai
+	defer func() { Zone = time.Local }()
+	want := time.Date(2021, 5, 1, 8, 30, 0, 0, shanghai)
+	tests := []struct {
+		in   string
+		want time.Time
+	}{
+		{"2021-05-01 08:30:00", want},
+		{"2021-05-01 08:30", want},
+		{"2021/05/01 08:30:00", want},
+		{"2021年05月01日 08:30", want},
+		{"202105010830", want},
+		{"2021-05-01T00:30:00Z", want},
+		{"1619829000000", want},
+		{"2021-05-01", time.Date(2021, 5, 1, 0, 0, 0, 0, shanghai)},
+		{"", time.Time{}},
+		{"null", time.Time{}},
+	}
+	for _, tt := range tests {
+		got, err := ParseTime(tt.in)
+		if err != nil || !got.Equal(tt.want) {
+			t.Errorf("ParseTime(%q) = %v, %v; want %v", tt.in, got, err, tt.want)
+		}
+	}
+	if _, err := ParseTime("yesterday"); err == nil {
+		t.Error("invalid time accepted")
+	}
+
+	first := guava.Styles[0]
+	Formats[0] = guava.YYYY_MM_DD
+	defer func() { Formats[0] = first }()
+	if guava.Styles[0] != first {
+		t.Error("changing Formats changed guava.Styles")
+	}
+}
+
+func TestJsonTime(t *testing.T) {
+	var v struct {
+		At    JsonTime     `json:"at"`
+		Day   JsonDate     `json:"day"`
+		Maybe NullJsonTime `json:"maybe"`
+		Zero  JsonTime     `json:"zero"`
+	}
+	if err := json.Unmarshal([]byte(`{"at":1619829000000,"day":"2021-05-01 08:30:00","maybe":null,"zero":""}`), &v); err != nil {
+		t.Fatal(err)
+	}
+	if v.Maybe.Valid || !v.Zero.IsZero() || v.Day.Time().Hour() != 0 {
+		t.Errorf("unexpected %+v", v)
+	}
+	v.At = JsonTime(time.Date(2021, 5, 1, 8, 30, 0, 0, time.Local))
+	b, _ := json.Marshal(v)
+	if string(b) != `{"at":"2021-05-01 08:30:00","day":"2021-05-01","maybe":null,"zero":null}` {
+		t.Errorf("marshal = %s", b)
+	}
+	if err := json.Unmarshal([]byte(`{"at":"not a time"}`), &v); err == nil {
+		t.Error("invalid time accepted")
+	}
+}
+
+func TestJsonTimeSql(t *testing.T) {
+	var j JsonTime
+	if err := j.Scan([]byte("2021-05-01 08:30:00")); err != nil || j.Time().Day() != 1 {
+		t.Errorf("scan bytes: %v %v", j, err)
+	}
+	if v, _ := (JsonTime{}).Value(); v != nil {
+		t.Errorf("zero value = %v", v)
+	}
+	var n NullJsonTime
+	if err := n.Scan(nil); err != nil || n.Valid {
+		t.Errorf("scan nil: %+v %v", n, err)
+	}
+	if err := n.Scan(time.Now()); err != nil || !n.Valid {
+		t.Errorf("scan time: %+v %v", n, err)
+	}
+	if v, _ := n.Value(); v == nil {
+		t.Error("valid NullJsonTime has nil value")
+	}
+}
@@ -1,11 +1,14 @@
 package database
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	_ "github.com/go-sql-driver/mysql"
+	"github.com/go-sql-driver/mysql"
 	"github.com/ilooky/go-layout/pkg/config"
-	"github.com/ilooky/go-layout/pkg/guava"
+	"github.com/ilooky/go-layout/pkg/errno"
 	"github.com/ilooky/logger"
+	"net/url"
 	"reflect"
 	"strings"
 	"time"
@@ -17,71 +20,147 @@ import (
 
 var Db *xorm.Engine
 
+// EngineResolver 根据 ctx 选择数据库连接，多租户等扩展通过 SetResolver 注册
+type EngineResolver func(ctx context.Context) (*xorm.Engine, error)
+
+var resolver EngineResolver
+
+func SetResolver(r EngineResolver) {
+	resolver = r
+}
+
+// Engine 返回 ctx 对应的数据库连接，带 ctx 的函数都通过它访问数据库；
+// 未注册 resolver 或 resolver 返回 nil 时使用 Db，resolver 出错时返回错误，不会落到默认库
+func Engine(ctx context.Context) (*xorm.Engine, error) {
+	if resolver == nil {
+		return Db, nil
+	}
+	e, err := resolver(ctx)
+	if err != nil {
+		return nil, errno.Internal.Wrap(err)
+	}
+	if e == nil {
+		return Db, nil
+	}
+	return e, nil
+}
+
 func InitOrm(c config.Mysql) (db *xorm.Engine, err error) {
-	mysqlUrl := fmt.Sprintf(
-		"%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=true&loc=Local",
-		c.Username,
-		c.Password,
-		c.Host,
-		c.Port,
-		c.Database,
-	)
-	logger.Infof("connect mysql url = %s", mysqlUrl)
-	if Db, err = xorm.NewEngine("mysql", mysqlUrl); err != nil {
+	if Db, err = NewEngine(c); err != nil {
 		return nil, err
-	} else {
-		l := log{level: levelMap[logger.GetLevel()], showSQL: c.ShowSql}
-		Db.SetLogger(&l)
-		Db.SetMaxIdleConns(10)
-		Db.SetMaxOpenConns(10)
-		Db.SetConnMaxLifetime(time.Minute * 60)
-		loc, _ := time.LoadLocation("Local")
-		Db.TZLocation = loc
-		Db.DatabaseTZ = loc
-		snakeMapper := names.SnakeMapper{}
-		tbMapper := names.NewPrefixMapper(snakeMapper, "us_")
-		Db.SetTableMapper(tbMapper)
-		Db.SetColumnMapper(snakeMapper)
 	}
+	Zone = Db.TZLocation
 	return Db, nil
 }
 
+// NewEngine 按配置创建一个新的连接，不修改 Db；实体实现 TableName() string 时使用其返回的表名，不加前缀
+func NewEngine(c config.Mysql) (*xorm.Engine, error) {
+	loc, err := location(c.Timezone)
+	if err != nil {
+		return nil, err
+	}
+	tbMapper, colMapper, err := Mappers(c)
+	if err != nil {
+		return nil, err
+	}
+	logger.Infof("connect mysql url = %s", Dsn(c, true))
+	db, err := xorm.NewEngine("mysql", Dsn(c, false))
+	if err != nil {
+		return nil, err
+	}
+	l := log{level: levelMap[logger.GetLevel()], showSQL: c.ShowSql}
+	db.SetLogger(&l)
+	db.SetMaxIdleConns(orDefault(c.MaxIdle, 10))
+	db.SetMaxOpenConns(orDefault(c.MaxOpen, 10))
+	lifetime := c.MaxLifetime
+	if lifetime <= 0 {
+		lifetime = time.Minute * 60
+	}
+	db.SetConnMaxLifetime(lifetime)
+	db.TZLocation = loc
+	db.DatabaseTZ = loc
+	db.SetTableMapper(tbMapper)
+	db.SetColumnMapper(colMapper)
+	db.AddHook(&queryHook{db: db, slow: c.SlowThreshold})
+	return db, nil
+}
+
+// Dsn 生成连接串，mask 为 true 时隐藏密码用于日志
+func Dsn(c config.Mysql, mask bool) string {
+	params := url.Values{}
+	params.Set("charset", c.Charset)
+	if c.Charset == "" {
+		params.Set("charset", "utf8mb4")
+	}
+	if c.Collation != "" {
+		params.Set("collation", c.Collation)
+	}
+	params.Set("parseTime", "true")
+	params.Set("loc", c.Timezone)
+	if c.Timezone == "" {
+		params.Set("loc", "Local")
+	}
+	params.Set("clientFoundRows", "true")
+	for k, v := range c.Params {
+		params.Set(k, v)
+	}
+	password := c.Password
+	if mask {
+		password = "******"
+	}
+	return fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?%s", c.Username, password, c.Host, c.Port, c.Database, params.Encode())
+}
+
+// Mappers 根据配置返回表名和列名映射，表名映射带 TablePrefix 前缀
+func Mappers(c config.Mysql) (table names.Mapper, column names.Mapper, err error) {
+	switch c.Mapper {
+	case "", "snake":
+		column = names.SnakeMapper{}
+	case "same":
+		column = names.SameMapper{}
+	case "gonic":
+		column = names.LintGonicMapper
+	default:
+		return nil, nil, fmt.Errorf("unknown mapper %s", c.Mapper)
+	}
+	table = column
+	switch c.TablePrefix {
+	case "":
+		table = names.NewPrefixMapper(column, "us_")
+	case "-":
+	default:
+		table = names.NewPrefixMapper(column, c.TablePrefix)
+	}
+	return table, column, nil
+}
+
+func location(tz string) (*time.Location, error) {
+	if tz == "" {
+		tz = "Local"
+	}
+	return time.LoadLocation(tz)
+}
+
+func orDefault(v, def int) int {
+	if v <= 0 {
+		return def
+	}
+	return v
+}
+
 type Base struct {
 	Id        int64    `json:"id"`
 	Created   JsonTime `json:"created"    xorm:"created"`
 	Updated   JsonTime `json:"updated"    xorm:"updated"`
 	DeletedAt JsonTime `json:"-"          xorm:"deleted"`
 }
-type JsonTime time.Time
 
-func (j JsonTime) MarshalJSON() ([]byte, error) {
-	return []byte(`"` + time.Time(j).Format(guava.DateLayout(guava.YYYY_MM_DD_HH_MM_SS)) + `"`), nil
-}
-
-func (j *JsonTime) UnmarshalJSON(b []byte) (err error) {
-	if b[0] == '"' && b[len(b)-1] == '"' {
-		b = b[1 : len(b)-1]
-	}
-	loc, err := time.LoadLocation("Local")
-	if err != nil {
-		panic(err)
-	}
-	sv := string(b)
-	if len(sv) == 10 {
-		sv += " 00:00:00"
-	} else if len(sv) == 16 {
-		sv += ":00"
-	}
-	now, err := time.ParseInLocation(guava.DateLayout(guava.YYYY_MM_DD_HH_MM_SS), string(b), loc)
-	if err != nil {
-		if now, err = time.ParseInLocation(guava.DateLayout(guava.YYYY_MM_DD_HH_MM), string(b), loc); err != nil {
-			now, err = time.ParseInLocation(guava.DateLayout(guava.YYYY_MM_DD), string(b), loc)
-		}
-	}
-	*j = JsonTime(now)
-	return
+// VersionedBase 带乐观锁版本号的 Base，嵌入时需要 xorm:"extends"，
+// Update 时版本号不匹配返回 ErrConflict
+type VersionedBase struct {
+	Base    `xorm:"extends"`
+	Version int64 `json:"version" xorm:"version"`
 }
-
 type log struct {
 	level   xlog.LogLevel
 	showSQL bool
@@ -147,128 +226,331 @@ func (l *log) IsShowSQL() bool {
 	return l.showSQL
 }
 
+// wrapErr 将唯一键冲突转换为 errno.Conflict，其余错误原样返回
+func wrapErr(err error) error {
+	var me *mysql.MySQLError
+	if errors.As(err, &me) && me.Number == 1062 {
+		return errno.Conflict.Wrap(err)
+	}
+	return err
+}
+
 func Save(entity interface{}) (err error) {
-	_, err = Db.InsertOne(entity)
-	return
+	return SaveContext(context.Background(), entity)
+}
+
+// SaveContext 插入实体，成功后通知变更钩子
+func SaveContext(ctx context.Context, entity interface{}) error {
+	db, err := Engine(ctx)
+	if err != nil {
+		return err
+	}
+	if _, err := db.Context(ctx).InsertOne(entity); err != nil {
+		return wrapErr(err)
+	}
+	if len(hooks) > 0 {
+		if table, id, ok := identify(db, entity); ok {
+			notify(ctx, Change{Op: OpInsert, Table: table, Id: id, After: entity})
+		}
+	}
+	return nil
 }
 
 func SaveAll(entity ...interface{}) (err error) {
-	_, err = Db.Insert(entity...)
-	return
+	return SaveAllContext(context.Background(), entity...)
+}
+
+// SaveAllContext 在一个事务中插入多个实体，切片参数按 BatchSize 分批插入
+func SaveAllContext(ctx context.Context, entity ...interface{}) error {
+	db, err := Engine(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = db.Transaction(func(session *xorm.Session) (interface{}, error) {
+		session.Context(ctx)
+		for _, e := range entity {
+			if _, err := insertChunks(session, e, BatchSize); err != nil {
+				return nil, err
+			}
+		}
+		return nil, nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, e := range entity {
+		notifyInsert(ctx, db, e)
+	}
+	return nil
 }
 
 func Delete(entity interface{}) (err error) {
-	_, err = Db.Delete(entity)
-	return
+	return DeleteContext(context.Background(), entity)
+}
+
+// DeleteContext 删除实体，有钩子时先按主键读取删除前的行
+func DeleteContext(ctx context.Context, entity interface{}) error {
+	db, err := Engine(ctx)
+	if err != nil {
+		return err
+	}
+	if len(hooks) == 0 {
+		_, err := db.Context(ctx).Delete(entity)
+		return err
+	}
+	table, id, ok := identify(db, entity)
+	before := snapshot(ctx, db, entity, id)
+	if _, err := db.Context(ctx).Delete(entity); err != nil {
+		return err
+	}
+	if ok {
+		if before == nil {
+			before = entity
+		}
+		notify(ctx, Change{Op: OpDelete, Table: table, Id: id, Before: before})
+	}
+	return nil
 }
 
 func Update(entity interface{}) (err error) {
-	_, err = Db.Update(entity)
-	return
+	return UpdateContext(context.Background(), entity)
 }
 
+// UpdateContext 按主键更新实体的非零字段，没有匹配的行（已删除或版本号过期）时返回 ErrConflict；
+// 有钩子时在更新前后按主键读取完整的行
+func UpdateContext(ctx context.Context, entity interface{}) error {
+	return update(ctx, entity, false)
+}
+
+func update(ctx context.Context, entity interface{}, allCols bool) error {
+	db, err := Engine(ctx)
+	if err != nil {
+		return err
+	}
+	table, id, ok := identify(db, entity)
+	var before interface{}
+	if len(hooks) > 0 {
+		before = snapshot(ctx, db, entity, id)
+	}
+	session := db.Context(ctx)
+	if id != nil {
+		session.ID(id)
+	}
+	if allCols {
+		session.AllCols()
+	}
+	n, err := session.Update(entity)
+	if err != nil {
+		return wrapErr(err)
+	}
+	if n == 0 {
+		return ErrConflict
+	}
+	if ok && len(hooks) > 0 {
+		after := snapshot(ctx, db, entity, id)
+		if after == nil {
+			after = entity
+		}
+		notify(ctx, Change{Op: OpUpdate, Table: table, Id: id, Before: before, After: after})
+	}
+	return nil
+}
+
+// 以下不带 ctx 的查询函数始终使用默认库 Db，多租户时应使用对应的 XxxContext 函数
+
 func FindOneById(id interface{}, dest interface{}) error {
-	get, err := Db.ID(id).Get(dest)
-	if !get || err != nil {
-		return fmt.Errorf("not find entity where id = %s", id)
+	return FindOneByIdContext(context.Background(), id, dest)
+}
+
+// FindOneByIdContext 在 ctx 对应的数据库中按主键查询，dest is ptr
+func FindOneByIdContext(ctx context.Context, id interface{}, dest interface{}) error {
+	db, err := Engine(ctx)
+	if err != nil {
+		return err
+	}
+	get, err := db.Context(ctx).ID(id).Get(dest)
+	if err != nil {
+		return err
+	}
+	if !get {
+		return errno.NotFound.Errorf("not find entity where id = %v", id)
 	}
 	return nil
 }
 
 // FindOneByField dest is ptr
 func FindOneByField(field string, value interface{}, dest interface{}) error {
-	get, err := Db.Where(field+"=?", value).Get(dest)
+	return FindOneByFieldContext(context.Background(), field, value, dest)
+}
+
+func FindOneByFieldContext(ctx context.Context, field string, value interface{}, dest interface{}) error {
+	db, err := Engine(ctx)
+	if err != nil {
+		return err
+	}
+	get, err := db.Context(ctx).Where(field+"=?", value).Get(dest)
 	if get {
 		return nil
 	}
 	if err != nil {
 		return err
 	}
-	return fmt.Errorf("not find entity ,where %s = %s", field, value)
+	return errno.NotFound.Errorf("not find entity ,where %s = %v", field, value)
 }
 
 // FindCols dest is *struct or *[]struct
 func FindCols(field string, value interface{}, dest interface{}, cols ...string) error {
-	session := Db.Where(field+"=?", value).Cols(cols...)
-	var err error
-	if reflect.ValueOf(dest).Elem().Kind() == reflect.Slice {
-		err = session.Find(dest)
-	} else {
-		get, err := session.Get(dest)
-		if !get {
-			return fmt.Errorf("not find entity,where %s = %s", field, value)
-		}
+	return FindColsContext(context.Background(), field, value, dest, cols...)
+}
+
+func FindColsContext(ctx context.Context, field string, value interface{}, dest interface{}, cols ...string) error {
+	db, err := Engine(ctx)
+	if err != nil {
 		return err
 	}
+	session := db.Context(ctx).Where(field+"=?", value).Cols(cols...)
+	if reflect.ValueOf(dest).Elem().Kind() == reflect.Slice {
+		return session.Find(dest)
+	}
+	get, err := session.Get(dest)
+	if !get && err == nil {
+		return errno.NotFound.Errorf("not find entity,where %s = %v", field, value)
+	}
 	return err
 }
 
 func FindListByField(field string, value interface{}, dest interface{}) error {
-	err := Db.Where(field+"=?", value).Find(dest)
+	return FindListByFieldContext(context.Background(), field, value, dest)
+}
+
+func FindListByFieldContext(ctx context.Context, field string, value interface{}, dest interface{}) error {
+	db, err := Engine(ctx)
 	if err != nil {
 		return err
 	}
-	return nil
+	return db.Context(ctx).Where(field+"=?", value).Find(dest)
 }
 
 func FindOneByCondition(conditions map[string]interface{}, dest interface{}) error {
-	var condition []string
-	var values []interface{}
-	for k, v := range conditions {
-		condition = append(condition, k+"=?")
-		values = append(values, v)
+	return FindOneByConditionContext(context.Background(), conditions, dest)
+}
+
+func FindOneByConditionContext(ctx context.Context, conditions map[string]interface{}, dest interface{}) error {
+	db, err := Engine(ctx)
+	if err != nil {
+		return err
 	}
-	if get, _ := Db.Where(strings.Join(condition, " and "), values...).Get(dest); get {
+	condition, values := where(conditions)
+	if get, _ := db.Context(ctx).Where(condition, values...).Get(dest); get {
 		return nil
 	}
-	return fmt.Errorf("not find entity ,where %+v ", conditions)
+	return errno.NotFound.Errorf("not find entity ,where %+v ", conditions)
 }
 
 func FindListByCondition(conditions map[string]interface{}, dest interface{}) error {
-	var condition []string
-	var values []interface{}
-	for k, v := range conditions {
-		condition = append(condition, k+"=?")
-		values = append(values, v)
+	return FindListByConditionContext(context.Background(), conditions, dest)
+}
+
+func FindListByConditionContext(ctx context.Context, conditions map[string]interface{}, dest interface{}) error {
+	db, err := Engine(ctx)
+	if err != nil {
+		return err
 	}
-	return Db.Where(strings.Join(condition, " and "), values...).Find(dest)
+	condition, values := where(conditions)
+	return db.Context(ctx).Where(condition, values...).Find(dest)
 }
 
 func FindByValues(dest interface{}, conditions map[string]interface{}, field string, inValues ...interface{}) error {
+	return FindByValuesContext(context.Background(), dest, conditions, field, inValues...)
+}
+
+func FindByValuesContext(ctx context.Context, dest interface{}, conditions map[string]interface{}, field string, inValues ...interface{}) error {
+	db, err := Engine(ctx)
+	if err != nil {
+		return err
+	}
+	condition, values := where(conditions)
+	return db.Context(ctx).Where(condition, values...).And(builder.In(field, inValues...)).Find(dest)
+}
+
+func where(conditions map[string]interface{}) (string, []interface{}) {
 	var condition []string
 	var values []interface{}
 	for k, v := range conditions {
 		condition = append(condition, k+"=?")
 		values = append(values, v)
 	}
-	return Db.Where(strings.Join(condition, " and "), values...).And(builder.In(field, inValues...)).Find(dest)
+	return strings.Join(condition, " and "), values
 }
 
 // Execute ("delete from us_diagram where STATION_CODE = ?", stationCode)
 func Execute(sqlOrArgs ...interface{}) {
-	session := Db.NewSession()
-	defer session.Close()
-	if _, err := session.Exec(sqlOrArgs...); err != nil {
-		_ = session.Rollback()
-	}
-	_ = session.Commit()
+	_ = ExecuteContext(context.Background(), sqlOrArgs...)
+}
+
+// ExecuteContext 在 ctx 对应的数据库中以事务执行一条 SQL
+func ExecuteContext(ctx context.Context, sqlOrArgs ...interface{}) error {
+	return TxContext(ctx, func(session *xorm.Session) error {
+		_, err := session.Exec(sqlOrArgs...)
+		return err
+	})
 }
 
 func Executes(sql ...string) {
-	session := Db.NewSession()
-	defer session.Close()
-	for _, s := range sql {
-		if _, err := session.Exec(s); err != nil {
-			_ = session.Rollback()
+	_ = ExecutesContext(context.Background(), sql...)
+}
+
+// ExecutesContext 在同一事务中依次执行多条 SQL，任意一条失败时回滚
+func ExecutesContext(ctx context.Context, sql ...string) error {
+	return TxContext(ctx, func(session *xorm.Session) error {
+		for _, s := range sql {
+			if _, err := session.Exec(s); err != nil {
+				return err
+			}
 		}
+		return nil
+	})
+}
+
+// Tx 在同一事务中执行 fn，fn 返回 error 时回滚
+func Tx(fn func(session *xorm.Session) error) error {
+	return TxContext(context.Background(), fn)
+}
+
+// TxContext 在 ctx 对应的数据库中开启事务执行 fn
+func TxContext(ctx context.Context, fn func(session *xorm.Session) error) error {
+	db, err := Engine(ctx)
+	if err != nil {
+		return err
 	}
-	_ = session.Commit()
+	_, err = db.Transaction(func(session *xorm.Session) (interface{}, error) {
+		session.Context(ctx)
+		return nil, fn(session)
+	})
+	return err
 }
 
 func CreateTable(beans ...interface{}) {
-	_ = Db.CreateTables(beans...)
+	_ = CreateTableContext(context.Background(), beans...)
+}
+
+func CreateTableContext(ctx context.Context, beans ...interface{}) error {
+	db, err := Engine(ctx)
+	if err != nil {
+		return err
+	}
+	return db.CreateTables(beans...)
 }
 
 func SyncTable(beans ...interface{}) {
-	_ = Db.Sync2(beans...)
+	_ = SyncTableContext(context.Background(), beans...)
+}
+
+// SyncTableContext 在 ctx 对应的数据库中同步表结构，租户库需分别同步
+func SyncTableContext(ctx context.Context, beans ...interface{}) error {
+	db, err := Engine(ctx)
+	if err != nil {
+		return err
+	}
+	return db.Sync2(beans...)
 }
@@ -0,0 +1,83 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ilooky/go-layout/pkg/config"
+	"github.com/ilooky/go-layout/pkg/database/dbtest"
+	"xorm.io/xorm"
+	"xorm.io/xorm/names"
+)
+
+type lineStation struct{}
+
+type legacy struct{}
+
+func (legacy) TableName() string { return "t_legacy" }
+
+func TestMappers(t *testing.T) {
+	tests := []struct {
+		conf  config.Mysql
+		table string
+	}{
+		{config.Mysql{}, "us_line_station"},
+		{config.Mysql{TablePrefix: "-"}, "line_station"},
+		{config.Mysql{TablePrefix: "ops_", Mapper: "same"}, "ops_lineStation"},
+	}
+	for _, tt := range tests {
+		tb, _, err := Mappers(tt.conf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := names.GetTableName(tb, reflect.ValueOf(lineStation{})); got != tt.table {
+			t.Errorf("%+v: table = %s, want %s", tt.conf, got, tt.table)
+		}
+		if got := names.GetTableName(tb, reflect.ValueOf(legacy{})); got != "t_legacy" {
+			t.Errorf("TableName override ignored: %s", got)
+		}
+	}
+	if _, _, err := Mappers(config.Mysql{Mapper: "camel"}); err == nil {
+		t.Error("unknown mapper accepted")
+	}
+}
+
+func TestDsn(t *testing.T) {
+	c := config.Mysql{Username: "root", Password: "secret", Host: "db", Port: "3306", Database: "us_diagram",
+		Timezone: "Asia/Shanghai", Params: map[string]string{"timeout": "5s"}}
+	dsn := Dsn(c, false)
+	for _, want := range []string{"root:secret@tcp(db:3306)/us_diagram?", "charset=utf8mb4", "loc=Asia%2FShanghai", "timeout=5s", "parseTime=true"} {
+		if !strings.Contains(dsn, want) {
+			t.Errorf("dsn %s missing %s", dsn, want)
+		}
+	}
+	if strings.Contains(Dsn(c, true), "secret") {
+		t.Error("masked dsn contains password")
+	}
+}
+
+// useEngine 将 Db 替换为 dbtest 驱动的 engine，测试结束时恢复
+func useEngine(t *testing.T, d *dbtest.Driver) *xorm.Engine {
+	e := dbtest.Open(t, d)
+	old := Db
+	Db = e
+	t.Cleanup(func() { Db = old })
+	return e
+}
+
+func TestEngineResolverError(t *testing.T) {
+	defer SetResolver(nil)
+	SetResolver(func(ctx context.Context) (*xorm.Engine, error) {
+		return nil, errors.New("no tenant database")
+	})
+	if e, err := Engine(context.Background()); err == nil || e != nil {
+		t.Fatalf("Engine = %v, %v; want error", e, err)
+	}
+	var list []lineStation
+	if err := FindListByFieldContext(context.Background(), "code", "A", &list); err == nil {
+		t.Error("query fell back to the default database")
+	}
+}
@@ -0,0 +1,53 @@
+package database
+
+import (
+	"context"
+
+	"github.com/ilooky/go-layout/pkg/errno"
+	"github.com/ilooky/go-layout/pkg/guava"
+	"xorm.io/builder"
+	"xorm.io/xorm"
+)
+
+// Paginate 按分页参数设置 session 的 limit 和排序；allowed 为允许排序的字段（JSON 字段名），
+// 经列名映射后拼入 ORDER BY，不在白名单中的字段返回 errno.Param
+func Paginate(session *xorm.Session, p guava.Paged, allowed ...string) (*xorm.Session, error) {
+	sorts, err := p.Sorts(allowed...)
+	if err != nil {
+		return nil, errno.Param.Wrap(err)
+	}
+	mapper := session.Engine().GetColumnMapper()
+	for _, s := range sorts {
+		col := mapper.Obj2Table(guava.ToCamel(s.Field))
+		if s.Desc {
+			session.Desc(col)
+		} else {
+			session.Asc(col)
+		}
+	}
+	return session.Limit(p.Lim(), p.Offset()), nil
+}
+
+// FindPage 分页查询 dest（*[]struct）并返回带总数的分页结果，cond 可为 nil
+func FindPage(ctx context.Context, dest interface{}, p guava.Paged, cond builder.Cond, allowed ...string) (guava.PageResult, error) {
+	if err := p.Validate(allowed...); err != nil {
+		return guava.PageResult{}, errno.Param.Wrap(err)
+	}
+	db, err := Engine(ctx)
+	if err != nil {
+		return guava.PageResult{}, err
+	}
+	session := db.Context(ctx)
+	defer session.Close()
+	if cond != nil {
+		session.Where(cond)
+	}
+	if _, err := Paginate(session, p, allowed...); err != nil {
+		return guava.PageResult{}, err
+	}
+	total, err := session.FindAndCount(dest)
+	if err != nil {
+		return guava.PageResult{}, err
+	}
+	return p.Result(total, dest), nil
+}
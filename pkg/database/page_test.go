@@ -0,0 +1,28 @@
+package database
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ilooky/go-layout/pkg/errno"
+	"github.com/ilooky/go-layout/pkg/guava"
+	"xorm.io/xorm"
+)
+
+func TestPaginateRejectsUnknownSort(t *testing.T) {
+	db, err := xorm.NewEngine("mysql", "root:pass@tcp(127.0.0.1:1)/test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	session := db.NewSession()
+	defer session.Close()
+	_, err = Paginate(session, guava.Paged{Sort: "-password"}, "name")
+	var app *errno.AppError
+	if !errors.As(err, &app) || app.Code != errno.Param.Code {
+		t.Errorf("expected errno.Param, got %v", err)
+	}
+	if _, err = Paginate(session, guava.Paged{Sort: "-createdAt,name"}, "name", "createdAt"); err != nil {
+		t.Errorf("allowed sort rejected: %v", err)
+	}
+}
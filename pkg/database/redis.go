@@ -4,14 +4,14 @@ import (
 	"context"
 	"github.com/go-redis/redis/v8"
 	"github.com/ilooky/go-layout/pkg/config"
+	"github.com/ilooky/go-layout/pkg/guava/conv"
 	"github.com/ilooky/logger"
-	"strconv"
 	"time"
 )
 
 func InitRedis(conf config.Redis) {
 	var ctx = context.Background()
-	dbIndex, _ := strconv.Atoi(conf.Database)
+	dbIndex := conv.ToIntOr(conf.Database, 0)
 	ring := redis.NewClient(&redis.Options{
 		Addr:         conf.Host + ":" + conf.Port,
 		Password:     conf.Password,
@@ -0,0 +1,171 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/ilooky/go-layout/pkg/errno"
+	"github.com/ilooky/go-layout/pkg/guava"
+	"github.com/ilooky/logger"
+	"xorm.io/xorm"
+)
+
+const (
+	OpRestore Op = "restore"
+	OpPurge   Op = "purge"
+)
+
+// Unscoped 返回 ctx 对应数据库中包含已软删除行的查询，如 session.Where(...).Find(&list)
+func Unscoped(ctx context.Context) (*xorm.Session, error) {
+	db, err := Engine(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return db.Context(ctx).Unscoped(), nil
+}
+
+// deletedCol 返回 bean 对应表名和 xorm:"deleted" 列名，未定义时返回错误
+func deletedCol(db *xorm.Engine, bean interface{}) (string, string, error) {
+	table, err := db.TableInfo(bean)
+	if err != nil {
+		return "", "", err
+	}
+	col := table.DeletedColumn()
+	if col == nil {
+		return "", "", fmt.Errorf("table %s has no deleted column", table.Name)
+	}
+	return table.Name, col.Name, nil
+}
+
+// deletedCond 软删除行的条件，未删除的行该列为 NULL 或零值时间
+func deletedCond(col string) string {
+	return col + " IS NOT NULL AND " + col + " > '0001-01-01 00:00:00'"
+}
+
+// FindDeleted 在 ctx 对应的数据库中按删除时间倒序分页查询已软删除的行，dest 为 *[]T 或 *[]*T
+func FindDeleted(ctx context.Context, dest interface{}, p guava.Paged) error {
+	db, err := Engine(ctx)
+	if err != nil {
+		return err
+	}
+	typ := reflect.TypeOf(dest)
+	if typ.Kind() != reflect.Ptr || typ.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("FindDeleted: expect pointer to slice, got %T", dest)
+	}
+	typ = typ.Elem().Elem()
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	bean := reflect.New(typ).Interface()
+	_, col, err := deletedCol(db, bean)
+	if err != nil {
+		return err
+	}
+	session := db.Context(ctx).Unscoped().Where(deletedCond(col)).Desc(col)
+	session.Limit(p.Lim(), p.Offset())
+	return session.Find(dest)
+}
+
+// Restore 恢复已软删除的行，行不存在或未被删除时返回 errno.NotFound
+func Restore(ctx context.Context, bean interface{}, id interface{}) error {
+	db, err := Engine(ctx)
+	if err != nil {
+		return err
+	}
+	table, col, err := deletedCol(db, bean)
+	if err != nil {
+		return err
+	}
+	n, err := db.Context(ctx).Table(bean).Unscoped().ID(id).Where(deletedCond(col)).
+		Update(map[string]interface{}{col: nil})
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return errno.NotFound.Errorf("no deleted entity where id = %v", id)
+	}
+	notify(ctx, Change{Op: OpRestore, Table: table, Id: id})
+	return nil
+}
+
+// HardDelete 物理删除一行，无论是否已软删除；行不存在时返回 errno.NotFound
+func HardDelete(ctx context.Context, bean interface{}, id interface{}) error {
+	db, err := Engine(ctx)
+	if err != nil {
+		return err
+	}
+	var before interface{}
+	if len(hooks) > 0 {
+		before = reflect.New(reflect.Indirect(reflect.ValueOf(bean)).Type()).Interface()
+		if get, err := db.Context(ctx).Unscoped().ID(id).Get(before); err != nil || !get {
+			before = nil
+		}
+	}
+	n, err := db.Context(ctx).Unscoped().ID(id).Delete(bean)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return errno.NotFound.Errorf("no entity where id = %v", id)
+	}
+	if table, err := db.TableInfo(bean); err == nil {
+		notify(ctx, Change{Op: OpDelete, Table: table.Name, Id: id, Before: before})
+	}
+	return nil
+}
+
+// Purge 分批物理删除 before 之前软删除的行，返回删除行数；batch <= 0 时使用 BatchSize。
+// 使用 DELETE ... LIMIT，仅支持 MySQL
+func Purge(ctx context.Context, bean interface{}, before time.Time, batch int) (int64, error) {
+	db, err := Engine(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if batch <= 0 {
+		batch = BatchSize
+	}
+	table, col, err := deletedCol(db, bean)
+	if err != nil {
+		return 0, err
+	}
+	var total int64
+	for {
+		res, err := db.Context(ctx).Exec(
+			"DELETE FROM "+table+" WHERE "+deletedCond(col)+" AND "+col+" < ? LIMIT ?", before, batch)
+		if err != nil {
+			return total, err
+		}
+		n, _ := res.RowsAffected()
+		total += n
+		if n < int64(batch) {
+			break
+		}
+	}
+	if total > 0 {
+		notify(ctx, Change{Op: OpPurge, Table: table})
+	}
+	return total, nil
+}
+
+// PurgeEvery 每隔 interval 清理软删除超过 retention 的行，直到 ctx 结束；batch 同 Purge
+func PurgeEvery(ctx context.Context, interval, retention time.Duration, batch int, beans ...interface{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		for _, bean := range beans {
+			n, err := Purge(ctx, bean, time.Now().Add(-retention), batch)
+			if err != nil {
+				logger.Errorf("purge %T failed: %v", bean, err)
+			} else if n > 0 {
+				logger.Infof("purged %d deleted rows of %T", n, bean)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package database
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ilooky/go-layout/pkg/database/dbtest"
+	"github.com/ilooky/go-layout/pkg/errno"
+	"github.com/ilooky/go-layout/pkg/guava"
+	"xorm.io/xorm"
+)
+
+type archived struct {
+	Id        int64
+	DeletedAt time.Time `xorm:"deleted"`
+}
+
+func TestPurgeDefaultBatch(t *testing.T) {
+	var limits []int64
+	d := &dbtest.Driver{Affected: func(query string, args []driver.Value) int64 {
+		limit := args[len(args)-1].(int64)
+		limits = append(limits, limit)
+		if len(limits) == 1 {
+			return limit
+		}
+		return 3
+	}}
+	useEngine(t, d)
+	for _, batch := range []int{0, -1} {
+		limits = nil
+		n, err := Purge(context.Background(), new(archived), time.Now(), batch)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != int64(BatchSize)+3 || len(limits) != 2 || limits[0] != int64(BatchSize) {
+			t.Errorf("batch %d: purged %d rows with limits %v", batch, n, limits)
+		}
+	}
+	for _, s := range d.Execs() {
+		if !strings.HasPrefix(s.SQL, "DELETE FROM archived WHERE deleted_at IS NOT NULL") {
+			t.Errorf("unexpected exec %s", s.SQL)
+		}
+	}
+}
+
+func TestFindDeletedUsesContextEngine(t *testing.T) {
+	d := &dbtest.Driver{Columns: []string{"id", "deleted_at"}, Rows: [][]driver.Value{{int64(7), time.Now()}}}
+	e := useEngine(t, d)
+	Db = nil
+	defer SetResolver(nil)
+	SetResolver(func(ctx context.Context) (*xorm.Engine, error) { return e, nil })
+	var list []archived
+	if err := FindDeleted(context.Background(), &list, guava.Paged{Limit: 10}); err != nil {
+		t.Fatal(err)
+	}
+	queries := d.Queries()
+	if len(list) != 1 || list[0].Id != 7 || len(queries) != 1 || !strings.Contains(queries[0].SQL, "ORDER BY `deleted_at` DESC LIMIT 10") {
+		t.Errorf("list = %+v, queries = %v", list, queries)
+	}
+	d.Rows = [][]driver.Value{{int64(8), time.Now()}}
+	var ptrs []*archived
+	if err := FindDeleted(context.Background(), &ptrs, guava.Paged{}); err != nil || len(ptrs) != 1 || ptrs[0].Id != 8 {
+		t.Errorf("FindDeleted(*[]*T) = %+v, %v", ptrs, err)
+	}
+	if err := FindDeleted(context.Background(), list, guava.Paged{}); err == nil {
+		t.Error("non-pointer dest accepted")
+	}
+
+	d.Reset()
+	d.Columns, d.Rows = []string{"count(*)"}, [][]driver.Value{{int64(1)}}
+	session, err := Unscoped(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = session.Count(new(archived)); err != nil || len(d.Queries()) != 1 || strings.Contains(d.Queries()[0].SQL, "deleted_at") {
+		t.Errorf("Unscoped count = %v, queries = %v", err, d.Queries())
+	}
+}
+
+func TestHardDeleteMissing(t *testing.T) {
+	d := &dbtest.Driver{}
+	useEngine(t, d)
+	defer func(old []Hook) { hooks = old }(hooks)
+	var changes []Change
+	AddHook(func(ctx context.Context, c Change) { changes = append(changes, c) })
+	for _, affected := range []int64{0, 1} {
+		changes = nil
+		d.Affected = func(string, []driver.Value) int64 { return affected }
+		err := HardDelete(context.Background(), new(archived), 7)
+		if affected == 0 && (!errors.Is(err, errno.NotFound) || len(changes) != 0) {
+			t.Errorf("missing row: err = %v, changes = %v", err, changes)
+		}
+		if affected == 1 && (err != nil || len(changes) != 1 || changes[0].Op != OpDelete) {
+			t.Errorf("deleted row: err = %v, changes = %v", err, changes)
+		}
+	}
+}
@@ -0,0 +1,259 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ilooky/go-layout/pkg/errno"
+	"github.com/ilooky/go-layout/pkg/middleware"
+	"github.com/ilooky/logger"
+	"xorm.io/xorm"
+	"xorm.io/xorm/contexts"
+)
+
+// ExplainSlow 为 true 时对慢 SELECT 异步执行 EXPLAIN 并记录执行计划，生产环境应关闭
+var ExplainSlow bool
+
+// explaining 限制同时执行的 EXPLAIN 数量，已满时跳过，避免数据库变慢时慢查询引发更多查询
+var explaining = make(chan struct{}, 4)
+
+// Stats 全部连接共享的 SQL 统计
+var Stats = NewQueryStats(1000, 512)
+
+// queryHook 记录每条语句的耗时，超过阈值时记录慢查询日志
+type queryHook struct {
+	db   *xorm.Engine
+	slow time.Duration
+}
+
+func (h *queryHook) BeforeProcess(c *contexts.ContextHook) (context.Context, error) {
+	return c.Ctx, nil
+}
+
+func (h *queryHook) AfterProcess(c *contexts.ContextHook) error {
+	Stats.Record(c.SQL, c.ExecuteTime, c.Err)
+	if h.slow <= 0 || c.ExecuteTime < h.slow {
+		return nil
+	}
+	logger.Warnf("slow sql %v: %s %v", c.ExecuteTime, c.SQL, Redact(c.Args))
+	if ExplainSlow && isSelect(c.SQL) {
+		select {
+		case explaining <- struct{}{}:
+			go func() {
+				defer func() { <-explaining }()
+				h.explain(c.SQL, c.Args)
+			}()
+		default:
+		}
+	}
+	return nil
+}
+
+// explain 直接使用 database/sql 执行，不经过 hook
+func (h *queryHook) explain(query string, args []interface{}) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	rows, err := h.db.DB().DB.QueryContext(ctx, "EXPLAIN "+query, args...)
+	if err != nil {
+		logger.Warnf("explain %s: %v", query, err)
+		return
+	}
+	defer rows.Close()
+	cols, _ := rows.Columns()
+	var plan []string
+	for rows.Next() {
+		values := make([]sql.NullString, len(cols))
+		dest := make([]interface{}, len(cols))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+		if err = rows.Scan(dest...); err != nil {
+			logger.Warnf("explain %s: %v", query, err)
+			return
+		}
+		fields := make([]string, 0, len(cols))
+		for i, v := range values {
+			if v.Valid {
+				fields = append(fields, cols[i]+"="+v.String)
+			}
+		}
+		plan = append(plan, strings.Join(fields, " "))
+	}
+	logger.Warnf("explain %s\n%s", query, strings.Join(plan, "\n"))
+}
+
+func isSelect(query string) bool {
+	return strings.HasPrefix(strings.ToUpper(strings.TrimSpace(query)), "SELECT")
+}
+
+// Redact 只保留参数的类型和长度，避免日志泄露业务数据
+func Redact(args []interface{}) []string {
+	redacted := make([]string, len(args))
+	for i, arg := range args {
+		switch v := arg.(type) {
+		case nil:
+			redacted[i] = "nil"
+		case string:
+			redacted[i] = "string(" + strconv.Itoa(len(v)) + ")"
+		case []byte:
+			redacted[i] = "bytes(" + strconv.Itoa(len(v)) + ")"
+		default:
+			redacted[i] = fmt.Sprintf("%T", v)
+		}
+	}
+	return redacted
+}
+
+var (
+	literalRe = regexp.MustCompile(`'(?:[^'\\]|\\.|'')*'|\b\d+(?:\.\d+)?\b`)
+	listRe    = regexp.MustCompile(`\(\s*\?(?:\s*,\s*\?)*\s*\)(?:\s*,\s*\(\s*\?(?:\s*,\s*\?)*\s*\))*`)
+	spaceRe   = regexp.MustCompile(`\s+`)
+)
+
+// Normalize 将语句中的字面量替换为 ?，合并 IN 列表和多行 VALUES，用于统计归类
+func Normalize(query string) string {
+	query = literalRe.ReplaceAllString(query, "?")
+	query = listRe.ReplaceAllString(query, "(...)")
+	return strings.TrimSpace(spaceRe.ReplaceAllString(query, " "))
+}
+
+// QueryStat 一类语句的统计，耗时单位为毫秒
+type QueryStat struct {
+	Query  string  `json:"query"`
+	Count  int64   `json:"count"`
+	Errors int64   `json:"errors"`
+	Total  float64 `json:"total"`
+	Max    float64 `json:"max"`
+	P50    float64 `json:"p50"`
+	P99    float64 `json:"p99"`
+}
+
+type queryStat struct {
+	count, errors int64
+	total, max    time.Duration
+	samples       []time.Duration
+	next          int
+}
+
+// QueryStats 按归一化语句汇总耗时，每类语句保留最近 samples 个耗时用于计算分位数，
+// 超过 limit 类后新的语句归入 "other"
+type QueryStats struct {
+	mu      sync.Mutex
+	limit   int
+	samples int
+	stats   map[string]*queryStat
+}
+
+func NewQueryStats(limit, samples int) *QueryStats {
+	return &QueryStats{limit: limit, samples: samples, stats: map[string]*queryStat{}}
+}
+
+func (s *QueryStats) Record(query string, d time.Duration, err error) {
+	key := Normalize(query)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st, ok := s.stats[key]
+	if !ok {
+		if len(s.stats) >= s.limit {
+			key = "other"
+			st = s.stats[key]
+		}
+		if st == nil {
+			st = &queryStat{}
+			s.stats[key] = st
+		}
+	}
+	st.count++
+	if err != nil {
+		st.errors++
+	}
+	st.total += d
+	if d > st.max {
+		st.max = d
+	}
+	if len(st.samples) < s.samples {
+		st.samples = append(st.samples, d)
+	} else {
+		st.samples[st.next] = d
+		st.next = (st.next + 1) % s.samples
+	}
+}
+
+// Snapshot 按 sortBy（count、total、max、p99，默认 total）倒序返回前 limit 类语句
+func (s *QueryStats) Snapshot(sortBy string, limit int) []QueryStat {
+	s.mu.Lock()
+	list := make([]QueryStat, 0, len(s.stats))
+	for query, st := range s.stats {
+		samples := append([]time.Duration(nil), st.samples...)
+		sort.Slice(samples, func(i, j int) bool { return samples[i] < samples[j] })
+		list = append(list, QueryStat{
+			Query:  query,
+			Count:  st.count,
+			Errors: st.errors,
+			Total:  ms(st.total),
+			Max:    ms(st.max),
+			P50:    ms(percentile(samples, 0.5)),
+			P99:    ms(percentile(samples, 0.99)),
+		})
+	}
+	s.mu.Unlock()
+	key := func(q QueryStat) float64 {
+		switch sortBy {
+		case "count":
+			return float64(q.Count)
+		case "max":
+			return q.Max
+		case "p99":
+			return q.P99
+		}
+		return q.Total
+	}
+	sort.Slice(list, func(i, j int) bool { return key(list[i]) > key(list[j]) })
+	if limit > 0 && len(list) > limit {
+		list = list[:limit]
+	}
+	return list
+}
+
+func (s *QueryStats) Reset() {
+	s.mu.Lock()
+	s.stats = map[string]*queryStat{}
+	s.mu.Unlock()
+}
+
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	return sorted[int(float64(len(sorted)-1)*p)]
+}
+
+func ms(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+// RegisterAdmin 注册 SQL 统计接口，guard 为鉴权中间件，如 auth.Require("manage", "admin:db")，
+// 为 nil 时拒绝所有请求：
+//
+//	GET    /admin/db/stats?sort=p99&limit=50  查看统计
+//	DELETE /admin/db/stats                    清空统计
+func RegisterAdmin(r gin.IRouter, guard gin.HandlerFunc) {
+	g := r.Group("/admin/db", middleware.Guard(guard))
+	g.GET("/stats", func(c *gin.Context) {
+		limit, _ := strconv.Atoi(c.Query("limit"))
+		middleware.Render(c, http.StatusOK, errno.Ok().WithData(Stats.Snapshot(c.Query("sort"), limit)))
+	})
+	g.DELETE("/stats", func(c *gin.Context) {
+		Stats.Reset()
+		middleware.Render(c, http.StatusOK, errno.Ok())
+	})
+}
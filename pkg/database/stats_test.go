@@ -0,0 +1,80 @@
+package database
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"SELECT * FROM us_line2 WHERE id = 12", "SELECT * FROM us_line2 WHERE id = ?"},
+		{"SELECT `id` FROM t WHERE name='it''s' AND code IN (?, ?, ?)", "SELECT `id` FROM t WHERE name=? AND code IN (...)"},
+		{"INSERT INTO t (a,b) VALUES (?,?),(?,?)\n", "INSERT INTO t (a,b) VALUES (...)"},
+		{"UPDATE t SET  x = 1.5\tWHERE id=?", "UPDATE t SET x = ? WHERE id=?"},
+	}
+	for _, tt := range tests {
+		if got := Normalize(tt.in); got != tt.want {
+			t.Errorf("Normalize(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestQueryStats(t *testing.T) {
+	s := NewQueryStats(2, 100)
+	for i := 1; i <= 100; i++ {
+		s.Record("SELECT * FROM t WHERE id = "+strconv.Itoa(i), time.Duration(i)*time.Millisecond, nil)
+	}
+	s.Record("DELETE FROM t WHERE id = ?", time.Millisecond, errors.New("lock wait timeout"))
+	s.Record("UPDATE t SET a = ?", time.Millisecond, nil)
+	s.Record("UPDATE u SET a = ?", time.Millisecond, nil)
+	list := s.Snapshot("count", 0)
+	if len(list) != 3 || list[0].Count != 100 || list[0].P50 != 50 || list[0].P99 != 99 || list[0].Max != 100 {
+		t.Fatalf("snapshot = %+v", list)
+	}
+	if list[1].Query != "other" && list[2].Query != "other" {
+		t.Errorf("statements over limit not grouped: %+v", list)
+	}
+	if top := s.Snapshot("total", 1); len(top) != 1 || top[0].Count != 100 {
+		t.Errorf("top = %+v", top)
+	}
+	s.Reset()
+	if len(s.Snapshot("", 0)) != 0 {
+		t.Error("reset did not clear stats")
+	}
+}
+
+func TestRedact(t *testing.T) {
+	got := Redact([]interface{}{"secret", int64(1), nil, []byte("ab"), time.Time{}})
+	want := []string{"string(6)", "int64", "nil", "bytes(2)", "time.Time"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Redact = %v", got)
+	}
+}
+
+func TestRegisterAdminGuard(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	allow := func(c *gin.Context) { c.Next() }
+	tests := []struct {
+		guard gin.HandlerFunc
+		want  int
+	}{
+		{nil, http.StatusForbidden},
+		{allow, http.StatusOK},
+	}
+	for _, tt := range tests {
+		h := gin.New()
+		RegisterAdmin(h, tt.guard)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/db/stats", nil))
+		if w.Code != tt.want {
+			t.Errorf("status = %d, want %d", w.Code, tt.want)
+		}
+	}
+}
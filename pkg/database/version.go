@@ -0,0 +1,47 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"reflect"
+
+	"github.com/ilooky/go-layout/pkg/errno"
+)
+
+// ErrConflict 更新时没有匹配的行，通常是版本号已被并发修改，响应为 HTTP 409
+var ErrConflict = errno.Stale.Errorf("entity was modified concurrently")
+
+// UpdateWithRetry 按主键读取最新的行交给 mutate 修改后整行更新，遇到 ErrConflict 时重新读取并重试，
+// 最多尝试 attempts 次（小于 1 时按 1 次），全部冲突时返回 ErrConflict；mutate 返回错误时直接返回，不更新。
+// bean 为指向实体的指针
+func UpdateWithRetry(ctx context.Context, bean interface{}, id interface{}, attempts int, mutate func(bean interface{}) error) error {
+	db, err := Engine(ctx)
+	if err != nil {
+		return err
+	}
+	if attempts < 1 {
+		attempts = 1
+	}
+	for i := 0; i < attempts; i++ {
+		// 清空 bean，避免上次读取的字段（如旧版本号）成为查询条件
+		v := reflect.ValueOf(bean).Elem()
+		v.Set(reflect.Zero(v.Type()))
+		get, err := db.Context(ctx).ID(id).Get(bean)
+		if err != nil {
+			return err
+		}
+		if !get {
+			return errno.NotFound.Errorf("not find entity where id = %v", id)
+		}
+		if err = mutate(bean); err != nil {
+			return err
+		}
+		if err = update(ctx, bean, true); !errors.Is(err, ErrConflict) {
+			return err
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+	return ErrConflict
+}
@@ -0,0 +1,49 @@
+package database
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ilooky/go-layout/pkg/database/dbtest"
+)
+
+type versioned struct {
+	Id      int64
+	Name    string
+	Version int64 `xorm:"version"`
+}
+
+func TestUpdateWithRetryConflict(t *testing.T) {
+	d := &dbtest.Driver{
+		Columns: []string{"id", "name", "version"},
+		Rows:    [][]driver.Value{{int64(1), "a", int64(1)}},
+	}
+	useEngine(t, d)
+	for _, attempts := range []int{3, 0} {
+		d.Reset()
+		calls := 0
+		err := UpdateWithRetry(context.Background(), new(versioned), 1, attempts, func(bean interface{}) error {
+			calls++
+			bean.(*versioned).Name = "b"
+			return nil
+		})
+		if !errors.Is(err, ErrConflict) {
+			t.Errorf("attempts %d: err = %v, want ErrConflict", attempts, err)
+		}
+		want := attempts
+		if want < 1 {
+			want = 1
+		}
+		if calls != want || len(d.Execs()) != want {
+			t.Errorf("attempts %d: mutate called %d times, %d updates", attempts, calls, len(d.Execs()))
+		}
+		for _, s := range d.Execs() {
+			if !strings.HasPrefix(s.SQL, "UPDATE") {
+				t.Errorf("unexpected exec %s", s.SQL)
+			}
+		}
+	}
+}
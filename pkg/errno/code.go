@@ -1,7 +1,67 @@
 package errno
 
+import (
+	"net/http"
+	"sync"
+)
+
+// ErrServer 与 ErrParam 是共享实例且只有中文提示。
+// Deprecated: 使用 ServerErr(lang)、ParamErr(lang) 或 FromError
 var (
 	OK        = NewResp(1, "OK")
 	ErrServer = NewResp(0, "服务异常，请联系管理员")
 	ErrParam  = NewResp(0, "参数有误")
 )
+
+// DefaultLang 未指定语言时使用的提示语言
+var DefaultLang = "zh"
+
+// 业务错误码，Internal 沿用 Resp 原有的 0，提示信息见 locales 目录
+var (
+	Internal     = define(0, http.StatusInternalServerError)
+	Param        = define(40000, http.StatusBadRequest)
+	InvalidField = define(40001, http.StatusBadRequest)
+	Unauthorized = define(40100, http.StatusUnauthorized)
+	TokenInvalid = define(40101, http.StatusUnauthorized)
+	Forbidden    = define(40300, http.StatusForbidden)
+	NotFound     = define(40400, http.StatusNotFound)
+	Conflict     = define(40900, http.StatusConflict)
+	Stale        = define(40901, http.StatusConflict)
+	Upstream     = define(50200, http.StatusBadGateway)
+)
+
+var (
+	mu    sync.RWMutex
+	codes = map[int]*AppError{}
+)
+
+// Define 注册业务错误码及其 HTTP 状态和中英文提示，重复注册会覆盖；
+// 其他语言的提示通过 LoadBundle 或 LoadDir 补充
+func Define(code int, status int, zh, en string) *AppError {
+	e := define(code, status)
+	mu.Lock()
+	defer mu.Unlock()
+	for lang, msg := range map[string]string{"zh": zh, "en": en} {
+		if catalog[lang] == nil {
+			catalog[lang] = map[int]string{}
+		}
+		catalog[lang][code] = msg
+	}
+	return e
+}
+
+func define(code int, status int) *AppError {
+	e := &AppError{Code: code, Status: status}
+	mu.Lock()
+	defer mu.Unlock()
+	codes[code] = e
+	return e
+}
+
+// Lookup 按错误码查找已注册的错误
+func Lookup(code int) (*AppError, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	e, ok := codes[code]
+	return e, ok
+}
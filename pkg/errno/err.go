@@ -1,6 +1,12 @@
 package errno
 
 import (
+	"context"
+	"database/sql"
+	"encoding/xml"
+	"errors"
+	"net/http"
+
 	"github.com/ilooky/go-layout/pkg/guava/json"
 	"github.com/ilooky/logger"
 )
@@ -17,12 +23,16 @@ type Resp interface {
 	ToString() string
 }
 
+// ShowStacks 为 false 时 WithStacks 只记录日志，不把错误详情放入响应，生产环境应关闭
+var ShowStacks = true
+
 type err struct {
-	Code    int         `json:"code"`
-	Message string      `json:"message"`
-	Content interface{} `json:"content"`
-	Stacks  string      `json:"stacks,omitempty"`
-	ID      string      `json:"id,omitempty"` // 当前请求的唯一ID，便于问题定位，忽略也可以
+	XMLName xml.Name    `json:"-" xml:"resp"`
+	Code    int         `json:"code"             xml:"code"`
+	Message string      `json:"message"          xml:"message"`
+	Content interface{} `json:"content"          xml:"content,omitempty"`
+	Stacks  string      `json:"stacks,omitempty" xml:"stacks,omitempty"`
+	ID      string      `json:"id,omitempty"     xml:"id,omitempty"` // 当前请求的唯一ID，便于问题定位，忽略也可以
 }
 
 func NewResp(code int, msg string) Resp {
@@ -37,12 +47,50 @@ func Ok() Resp {
 	return NewResp(1, "")
 }
 
-func ParamErr() Resp {
-	return NewResp(0, "参数有误")
+// ParamErr 参数错误，lang 缺省时使用 DefaultLang
+func ParamErr(lang ...string) Resp {
+	return NewResp(0, Message(Param.Code, first(lang)))
+}
+
+// ServerErr 服务异常，lang 缺省时使用 DefaultLang
+func ServerErr(lang ...string) Resp {
+	return NewResp(0, Message(Internal.Code, first(lang)))
+}
+
+func first(lang []string) string {
+	if len(lang) > 0 {
+		return lang[0]
+	}
+	return ""
 }
 
-func ServerErr() Resp {
-	return NewResp(0, "服务异常，请联系管理员")
+// FromError 将 handler 或数据库调用返回的错误转换为 HTTP 状态码和响应：
+// AppError 使用其注册的状态码和提示，EmptyErr 和 sql.ErrNoRows 视为 NotFound，
+// 超时视为 Upstream，其余错误一律为 Internal，避免把内部错误信息返回给调用方
+func FromError(e error, lang ...string) (int, Resp) {
+	if e == nil {
+		return http.StatusOK, Ok()
+	}
+	app := Internal
+	var target *AppError
+	switch {
+	case errors.As(e, &target):
+		app = target
+	case errors.Is(e, NotFound), errors.Is(e, sql.ErrNoRows):
+		app = NotFound
+	case errors.Is(e, Param):
+		app = Param
+	case errors.Is(e, context.DeadlineExceeded):
+		app = Upstream
+	}
+	status := app.Status
+	if registered, ok := Lookup(app.Code); ok && status == 0 {
+		status = registered.Status
+	}
+	if status == 0 {
+		status = http.StatusInternalServerError
+	}
+	return status, app.Resp(first(lang))
 }
 
 func (e *err) i() {}
@@ -63,7 +111,9 @@ func (e *err) WithMessage(message string) Resp {
 
 func (e *err) WithStacks(err error) Resp {
 	logger.Error(err)
-	e.Stacks = err.Error()
+	if ShowStacks {
+		e.Stacks = err.Error()
+	}
 	return e
 }
 
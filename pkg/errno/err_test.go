@@ -0,0 +1,53 @@
+package errno
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestAppErrorIsAs(t *testing.T) {
+	cause := errors.New("row missing")
+	err := fmt.Errorf("load user: %w", NotFound.Wrap(cause))
+	if !errors.Is(err, NotFound) {
+		t.Error("wrapped NotFound should match NotFound")
+	}
+	if errors.Is(err, Conflict) {
+		t.Error("NotFound should not match Conflict")
+	}
+	if !errors.Is(err, cause) {
+		t.Error("cause should be reachable through Unwrap")
+	}
+	var app *AppError
+	if !errors.As(err, &app) || app.Code != NotFound.Code {
+		t.Errorf("errors.As got %v", app)
+	}
+}
+
+func TestFromError(t *testing.T) {
+	tests := []struct {
+		err    error
+		status int
+		code   int
+	}{
+		{nil, http.StatusOK, 1},
+		{NotFound.Errorf("id = %d", 1), http.StatusNotFound, NotFound.Code},
+		{fmt.Errorf("save: %w", Conflict), http.StatusConflict, Conflict.Code},
+		{fmt.Errorf("update: %w", Stale.Errorf("version 3")), http.StatusConflict, Stale.Code},
+		{NewEmptyErr(), http.StatusNotFound, NotFound.Code},
+		{NewParamErr(), http.StatusBadRequest, Param.Code},
+		{sql.ErrNoRows, http.StatusNotFound, NotFound.Code},
+		{errors.New("boom"), http.StatusInternalServerError, Internal.Code},
+	}
+	for _, test := range tests {
+		status, resp := FromError(test.err)
+		if status != test.status || resp.(*err).Code != test.code {
+			t.Errorf("FromError(%v) = %d, %d; want %d, %d", test.err, status, resp.(*err).Code, test.status, test.code)
+		}
+	}
+	if _, resp := FromError(Unauthorized, "en"); resp.(*err).Message != "unauthorized" {
+		t.Errorf("english message = %q", resp.(*err).Message)
+	}
+}
@@ -1,5 +1,10 @@
 package errno
 
+import (
+	"errors"
+	"fmt"
+)
+
 type EmptyErr struct {
 	msg string
 }
@@ -20,3 +25,92 @@ func (e EmptyErr) Error() string {
 
 func (e EmptyErr) RuntimeError() {
 }
+
+// Is 使 EmptyErr 可以通过 errors.Is 匹配到对应的业务错误码
+func (e EmptyErr) Is(target error) bool {
+	if e == NewParamErr() {
+		return Param.Is(target)
+	}
+	return NotFound.Is(target)
+}
+
+var _ error = (*AppError)(nil)
+
+// AppError 带业务错误码的错误，通过 errors.Is 按错误码匹配，通过 errors.As 取出错误码，
+// 可包装底层错误；detail 和 cause 只用于日志，返回给调用方的是错误码注册的提示信息
+type AppError struct {
+	Code   int
+	Status int
+	detail string
+	cause  error
+	params map[string]interface{}
+	data   interface{}
+}
+
+func (e *AppError) Error() string {
+	msg := fmt.Sprintf("errno %d", e.Code)
+	if e.detail != "" {
+		msg += ": " + e.detail
+	} else if m := Format(e.Code, "", e.params); m != "" {
+		msg += ": " + m
+	}
+	if e.cause != nil {
+		msg += ": " + e.cause.Error()
+	}
+	return msg
+}
+
+func (e *AppError) Unwrap() error {
+	return e.cause
+}
+
+// Is 错误码相同即视为同一错误
+func (e *AppError) Is(target error) bool {
+	var t *AppError
+	if errors.As(target, &t) {
+		return t.Code == e.Code
+	}
+	return false
+}
+
+// Wrap 返回包装了 cause 的同码错误
+func (e *AppError) Wrap(cause error) *AppError {
+	c := *e
+	c.cause = cause
+	return &c
+}
+
+// Errorf 返回附带详细描述的同码错误，描述只出现在日志中
+func (e *AppError) Errorf(format string, args ...interface{}) *AppError {
+	c := *e
+	c.detail = fmt.Sprintf(format, args...)
+	return &c
+}
+
+// With 返回带有提示参数的同码错误，替换提示中的 {key}
+func (e *AppError) With(key string, value interface{}) *AppError {
+	c := *e
+	c.params = make(map[string]interface{}, len(e.params)+1)
+	for k, v := range e.params {
+		c.params[k] = v
+	}
+	c.params[key] = value
+	return &c
+}
+
+// WithData 返回携带响应内容的同码错误，如字段校验错误列表
+func (e *AppError) WithData(data interface{}) *AppError {
+	c := *e
+	c.data = data
+	return &c
+}
+
+// Data 返回 WithData 设置的响应内容
+func (e *AppError) Data() interface{} {
+	return e.data
+}
+
+// Resp 转换为响应，lang 为空时使用默认语言
+func (e *AppError) Resp(lang string) Resp {
+	return NewResp(e.Code, Format(e.Code, lang, e.params)).WithData(e.data)
+}
@@ -0,0 +1,141 @@
+package errno
+
+import (
+	"embed"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ilooky/go-layout/pkg/guava"
+	"gopkg.in/yaml.v2"
+)
+
+// 内置的提示信息，每个语言一个 YAML 文件，键为错误码，值支持 {name} 形式的参数
+//
+//go:embed locales/*.yaml
+var locales embed.FS
+
+// catalog 语言 -> 错误码 -> 提示模板
+var catalog = map[string]map[int]string{}
+
+func init() {
+	entries, _ := locales.ReadDir("locales")
+	for _, entry := range entries {
+		data, _ := locales.ReadFile("locales/" + entry.Name())
+		if err := LoadBundle(strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name())), data); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// LoadBundle 加载一个语言的 YAML 提示包，已存在的错误码会被覆盖
+func LoadBundle(lang string, data []byte) error {
+	bundle := map[int]string{}
+	if err := yaml.Unmarshal(data, &bundle); err != nil {
+		return fmt.Errorf("errno: parse %s bundle: %w", lang, err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if catalog[lang] == nil {
+		catalog[lang] = map[int]string{}
+	}
+	for code, msg := range bundle {
+		catalog[lang][code] = msg
+	}
+	return nil
+}
+
+// LoadDir 加载目录下的 <lang>.yaml 提示包，供项目补充自定义错误码的翻译
+func LoadDir(dir string) error {
+	files, err := filepath.Glob(filepath.Join(dir, "*.yaml"))
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		data, err := os.ReadFile(f)
+		if err != nil {
+			return err
+		}
+		if err = LoadBundle(strings.TrimSuffix(filepath.Base(f), ".yaml"), data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Message 返回错误码在 lang 下的提示，找不到时回退到默认语言
+func Message(code int, lang string) string {
+	return Format(code, lang, nil)
+}
+
+// Format 返回错误码在 lang 下的提示，并用 params 替换其中的 {name} 参数
+func Format(code int, lang string, params map[string]interface{}) string {
+	mu.RLock()
+	msg, ok := catalog[lang][code]
+	if !ok {
+		msg = catalog[DefaultLang][code]
+	}
+	mu.RUnlock()
+	return guava.Interpolate(msg, params)
+}
+
+// Langs 返回已加载的语言
+func Langs() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	langs := make([]string, 0, len(catalog))
+	for lang := range catalog {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	return langs
+}
+
+// Missing 返回每种语言缺少翻译的已注册错误码，用于测试和发布前检查
+func Missing() map[string][]int {
+	mu.RLock()
+	defer mu.RUnlock()
+	missing := map[string][]int{}
+	for lang, bundle := range catalog {
+		for code := range codes {
+			if _, ok := bundle[code]; !ok {
+				missing[lang] = append(missing[lang], code)
+			}
+		}
+		sort.Ints(missing[lang])
+	}
+	return missing
+}
+
+// Lang 按 Accept-Language 头选出已加载且权重最高的语言，如 "en-US,en;q=0.9,zh;q=0.8"，
+// 没有匹配时返回 DefaultLang
+func Lang(acceptLanguage string) string {
+	best, bestQ := DefaultLang, 0.0
+	mu.RLock()
+	defer mu.RUnlock()
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		tag := strings.ToLower(strings.TrimSpace(fields[0]))
+		q := 1.0
+		for _, f := range fields[1:] {
+			if v := strings.TrimSpace(f); strings.HasPrefix(v, "q=") {
+				if parsed, err := strconv.ParseFloat(v[2:], 64); err == nil {
+					q = parsed
+				}
+			}
+		}
+		if q <= bestQ {
+			continue
+		}
+		for _, candidate := range []string{tag, strings.SplitN(tag, "-", 2)[0]} {
+			if _, ok := catalog[candidate]; ok {
+				best, bestQ = candidate, q
+				break
+			}
+		}
+	}
+	return best
+}
@@ -0,0 +1,45 @@
+package errno
+
+import "testing"
+
+// TestMissingTranslations 新增错误码时必须在 locales 下补齐每种语言的提示
+func TestMissingTranslations(t *testing.T) {
+	for lang, codes := range Missing() {
+		if len(codes) > 0 {
+			t.Errorf("locale %s is missing messages for codes %v", lang, codes)
+		}
+	}
+	if len(Langs()) < 2 {
+		t.Errorf("expected embedded zh and en bundles, got %v", Langs())
+	}
+}
+
+func TestLang(t *testing.T) {
+	tests := []struct {
+		header, want string
+	}{
+		{"", DefaultLang},
+		{"en-US,en;q=0.9", "en"},
+		{"fr-FR,zh;q=0.8,en;q=0.5", "zh"},
+		{"de,en;q=0.1", "en"},
+		{"zh-CN;q=0.4,en;q=0.7", "en"},
+	}
+	for _, test := range tests {
+		if got := Lang(test.header); got != test.want {
+			t.Errorf("Lang(%q) = %s, want %s", test.header, got, test.want)
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	_, resp := FromError(InvalidField.With("field", "name"), "en")
+	if msg := resp.(*err).Message; msg != "invalid parameter name" {
+		t.Errorf("message = %q", msg)
+	}
+	if msg := Format(InvalidField.Code, "en", map[string]interface{}{"other": 1}); msg != "invalid parameter {field}" {
+		t.Errorf("missing params should stay as placeholders, got %q", msg)
+	}
+	if msg := Message(NotFound.Code, "fr"); msg != Message(NotFound.Code, DefaultLang) {
+		t.Errorf("unknown locale should fall back to default, got %q", msg)
+	}
+}
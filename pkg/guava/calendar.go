@@ -0,0 +1,177 @@
+package guava
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Period 报表使用的时间周期
+type Period string
+
+const (
+	PeriodDay     Period = "day"
+	PeriodWeek    Period = "week"
+	PeriodMonth   Period = "month"
+	PeriodQuarter Period = "quarter"
+	PeriodYear    Period = "year"
+)
+
+// WeekStart 一周的第一天，StartOf(t, PeriodWeek) 使用
+var WeekStart = time.Monday
+
+// StartOf 返回 t 所在周期在 t 的时区中的起点
+func StartOf(t time.Time, p Period) time.Time {
+	y, m, d := t.Date()
+	loc := t.Location()
+	switch p {
+	case PeriodWeek:
+		offset := (int(t.Weekday()) - int(WeekStart) + 7) % 7
+		return time.Date(y, m, d-offset, 0, 0, 0, 0, loc)
+	case PeriodMonth:
+		return time.Date(y, m, 1, 0, 0, 0, 0, loc)
+	case PeriodQuarter:
+		return time.Date(y, m-(m-1)%3, 1, 0, 0, 0, 0, loc)
+	case PeriodYear:
+		return time.Date(y, 1, 1, 0, 0, 0, 0, loc)
+	}
+	return time.Date(y, m, d, 0, 0, 0, 0, loc)
+}
+
+// EndOf 返回 t 所在周期的最后一纳秒
+func EndOf(t time.Time, p Period) time.Time {
+	return Add(StartOf(t, p), p, 1).Add(-time.Nanosecond)
+}
+
+// Add 增加 n 个周期；按月、季、年增加时若目标月没有该日则取月末，如 1 月 31 日加一个月为 2 月末
+func Add(t time.Time, p Period, n int) time.Time {
+	switch p {
+	case PeriodWeek:
+		return t.AddDate(0, 0, 7*n)
+	case PeriodMonth:
+		return addMonths(t, n)
+	case PeriodQuarter:
+		return addMonths(t, 3*n)
+	case PeriodYear:
+		return addMonths(t, 12*n)
+	}
+	return t.AddDate(0, 0, n)
+}
+
+func addMonths(t time.Time, n int) time.Time {
+	y, m, d := t.Date()
+	first := time.Date(y, m+time.Month(n), 1, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+	if last := daysIn(first); d > last {
+		d = last
+	}
+	return first.AddDate(0, 0, d-1)
+}
+
+func daysIn(t time.Time) int {
+	return time.Date(t.Year(), t.Month()+1, 0, 0, 0, 0, 0, t.Location()).Day()
+}
+
+// Each 从 start 开始每隔 step 个周期调用 fn，直到超过 end 或 fn 返回 false
+func Each(start, end time.Time, p Period, step int, fn func(t time.Time) bool) {
+	if step <= 0 {
+		step = 1
+	}
+	// 每次都从 start 计算，避免按月增加时月末被逐步截短
+	for i := 0; ; i += step {
+		t := Add(start, p, i)
+		if t.After(end) || !fn(t) {
+			return
+		}
+	}
+}
+
+// Range 返回 Each 遍历到的全部时间
+func Range(start, end time.Time, p Period, step int) []time.Time {
+	var list []time.Time
+	Each(start, end, p, step, func(t time.Time) bool {
+		list = append(list, t)
+		return true
+	})
+	return list
+}
+
+// PeriodKey 周期的展示键：2021-03-04、2021-W09、2021-03、2021-Q1、2021
+func PeriodKey(t time.Time, p Period) string {
+	switch p {
+	case PeriodWeek:
+		y, w := t.ISOWeek()
+		return fmt.Sprintf("%d-W%02d", y, w)
+	case PeriodMonth:
+		return FormatDate(t, YYYY_MM)
+	case PeriodQuarter:
+		return strconv.Itoa(t.Year()) + "-Q" + strconv.Itoa((int(t.Month())+2)/3)
+	case PeriodYear:
+		return strconv.Itoa(t.Year())
+	}
+	return FormatDate(t, YYYY_MM_DD)
+}
+
+type Point struct {
+	Time  time.Time
+	Value float64
+}
+
+// Bucket 一个周期内的汇总
+type Bucket struct {
+	Start time.Time `json:"start"`
+	Key   string    `json:"key"`
+	Count int       `json:"count"`
+	Sum   float64   `json:"sum"`
+	Min   float64   `json:"min"`
+	Max   float64   `json:"max"`
+}
+
+func (b Bucket) Avg() float64 {
+	if b.Count == 0 {
+		return 0
+	}
+	return b.Sum / float64(b.Count)
+}
+
+// Buckets 将时间序列按周期汇总并按时间排序；start、end 非零时只统计该区间，并补齐没有数据的周期
+func Buckets(points []Point, p Period, start, end time.Time) []Bucket {
+	// 按 Unix 秒索引，time.Time 作为键时时区或单调时钟不同的同一时刻会被当作不同的周期
+	index := map[int64]*Bucket{}
+	get := func(t time.Time) *Bucket {
+		s := StartOf(t, p)
+		b, ok := index[s.Unix()]
+		if !ok {
+			b = &Bucket{Start: s, Key: PeriodKey(s, p)}
+			index[s.Unix()] = b
+		}
+		return b
+	}
+	bounded := !start.IsZero() && !end.IsZero()
+	if bounded {
+		Each(StartOf(start, p), end, p, 1, func(t time.Time) bool {
+			get(t)
+			return true
+		})
+	}
+	for _, pt := range points {
+		if bounded && (pt.Time.Before(start) || pt.Time.After(end)) {
+			continue
+		}
+		b := get(pt.Time)
+		if b.Count == 0 || pt.Value < b.Min {
+			b.Min = pt.Value
+		}
+		if b.Count == 0 || pt.Value > b.Max {
+			b.Max = pt.Value
+		}
+		b.Count++
+		b.Sum += pt.Value
+	}
+	list := make([]Bucket, 0, len(index))
+	for _, b := range index {
+		list = append(list, *b)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Start.Before(list[j].Start) })
+	return list
+}
@@ -0,0 +1,152 @@
+package guava
+
+import (
+	"testing"
+	"time"
+)
+
+func day(s string) time.Time {
+	t, err := time.ParseInLocation("2006-01-02 15:04:05", s, time.UTC)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestStartEndOf(t *testing.T) {
+	now := day("2021-08-18 13:45:10") // 周三
+	tests := []struct {
+		p          Period
+		start, end string
+	}{
+		{PeriodDay, "2021-08-18 00:00:00", "2021-08-18 23:59:59"},
+		{PeriodWeek, "2021-08-16 00:00:00", "2021-08-22 23:59:59"},
+		{PeriodMonth, "2021-08-01 00:00:00", "2021-08-31 23:59:59"},
+		{PeriodQuarter, "2021-07-01 00:00:00", "2021-09-30 23:59:59"},
+		{PeriodYear, "2021-01-01 00:00:00", "2021-12-31 23:59:59"},
+	}
+	for _, tt := range tests {
+		if got := StartOf(now, tt.p); !got.Equal(day(tt.start)) {
+			t.Errorf("StartOf(%s) = %v, want %s", tt.p, got, tt.start)
+		}
+		want := day(tt.end).Add(time.Second - time.Nanosecond)
+		if got := EndOf(now, tt.p); !got.Equal(want) {
+			t.Errorf("EndOf(%s) = %v, want %v", tt.p, got, want)
+		}
+	}
+	if got := StartOf(day("2021-08-22 10:00:00"), PeriodWeek); !got.Equal(day("2021-08-16 00:00:00")) {
+		t.Errorf("StartOf(sunday) = %v", got)
+	}
+}
+
+func TestAddClampsMonthEnd(t *testing.T) {
+	tests := []struct {
+		from string
+		p    Period
+		n    int
+		want string
+	}{
+		{"2021-01-31 08:00:00", PeriodMonth, 1, "2021-02-28 08:00:00"},
+		{"2020-01-31 08:00:00", PeriodMonth, 1, "2020-02-29 08:00:00"},
+		{"2021-03-31 00:00:00", PeriodMonth, -1, "2021-02-28 00:00:00"},
+		{"2021-11-30 00:00:00", PeriodQuarter, 1, "2022-02-28 00:00:00"},
+		{"2020-02-29 00:00:00", PeriodYear, 1, "2021-02-28 00:00:00"},
+		{"2021-08-18 00:00:00", PeriodWeek, -2, "2021-08-04 00:00:00"},
+	}
+	for _, tt := range tests {
+		if got := Add(day(tt.from), tt.p, tt.n); !got.Equal(day(tt.want)) {
+			t.Errorf("Add(%s, %s, %d) = %v, want %s", tt.from, tt.p, tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestRange(t *testing.T) {
+	got := Range(day("2021-01-31 00:00:00"), day("2021-05-31 00:00:00"), PeriodMonth, 1)
+	want := []string{"2021-01-31", "2021-02-28", "2021-03-31", "2021-04-30", "2021-05-31"}
+	if len(got) != len(want) {
+		t.Fatalf("Range = %v", got)
+	}
+	for i := range want {
+		if s := FormatDate(got[i], YYYY_MM_DD); s != want[i] {
+			t.Errorf("Range[%d] = %s, want %s", i, s, want[i])
+		}
+	}
+	if got = Range(day("2021-01-01 00:00:00"), day("2021-01-10 00:00:00"), PeriodDay, 3); len(got) != 4 {
+		t.Errorf("Range step 3 = %v", got)
+	}
+}
+
+func TestBuckets(t *testing.T) {
+	points := []Point{
+		{day("2021-01-15 10:00:00"), 2},
+		{day("2021-01-20 10:00:00"), 4},
+		{day("2021-03-01 10:00:00"), 1},
+		{day("2021-06-01 10:00:00"), 9},
+	}
+	got := Buckets(points, PeriodMonth, day("2021-01-01 00:00:00"), day("2021-03-31 23:59:59"))
+	if len(got) != 3 {
+		t.Fatalf("Buckets = %+v", got)
+	}
+	if b := got[0]; b.Key != "2021-01" || b.Count != 2 || b.Sum != 6 || b.Min != 2 || b.Max != 4 || b.Avg() != 3 {
+		t.Errorf("january = %+v", b)
+	}
+	if b := got[1]; b.Key != "2021-02" || b.Count != 0 {
+		t.Errorf("february should be an empty bucket: %+v", b)
+	}
+	if got = Buckets(points, PeriodQuarter, time.Time{}, time.Time{}); len(got) != 2 || got[0].Key != "2021-Q1" || got[1].Key != "2021-Q2" {
+		t.Errorf("unbounded quarters = %+v", got)
+	}
+	// 同一时区的不同 *time.Location 实例，如分别从数据库读取的时间
+	cst1, cst2 := time.FixedZone("CST", 8*3600), time.FixedZone("CST", 8*3600)
+	zoned := []Point{
+		{time.Date(2021, 1, 15, 10, 0, 0, 0, cst1), 1},
+		{time.Date(2021, 1, 15, 12, 0, 0, 0, cst2), 2},
+	}
+	if got = Buckets(zoned, PeriodDay, time.Time{}, time.Time{}); len(got) != 1 || got[0].Count != 2 {
+		t.Errorf("same day in equal zones = %+v", got)
+	}
+	if key := PeriodKey(day("2021-01-03 00:00:00"), PeriodWeek); key != "2020-W53" {
+		t.Errorf("PeriodKey(week) = %s", key)
+	}
+}
+
+func TestHumanize(t *testing.T) {
+	d := 2*24*time.Hour + 3*time.Hour + 5*time.Minute
+	tests := []struct {
+		d         time.Duration
+		lang, out string
+	}{
+		{d, "zh", "2天3小时"},
+		{d, "en-US", "2 days 3 hours"},
+		{time.Hour + 30*time.Second, "en", "1 hour"},
+		{90 * time.Second, "zh", "1分钟30秒"},
+		{0, "en", "0 seconds"},
+	}
+	for _, tt := range tests {
+		if got := HumanizeDuration(tt.d, tt.lang); got != tt.out {
+			t.Errorf("HumanizeDuration(%v, %s) = %q, want %q", tt.d, tt.lang, got, tt.out)
+		}
+	}
+	now := day("2021-08-18 12:00:00")
+	since := []struct {
+		t         time.Time
+		lang, out string
+	}{
+		{now.Add(-30 * time.Second), "zh", "刚刚"},
+		{now.Add(-3 * time.Minute), "zh", "3分钟前"},
+		{now.Add(-26 * time.Hour), "en", "1 day ago"},
+		{now.Add(49 * time.Hour), "en", "in 2 days"},
+		{now.Add(49 * time.Hour), "zh", "2天后"},
+	}
+	for _, tt := range since {
+		if got := HumanizeSince(tt.t, now, tt.lang); got != tt.out {
+			t.Errorf("HumanizeSince(%v) = %q, want %q", tt.t, got, tt.out)
+		}
+	}
+	if age := Age(day("1990-08-19 00:00:00"), now); age != 30 {
+		t.Errorf("Age = %d, want 30", age)
+	}
+	if age := Age(day("1990-08-18 00:00:00"), now); age != 31 {
+		t.Errorf("Age on birthday = %d, want 31", age)
+	}
+}
@@ -0,0 +1,74 @@
+package guava
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+func isWordSep(r rune) bool {
+	return r == '_' || r == '-' || r == '.' || unicode.IsSpace(r)
+}
+
+// ToSnake 转换为 snake_case，对 Go 标识符与 xorm 的 SnakeMapper 结果一致，
+// 即每个大写字母前都加下划线：UserID -> user_i_d；空格、- 和 . 视为分隔符
+func ToSnake(s string) string {
+	return delimit(s, '_')
+}
+
+// ToKebab 转换为 kebab-case，规则同 ToSnake
+func ToKebab(s string) string {
+	return delimit(s, '-')
+}
+
+func delimit(s string, sep rune) string {
+	var b strings.Builder
+	b.Grow(len(s) + 4)
+	last := sep
+	for _, r := range s {
+		switch {
+		case isWordSep(r):
+			r = sep
+			if last == sep {
+				continue
+			}
+		case unicode.IsUpper(r):
+			if last != sep {
+				b.WriteRune(sep)
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+		last = r
+	}
+	return strings.TrimSuffix(b.String(), string(sep))
+}
+
+// ToCamel 转换为 UpperCamelCase，与 SnakeMapper.Table2Obj 一致：user_i_d -> UserID
+func ToCamel(s string) string {
+	var b strings.Builder
+	b.Grow(len(s))
+	upper := true
+	for _, r := range s {
+		if isWordSep(r) {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// ToLowerCamel 转换为 lowerCamelCase，常用于 JSON 字段名
+func ToLowerCamel(s string) string {
+	c := ToCamel(s)
+	r, size := utf8.DecodeRuneInString(c)
+	if r == utf8.RuneError {
+		return c
+	}
+	return string(unicode.ToLower(r)) + c[size:]
+}
@@ -0,0 +1,63 @@
+package guava
+
+import (
+	"sort"
+	"strings"
+)
+
+// Collator 生成用于比较的键，如按拼音比较时返回汉字的拼音；项目按需实现并设置 DefaultCollator
+type Collator interface {
+	Key(s string) string
+}
+
+// CollatorFunc 将函数适配为 Collator
+type CollatorFunc func(s string) string
+
+func (f CollatorFunc) Key(s string) string {
+	return f(s)
+}
+
+// FoldCollator 忽略大小写和首尾空白
+type FoldCollator struct{}
+
+func (FoldCollator) Key(s string) string {
+	return strings.ToLower(strings.TrimSpace(s))
+}
+
+// DefaultCollator 未指定 Collator 时使用
+var DefaultCollator Collator = FoldCollator{}
+
+func collatorOf(c []Collator) Collator {
+	if len(c) > 0 && c[0] != nil {
+		return c[0]
+	}
+	return DefaultCollator
+}
+
+// Equal 按 Collator 比较两个字符串是否相同
+func Equal(a, b string, c ...Collator) bool {
+	col := collatorOf(c)
+	return col.Key(a) == col.Key(b)
+}
+
+// Compare 按 Collator 的键比较，返回 -1、0、1
+func Compare(a, b string, c ...Collator) int {
+	col := collatorOf(c)
+	return strings.Compare(col.Key(a), col.Key(b))
+}
+
+// ContainsText 按 Collator 判断 s 是否包含 sub，用于搜索框的模糊匹配
+func ContainsText(s, sub string, c ...Collator) bool {
+	col := collatorOf(c)
+	return strings.Contains(col.Key(s), col.Key(sub))
+}
+
+// SortStrings 按 Collator 稳定排序，每个元素只计算一次键
+func SortStrings(list []string, c ...Collator) {
+	col := collatorOf(c)
+	keys := make(map[string]string, len(list))
+	for _, s := range list {
+		keys[s] = col.Key(s)
+	}
+	sort.SliceStable(list, func(i, j int) bool { return keys[list[i]] < keys[list[j]] })
+}
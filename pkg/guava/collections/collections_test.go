@@ -0,0 +1,228 @@
+package collections
+
+import (
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+type user struct {
+	Name string
+	Dept string
+	Age  int
+}
+
+var users = []user{
+	{"a", "dev", 30},
+	{"b", "ops", 25},
+	{"c", "dev", 41},
+	{"d", "qa", 25},
+}
+
+func TestSliceHelpers(t *testing.T) {
+	if !Contains([]string{"x", "y"}, "y") || Contains([]int{1, 2}, 3) {
+		t.Error("Contains")
+	}
+	if i := IndexOf([]int{4, 5, 5}, 5); i != 1 {
+		t.Errorf("IndexOf = %d", i)
+	}
+	names := Map(users, func(u user) string { return u.Name })
+	if !reflect.DeepEqual(names, []string{"a", "b", "c", "d"}) {
+		t.Errorf("Map = %v", names)
+	}
+	young := Filter(users, func(u user) bool { return u.Age < 30 })
+	if len(young) != 2 || young[0].Name != "b" {
+		t.Errorf("Filter = %v", young)
+	}
+	if sum := Reduce(users, 0, func(acc int, u user) int { return acc + u.Age }); sum != 121 {
+		t.Errorf("Reduce = %d", sum)
+	}
+	groups := GroupBy(users, func(u user) string { return u.Dept })
+	if len(groups) != 3 || len(groups["dev"]) != 2 || groups["dev"][1].Name != "c" {
+		t.Errorf("GroupBy = %v", groups)
+	}
+	match, rest := Partition([]int{1, 2, 3, 4, 5}, func(n int) bool { return n%2 == 0 })
+	if !reflect.DeepEqual(match, []int{2, 4}) || !reflect.DeepEqual(rest, []int{1, 3, 5}) {
+		t.Errorf("Partition = %v %v", match, rest)
+	}
+	if u := Uniq([]int{3, 1, 3, 2, 1}); !reflect.DeepEqual(u, []int{3, 1, 2}) {
+		t.Errorf("Uniq = %v", u)
+	}
+	if u := UniqBy(users, func(u user) int { return u.Age }); len(u) != 3 || u[1].Name != "b" {
+		t.Errorf("UniqBy = %v", u)
+	}
+	if m := ToMap(users, func(u user) int { return u.Age }); m[25].Name != "d" {
+		t.Errorf("ToMap = %v", m)
+	}
+	if Max(3, 9, 1) != 9 || Min("b", "a", "c") != "a" {
+		t.Error("Max/Min")
+	}
+}
+
+func TestChunk(t *testing.T) {
+	tests := []struct {
+		n, size int
+		want    []int
+	}{
+		{0, 3, nil},
+		{5, 2, []int{2, 2, 1}},
+		{6, 3, []int{3, 3}},
+		{2, 5, []int{2}},
+	}
+	for _, tt := range tests {
+		list := make([]int, tt.n)
+		var got []int
+		for _, c := range Chunk(list, tt.size) {
+			got = append(got, len(c))
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Chunk(%d, %d) sizes = %v, want %v", tt.n, tt.size, got, tt.want)
+		}
+	}
+	// 追加到前一块不能覆盖后一块
+	chunks := Chunk([]int{1, 2, 3, 4}, 2)
+	_ = append(chunks[0], 9)
+	if chunks[1][0] != 3 {
+		t.Errorf("append to chunk overwrote the next chunk: %v", chunks)
+	}
+}
+
+func TestSet(t *testing.T) {
+	a := NewSet(1, 2, 3)
+	b := NewSet(2, 3, 4)
+	if got := Sorted(a.Union(b)); !reflect.DeepEqual(got, []int{1, 2, 3, 4}) {
+		t.Errorf("Union = %v", got)
+	}
+	if got := Sorted(a.Intersect(b)); !reflect.DeepEqual(got, []int{2, 3}) {
+		t.Errorf("Intersect = %v", got)
+	}
+	if got := Sorted(a.Difference(b)); !reflect.DeepEqual(got, []int{1}) {
+		t.Errorf("Difference = %v", got)
+	}
+	a.Remove(1)
+	if a.Has(1) || a.Len() != 2 {
+		t.Errorf("Remove: %v", a)
+	}
+	if got := Union([]string{"b", "a"}, []string{"a", "c"}); !reflect.DeepEqual(got, []string{"b", "a", "c"}) {
+		t.Errorf("slice Union = %v", got)
+	}
+	if got := Intersect([]int{5, 1, 2, 1}, []int{1, 5}); !reflect.DeepEqual(got, []int{5, 1}) {
+		t.Errorf("slice Intersect = %v", got)
+	}
+	if got := Difference([]int{5, 1, 2, 2}, []int{1}); !reflect.DeepEqual(got, []int{5, 2}) {
+		t.Errorf("slice Difference = %v", got)
+	}
+}
+
+func TestOrderedMap(t *testing.T) {
+	m := NewOrderedMap[string, int]()
+	for i, k := range []string{"z", "a", "m", "b"} {
+		m.Set(k, i)
+	}
+	m.Set("a", 10)
+	m.Delete("m")
+	m.Delete("missing")
+	if got := m.Keys(); !reflect.DeepEqual(got, []string{"z", "a", "b"}) {
+		t.Errorf("Keys = %v", got)
+	}
+	if v, ok := m.Get("a"); !ok || v != 10 {
+		t.Errorf("Get(a) = %d, %v", v, ok)
+	}
+	data, err := json.Marshal(m)
+	if err != nil || string(data) != `{"z":0,"a":10,"b":3}` {
+		t.Errorf("Marshal = %s, %v", data, err)
+	}
+	back := NewOrderedMap[string, int]()
+	if err = json.Unmarshal([]byte(`{"y":1,"x":2,"w":3}`), back); err != nil {
+		t.Fatal(err)
+	}
+	if got := back.Values(); !reflect.DeepEqual(got, []int{1, 2, 3}) {
+		t.Errorf("Unmarshal order = %v", got)
+	}
+	var ints OrderedMap[int, string]
+	if err = json.Unmarshal([]byte(`{"1":"a"}`), &ints); err == nil {
+		t.Error("non-string keys accepted")
+	}
+	m.Delete("z")
+	m.Delete("b")
+	m.Delete("a")
+	m.Set("n", 1)
+	if got := m.Keys(); !reflect.DeepEqual(got, []string{"n"}) {
+		t.Errorf("Keys after emptying = %v", got)
+	}
+}
+
+var sink int
+
+func ints(n int) []int {
+	list := make([]int, n)
+	for i := range list {
+		list[i] = i % (n / 2)
+	}
+	return list
+}
+
+// inIface 旧的 interface{} 实现，作为对照
+func inIface(val interface{}, slice ...interface{}) bool {
+	for _, v := range slice {
+		if v == val {
+			return true
+		}
+	}
+	return false
+}
+
+func BenchmarkContains(b *testing.B) {
+	list := ints(1000)
+	for i := 0; i < b.N; i++ {
+		if Contains(list, 499) {
+			sink++
+		}
+	}
+}
+
+func BenchmarkContainsInterface(b *testing.B) {
+	list := ints(1000)
+	boxed := make([]interface{}, len(list))
+	for i, v := range list {
+		boxed[i] = v
+	}
+	for i := 0; i < b.N; i++ {
+		if inIface(499, boxed...) {
+			sink++
+		}
+	}
+}
+
+func BenchmarkMap(b *testing.B) {
+	list := ints(1000)
+	for i := 0; i < b.N; i++ {
+		sink += len(Map(list, strconv.Itoa))
+	}
+}
+
+func BenchmarkMapLoop(b *testing.B) {
+	list := ints(1000)
+	for i := 0; i < b.N; i++ {
+		out := make([]string, len(list))
+		for j, v := range list {
+			out[j] = strconv.Itoa(v)
+		}
+		sink += len(out)
+	}
+}
+
+func BenchmarkUniq(b *testing.B) {
+	list := ints(1000)
+	for i := 0; i < b.N; i++ {
+		sink += len(Uniq(list))
+	}
+}
+
+func BenchmarkGroupBy(b *testing.B) {
+	list := ints(1000)
+	for i := 0; i < b.N; i++ {
+		sink += len(GroupBy(list, func(v int) int { return v % 10 }))
+	}
+}
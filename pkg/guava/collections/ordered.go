@@ -0,0 +1,145 @@
+package collections
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+type entry[K comparable, V any] struct {
+	key        K
+	value      V
+	prev, next *entry[K, V]
+}
+
+// OrderedMap 按插入顺序遍历的 map，重复 Set 不改变键的位置；
+// 序列化为 JSON 对象时保持顺序，非并发安全
+type OrderedMap[K comparable, V any] struct {
+	index      map[K]*entry[K, V]
+	head, tail *entry[K, V]
+}
+
+func NewOrderedMap[K comparable, V any]() *OrderedMap[K, V] {
+	return &OrderedMap[K, V]{index: map[K]*entry[K, V]{}}
+}
+
+func (m *OrderedMap[K, V]) Set(key K, value V) {
+	if m.index == nil {
+		m.index = map[K]*entry[K, V]{}
+	}
+	if e, ok := m.index[key]; ok {
+		e.value = value
+		return
+	}
+	e := &entry[K, V]{key: key, value: value, prev: m.tail}
+	if m.tail == nil {
+		m.head = e
+	} else {
+		m.tail.next = e
+	}
+	m.tail = e
+	m.index[key] = e
+}
+
+func (m *OrderedMap[K, V]) Get(key K) (V, bool) {
+	if e, ok := m.index[key]; ok {
+		return e.value, true
+	}
+	var zero V
+	return zero, false
+}
+
+func (m *OrderedMap[K, V]) Delete(key K) {
+	e, ok := m.index[key]
+	if !ok {
+		return
+	}
+	if e.prev == nil {
+		m.head = e.next
+	} else {
+		e.prev.next = e.next
+	}
+	if e.next == nil {
+		m.tail = e.prev
+	} else {
+		e.next.prev = e.prev
+	}
+	delete(m.index, key)
+}
+
+func (m *OrderedMap[K, V]) Len() int {
+	return len(m.index)
+}
+
+// Range 按插入顺序遍历，fn 返回 false 时停止；遍历中不能修改 map
+func (m *OrderedMap[K, V]) Range(fn func(key K, value V) bool) {
+	for e := m.head; e != nil; e = e.next {
+		if !fn(e.key, e.value) {
+			return
+		}
+	}
+}
+
+func (m *OrderedMap[K, V]) Keys() []K {
+	out := make([]K, 0, m.Len())
+	for e := m.head; e != nil; e = e.next {
+		out = append(out, e.key)
+	}
+	return out
+}
+
+func (m *OrderedMap[K, V]) Values() []V {
+	out := make([]V, 0, m.Len())
+	for e := m.head; e != nil; e = e.next {
+		out = append(out, e.value)
+	}
+	return out
+}
+
+// MarshalJSON 键使用 fmt.Sprint 的结果
+func (m *OrderedMap[K, V]) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for e := m.head; e != nil; e = e.next {
+		if e != m.head {
+			buf.WriteByte(',')
+		}
+		key, _ := json.Marshal(fmt.Sprint(e.key))
+		value, err := json.Marshal(e.value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// UnmarshalJSON 按 JSON 中的顺序追加，只支持字符串类型的键
+func (m *OrderedMap[K, V]) UnmarshalJSON(data []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if t, err := dec.Token(); err != nil {
+		return err
+	} else if t != json.Delim('{') {
+		return fmt.Errorf("collections: ordered map expects a JSON object")
+	}
+	for dec.More() {
+		t, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		key, ok := any(t).(K)
+		if !ok {
+			return fmt.Errorf("collections: cannot use JSON key %q as %T", t, key)
+		}
+		var value V
+		if err = dec.Decode(&value); err != nil {
+			return err
+		}
+		m.Set(key, value)
+	}
+	_, err := dec.Token()
+	return err
+}
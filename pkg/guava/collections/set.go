@@ -0,0 +1,102 @@
+package collections
+
+import "sort"
+
+// Set 基于 map 的集合，零值不可用，使用 NewSet 创建
+type Set[T comparable] map[T]struct{}
+
+func NewSet[T comparable](items ...T) Set[T] {
+	s := make(Set[T], len(items))
+	s.Add(items...)
+	return s
+}
+
+func (s Set[T]) Add(items ...T) {
+	for _, v := range items {
+		s[v] = struct{}{}
+	}
+}
+
+func (s Set[T]) Remove(items ...T) {
+	for _, v := range items {
+		delete(s, v)
+	}
+}
+
+func (s Set[T]) Has(v T) bool {
+	_, ok := s[v]
+	return ok
+}
+
+func (s Set[T]) Len() int {
+	return len(s)
+}
+
+// Items 返回集合元素，顺序不确定
+func (s Set[T]) Items() []T {
+	return Keys(s)
+}
+
+func (s Set[T]) Union(other Set[T]) Set[T] {
+	out := make(Set[T], len(s)+len(other))
+	for v := range s {
+		out[v] = struct{}{}
+	}
+	for v := range other {
+		out[v] = struct{}{}
+	}
+	return out
+}
+
+func (s Set[T]) Intersect(other Set[T]) Set[T] {
+	small, big := s, other
+	if len(small) > len(big) {
+		small, big = big, small
+	}
+	out := make(Set[T])
+	for v := range small {
+		if big.Has(v) {
+			out[v] = struct{}{}
+		}
+	}
+	return out
+}
+
+// Difference 返回在 s 中但不在 other 中的元素
+func (s Set[T]) Difference(other Set[T]) Set[T] {
+	out := make(Set[T])
+	for v := range s {
+		if !other.Has(v) {
+			out[v] = struct{}{}
+		}
+	}
+	return out
+}
+
+// Sorted 返回排序后的元素
+func Sorted[T Ordered](s Set[T]) []T {
+	out := s.Items()
+	sort.Slice(out, func(i, j int) bool { return out[i] < out[j] })
+	return out
+}
+
+// Union 合并多个切片并去重，保持第一次出现的顺序
+func Union[T comparable](lists ...[]T) []T {
+	var all []T
+	for _, l := range lists {
+		all = append(all, l...)
+	}
+	return Uniq(all)
+}
+
+// Intersect 返回同时出现在 a 和 b 中的元素，按 a 的顺序去重
+func Intersect[T comparable](a, b []T) []T {
+	in := NewSet(b...)
+	return Uniq(Filter(a, in.Has))
+}
+
+// Difference 返回在 a 中但不在 b 中的元素，按 a 的顺序去重
+func Difference[T comparable](a, b []T) []T {
+	in := NewSet(b...)
+	return Uniq(Filter(a, func(v T) bool { return !in.Has(v) }))
+}
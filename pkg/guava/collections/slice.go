@@ -0,0 +1,158 @@
+// Package collections 提供切片、集合和有序 map 的泛型工具，函数不修改传入的切片
+package collections
+
+// Ordered 可以用 < 比较的类型
+type Ordered interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 |
+		~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uintptr |
+		~float32 | ~float64 | ~string
+}
+
+func Contains[T comparable](list []T, v T) bool {
+	return IndexOf(list, v) >= 0
+}
+
+// IndexOf 返回 v 第一次出现的下标，不存在时返回 -1
+func IndexOf[T comparable](list []T, v T) int {
+	for i := range list {
+		if list[i] == v {
+			return i
+		}
+	}
+	return -1
+}
+
+func ContainsFunc[T any](list []T, fn func(T) bool) bool {
+	for i := range list {
+		if fn(list[i]) {
+			return true
+		}
+	}
+	return false
+}
+
+func Map[T, R any](list []T, fn func(T) R) []R {
+	out := make([]R, len(list))
+	for i := range list {
+		out[i] = fn(list[i])
+	}
+	return out
+}
+
+func Filter[T any](list []T, fn func(T) bool) []T {
+	out := make([]T, 0, len(list))
+	for i := range list {
+		if fn(list[i]) {
+			out = append(out, list[i])
+		}
+	}
+	return out
+}
+
+func Reduce[T, R any](list []T, init R, fn func(acc R, v T) R) R {
+	for i := range list {
+		init = fn(init, list[i])
+	}
+	return init
+}
+
+// GroupBy 按 key 分组，组内保持原顺序
+func GroupBy[T any, K comparable](list []T, key func(T) K) map[K][]T {
+	out := make(map[K][]T)
+	for i := range list {
+		k := key(list[i])
+		out[k] = append(out[k], list[i])
+	}
+	return out
+}
+
+// Partition 返回满足和不满足 fn 的两部分
+func Partition[T any](list []T, fn func(T) bool) (match, rest []T) {
+	for i := range list {
+		if fn(list[i]) {
+			match = append(match, list[i])
+		} else {
+			rest = append(rest, list[i])
+		}
+	}
+	return match, rest
+}
+
+// Chunk 按 size 切分，各块共享 list 的底层数组；size 小于 1 时 panic
+func Chunk[T any](list []T, size int) [][]T {
+	if size < 1 {
+		panic("collections: chunk size must be positive")
+	}
+	out := make([][]T, 0, (len(list)+size-1)/size)
+	for size < len(list) {
+		list, out = list[size:], append(out, list[:size:size])
+	}
+	if len(list) > 0 {
+		out = append(out, list)
+	}
+	return out
+}
+
+// Uniq 去重并保持第一次出现的顺序
+func Uniq[T comparable](list []T) []T {
+	return UniqBy(list, func(v T) T { return v })
+}
+
+func UniqBy[T any, K comparable](list []T, key func(T) K) []T {
+	seen := make(map[K]struct{}, len(list))
+	out := make([]T, 0, len(list))
+	for i := range list {
+		k := key(list[i])
+		if _, ok := seen[k]; ok {
+			continue
+		}
+		seen[k] = struct{}{}
+		out = append(out, list[i])
+	}
+	return out
+}
+
+// ToMap 以 key 为键建立索引，键重复时保留最后一个
+func ToMap[T any, K comparable](list []T, key func(T) K) map[K]T {
+	out := make(map[K]T, len(list))
+	for i := range list {
+		out[key(list[i])] = list[i]
+	}
+	return out
+}
+
+// Keys 返回 map 的键，顺序不确定
+func Keys[K comparable, V any](m map[K]V) []K {
+	out := make([]K, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	return out
+}
+
+// Values 返回 map 的值，顺序不确定
+func Values[K comparable, V any](m map[K]V) []V {
+	out := make([]V, 0, len(m))
+	for _, v := range m {
+		out = append(out, v)
+	}
+	return out
+}
+
+func Max[T Ordered](first T, rest ...T) T {
+	for _, v := range rest {
+		if v > first {
+			first = v
+		}
+	}
+	return first
+}
+
+func Min[T Ordered](first T, rest ...T) T {
+	for _, v := range rest {
+		if v < first {
+			first = v
+		}
+	}
+	return first
+}
@@ -0,0 +1,347 @@
+// Package conv 提供返回错误的类型转换，ToXxx 无法转换时返回错误，ToXxxOr 返回默认值。
+// 字符串会去掉首尾空白和千分位逗号，json.Number 按字符串处理，指针和自定义类型按底层类型处理
+package conv
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrInvalid 值的类型或格式无法转换
+	ErrInvalid = errors.New("invalid value")
+	// ErrOverflow 值超出目标类型的范围，或转换为整数时有小数部分
+	ErrOverflow = errors.New("value out of range")
+)
+
+func fail(v interface{}, to string, err error) error {
+	return fmt.Errorf("conv: cannot convert %T(%v) to %s: %w", v, v, to, err)
+}
+
+var grouped = regexp.MustCompile(`^[+-]?\d{1,3}(,\d{3})+(\.\d*)?$`)
+
+// clean 去掉空白和千分位逗号，如 " 1,234.5 " 为 "1234.5"
+func clean(s string) string {
+	s = strings.TrimSpace(s)
+	if grouped.MatchString(s) {
+		s = strings.ReplaceAll(s, ",", "")
+	}
+	return s
+}
+
+func indirect(v interface{}) reflect.Value {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return reflect.Value{}
+		}
+		rv = rv.Elem()
+	}
+	return rv
+}
+
+func toInt(v interface{}, bits int, to string) (int64, error) {
+	rv := indirect(v)
+	var n int64
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n = rv.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		u := rv.Uint()
+		if u > math.MaxInt64 {
+			return 0, fail(v, to, ErrOverflow)
+		}
+		n = int64(u)
+	case reflect.Float32, reflect.Float64:
+		f := rv.Float()
+		if f != math.Trunc(f) || f < math.MinInt64 || f >= math.MaxInt64 {
+			return 0, fail(v, to, ErrOverflow)
+		}
+		n = int64(f)
+	case reflect.Bool:
+		if rv.Bool() {
+			n = 1
+		}
+	case reflect.String:
+		s := clean(rv.String())
+		i, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			if errors.Is(err, strconv.ErrRange) {
+				return 0, fail(v, to, ErrOverflow)
+			}
+			// 允许 "3.0"、"1e3" 这类没有小数部分的数字
+			f, ferr := strconv.ParseFloat(s, 64)
+			if ferr != nil {
+				return 0, fail(v, to, ErrInvalid)
+			}
+			return toInt(f, bits, to)
+		}
+		n = i
+	default:
+		return 0, fail(v, to, ErrInvalid)
+	}
+	if bits < 64 && (n < -1<<(bits-1) || n > 1<<(bits-1)-1) {
+		return 0, fail(v, to, ErrOverflow)
+	}
+	return n, nil
+}
+
+func toUint(v interface{}, bits int, to string) (uint64, error) {
+	rv := indirect(v)
+	var n uint64
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i := rv.Int()
+		if i < 0 {
+			return 0, fail(v, to, ErrOverflow)
+		}
+		n = uint64(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n = rv.Uint()
+	case reflect.Float32, reflect.Float64:
+		f := rv.Float()
+		if f != math.Trunc(f) || f < 0 || f >= math.MaxUint64 {
+			return 0, fail(v, to, ErrOverflow)
+		}
+		n = uint64(f)
+	case reflect.Bool:
+		if rv.Bool() {
+			n = 1
+		}
+	case reflect.String:
+		s := strings.TrimPrefix(clean(rv.String()), "+")
+		u, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			if errors.Is(err, strconv.ErrRange) || strings.HasPrefix(s, "-") {
+				return 0, fail(v, to, ErrOverflow)
+			}
+			f, ferr := strconv.ParseFloat(s, 64)
+			if ferr != nil {
+				return 0, fail(v, to, ErrInvalid)
+			}
+			return toUint(f, bits, to)
+		}
+		n = u
+	default:
+		return 0, fail(v, to, ErrInvalid)
+	}
+	if bits < 64 && n > 1<<bits-1 {
+		return 0, fail(v, to, ErrOverflow)
+	}
+	return n, nil
+}
+
+func toFloat(v interface{}, bits int, to string) (float64, error) {
+	rv := indirect(v)
+	var f float64
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		f = float64(rv.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		f = float64(rv.Uint())
+	case reflect.Float32, reflect.Float64:
+		f = rv.Float()
+	case reflect.Bool:
+		if rv.Bool() {
+			f = 1
+		}
+	case reflect.String:
+		p, err := strconv.ParseFloat(clean(rv.String()), 64)
+		if err != nil {
+			if errors.Is(err, strconv.ErrRange) {
+				return 0, fail(v, to, ErrOverflow)
+			}
+			return 0, fail(v, to, ErrInvalid)
+		}
+		f = p
+	default:
+		return 0, fail(v, to, ErrInvalid)
+	}
+	if bits == 32 && math.Abs(f) > math.MaxFloat32 && !math.IsInf(f, 0) {
+		return 0, fail(v, to, ErrOverflow)
+	}
+	return f, nil
+}
+
+func ToInt(v interface{}) (int, error) {
+	n, err := toInt(v, strconv.IntSize, "int")
+	return int(n), err
+}
+
+func ToInt8(v interface{}) (int8, error) {
+	n, err := toInt(v, 8, "int8")
+	return int8(n), err
+}
+
+func ToInt16(v interface{}) (int16, error) {
+	n, err := toInt(v, 16, "int16")
+	return int16(n), err
+}
+
+func ToInt32(v interface{}) (int32, error) {
+	n, err := toInt(v, 32, "int32")
+	return int32(n), err
+}
+
+func ToInt64(v interface{}) (int64, error) {
+	return toInt(v, 64, "int64")
+}
+
+func ToUint(v interface{}) (uint, error) {
+	n, err := toUint(v, strconv.IntSize, "uint")
+	return uint(n), err
+}
+
+func ToUint8(v interface{}) (uint8, error) {
+	n, err := toUint(v, 8, "uint8")
+	return uint8(n), err
+}
+
+func ToUint16(v interface{}) (uint16, error) {
+	n, err := toUint(v, 16, "uint16")
+	return uint16(n), err
+}
+
+func ToUint32(v interface{}) (uint32, error) {
+	n, err := toUint(v, 32, "uint32")
+	return uint32(n), err
+}
+
+func ToUint64(v interface{}) (uint64, error) {
+	return toUint(v, 64, "uint64")
+}
+
+func ToFloat32(v interface{}) (float32, error) {
+	f, err := toFloat(v, 32, "float32")
+	return float32(f), err
+}
+
+func ToFloat64(v interface{}) (float64, error) {
+	return toFloat(v, 64, "float64")
+}
+
+// ToBool 数字非零为 true；字符串支持 strconv.ParseBool 的格式以及 yes/no、y/n、on/off
+func ToBool(v interface{}) (bool, error) {
+	rv := indirect(v)
+	switch rv.Kind() {
+	case reflect.Bool:
+		return rv.Bool(), nil
+	case reflect.String:
+		switch s := strings.ToLower(strings.TrimSpace(rv.String())); s {
+		case "yes", "y", "on":
+			return true, nil
+		case "no", "n", "off":
+			return false, nil
+		default:
+			b, err := strconv.ParseBool(s)
+			if err != nil {
+				return false, fail(v, "bool", ErrInvalid)
+			}
+			return b, nil
+		}
+	}
+	f, err := toFloat(v, 64, "bool")
+	if err != nil {
+		return false, err
+	}
+	return f != 0, nil
+}
+
+// ToDuration 字符串按 time.ParseDuration 解析，没有单位的数字和整数按纳秒处理
+func ToDuration(v interface{}) (time.Duration, error) {
+	rv := indirect(v)
+	if rv.Kind() == reflect.String {
+		s := clean(rv.String())
+		if d, err := time.ParseDuration(s); err == nil {
+			return d, nil
+		}
+		if _, err := strconv.ParseFloat(s, 64); err != nil {
+			return 0, fail(v, "duration", ErrInvalid)
+		}
+	}
+	n, err := toInt(v, 64, "duration")
+	return time.Duration(n), err
+}
+
+// TimeLayouts ToTime 解析字符串时依次尝试的格式，不含时区的格式按本地时区解析
+var TimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	"2006/01/02 15:04:05",
+	"2006/01/02",
+	"20060102150405",
+	"20060102",
+}
+
+// ToTime 字符串按 TimeLayouts 解析，整数和不匹配任何格式的数字字符串按毫秒时间戳处理
+func ToTime(v interface{}) (time.Time, error) {
+	switch t := v.(type) {
+	case time.Time:
+		return t, nil
+	case *time.Time:
+		if t != nil {
+			return *t, nil
+		}
+		return time.Time{}, fail(v, "time", ErrInvalid)
+	}
+	rv := indirect(v)
+	if rv.Kind() == reflect.String {
+		s := strings.TrimSpace(rv.String())
+		for _, layout := range TimeLayouts {
+			if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+				return t, nil
+			}
+		}
+	}
+	ms, err := toInt(v, 64, "time")
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(0, 0).Add(time.Duration(ms) * time.Millisecond), nil
+}
+
+// ToString 数字按十进制格式化，[]byte、error 和 fmt.Stringer 取其文本，nil 为空字符串
+func ToString(v interface{}) (string, error) {
+	switch s := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return s, nil
+	case json.Number:
+		return string(s), nil
+	case []byte:
+		return string(s), nil
+	case error:
+		return s.Error(), nil
+	case fmt.Stringer:
+		return s.String(), nil
+	}
+	rv := indirect(v)
+	switch rv.Kind() {
+	case reflect.Invalid:
+		return "", nil
+	case reflect.String:
+		return rv.String(), nil
+	case reflect.Bool:
+		return strconv.FormatBool(rv.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(rv.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(rv.Uint(), 10), nil
+	case reflect.Float32:
+		return strconv.FormatFloat(rv.Float(), 'f', -1, 32), nil
+	case reflect.Float64:
+		return strconv.FormatFloat(rv.Float(), 'f', -1, 64), nil
+	}
+	return "", fail(v, "string", ErrInvalid)
+}
@@ -0,0 +1,144 @@
+package conv
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+type status int
+
+func TestToInt64(t *testing.T) {
+	n := 7
+	tests := []struct {
+		in   interface{}
+		want int64
+		err  error
+	}{
+		{42, 42, nil},
+		{uint8(8), 8, nil},
+		{status(3), 3, nil},
+		{&n, 7, nil},
+		{3.0, 3, nil},
+		{true, 1, nil},
+		{" 12 ", 12, nil},
+		{"-1,234,567", -1234567, nil},
+		{"1e3", 1000, nil},
+		{json.Number("99"), 99, nil},
+		{"9223372036854775807", math.MaxInt64, nil},
+		{"9223372036854775808", 0, ErrOverflow},
+		{uint64(math.MaxUint64), 0, ErrOverflow},
+		{3.5, 0, ErrOverflow},
+		{"1,23", 0, ErrInvalid},
+		{"", 0, ErrInvalid},
+		{"abc", 0, ErrInvalid},
+		{[]int{1}, 0, ErrInvalid},
+		{nil, 0, ErrInvalid},
+	}
+	for _, tt := range tests {
+		got, err := ToInt64(tt.in)
+		if !errors.Is(err, tt.err) || got != tt.want {
+			t.Errorf("ToInt64(%#v) = %d, %v; want %d, %v", tt.in, got, err, tt.want, tt.err)
+		}
+	}
+}
+
+func TestSizedInts(t *testing.T) {
+	if _, err := ToInt8(128); !errors.Is(err, ErrOverflow) {
+		t.Errorf("ToInt8(128) err = %v", err)
+	}
+	if n, err := ToInt8("-128"); err != nil || n != -128 {
+		t.Errorf("ToInt8(-128) = %d, %v", n, err)
+	}
+	if _, err := ToUint8(256); !errors.Is(err, ErrOverflow) {
+		t.Errorf("ToUint8(256) err = %v", err)
+	}
+	if _, err := ToUint("-1"); !errors.Is(err, ErrOverflow) {
+		t.Errorf("ToUint(-1) err = %v", err)
+	}
+	if n, err := ToUint64("18446744073709551615"); err != nil || n != math.MaxUint64 {
+		t.Errorf("ToUint64(max) = %d, %v", n, err)
+	}
+	if n, err := ToInt32(json.Number("2147483647")); err != nil || n != math.MaxInt32 {
+		t.Errorf("ToInt32(max) = %d, %v", n, err)
+	}
+	if _, err := ToFloat32(1e300); !errors.Is(err, ErrOverflow) {
+		t.Errorf("ToFloat32(1e300) err = %v", err)
+	}
+	if f, err := ToFloat64(" 1,234.5 "); err != nil || f != 1234.5 {
+		t.Errorf("ToFloat64 = %v, %v", f, err)
+	}
+	if n := ToIntOr("x", -1); n != -1 {
+		t.Errorf("ToIntOr = %d", n)
+	}
+}
+
+func TestToBoolDurationString(t *testing.T) {
+	for in, want := range map[interface{}]bool{"yes": true, " Off ": false, "1": true, "false": false, 0: false, 2.5: true} {
+		if got, err := ToBool(in); err != nil || got != want {
+			t.Errorf("ToBool(%v) = %v, %v", in, got, err)
+		}
+	}
+	if _, err := ToBool("maybe"); !errors.Is(err, ErrInvalid) {
+		t.Errorf("ToBool(maybe) err = %v", err)
+	}
+	durations := []struct {
+		in   interface{}
+		want time.Duration
+	}{
+		{"1m30s", 90 * time.Second},
+		{"500", 500},
+		{int64(time.Second), time.Second},
+		{time.Minute, time.Minute},
+	}
+	for _, tt := range durations {
+		if got, err := ToDuration(tt.in); err != nil || got != tt.want {
+			t.Errorf("ToDuration(%v) = %v, %v", tt.in, got, err)
+		}
+	}
+	if _, err := ToDuration("soon"); !errors.Is(err, ErrInvalid) {
+		t.Errorf("ToDuration(soon) err = %v", err)
+	}
+	strs := []struct {
+		in   interface{}
+		want string
+	}{
+		{nil, ""},
+		{12, "12"},
+		{uint64(math.MaxUint64), "18446744073709551615"},
+		{0.1, "0.1"},
+		{float32(0.1), "0.1"},
+		{[]byte("b"), "b"},
+		{errors.New("e"), "e"},
+		{time.Second, "1s"},
+		{status(2), "2"},
+	}
+	for _, tt := range strs {
+		if got, err := ToString(tt.in); err != nil || got != tt.want {
+			t.Errorf("ToString(%#v) = %q, %v", tt.in, got, err)
+		}
+	}
+	if _, err := ToString(map[string]int{}); !errors.Is(err, ErrInvalid) {
+		t.Errorf("ToString(map) err = %v", err)
+	}
+}
+
+func TestToTime(t *testing.T) {
+	want := time.Date(2021, 8, 18, 10, 30, 0, 0, time.Local)
+	for _, in := range []interface{}{"2021-08-18 10:30:00", " 2021-08-18T10:30:00 ", want.UnixNano() / 1e6, "20210818103000", &want} {
+		if got, err := ToTime(in); err != nil || !got.Equal(want) {
+			t.Errorf("ToTime(%v) = %v, %v", in, got, err)
+		}
+	}
+	if got, err := ToTime("2021-08-18T10:30:00Z"); err != nil || !got.Equal(time.Date(2021, 8, 18, 10, 30, 0, 0, time.UTC)) {
+		t.Errorf("ToTime(RFC3339) = %v, %v", got, err)
+	}
+	if _, err := ToTime("yesterday"); !errors.Is(err, ErrInvalid) {
+		t.Errorf("ToTime(yesterday) err = %v", err)
+	}
+	if got := ToTimeOr("", want); !got.Equal(want) {
+		t.Errorf("ToTimeOr = %v", got)
+	}
+}
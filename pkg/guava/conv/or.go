@@ -0,0 +1,115 @@
+package conv
+
+import "time"
+
+func ToIntOr(v interface{}, def int) int {
+	if n, err := ToInt(v); err == nil {
+		return n
+	}
+	return def
+}
+
+func ToInt8Or(v interface{}, def int8) int8 {
+	if n, err := ToInt8(v); err == nil {
+		return n
+	}
+	return def
+}
+
+func ToInt16Or(v interface{}, def int16) int16 {
+	if n, err := ToInt16(v); err == nil {
+		return n
+	}
+	return def
+}
+
+func ToInt32Or(v interface{}, def int32) int32 {
+	if n, err := ToInt32(v); err == nil {
+		return n
+	}
+	return def
+}
+
+func ToInt64Or(v interface{}, def int64) int64 {
+	if n, err := ToInt64(v); err == nil {
+		return n
+	}
+	return def
+}
+
+func ToUintOr(v interface{}, def uint) uint {
+	if n, err := ToUint(v); err == nil {
+		return n
+	}
+	return def
+}
+
+func ToUint8Or(v interface{}, def uint8) uint8 {
+	if n, err := ToUint8(v); err == nil {
+		return n
+	}
+	return def
+}
+
+func ToUint16Or(v interface{}, def uint16) uint16 {
+	if n, err := ToUint16(v); err == nil {
+		return n
+	}
+	return def
+}
+
+func ToUint32Or(v interface{}, def uint32) uint32 {
+	if n, err := ToUint32(v); err == nil {
+		return n
+	}
+	return def
+}
+
+func ToUint64Or(v interface{}, def uint64) uint64 {
+	if n, err := ToUint64(v); err == nil {
+		return n
+	}
+	return def
+}
+
+func ToFloat32Or(v interface{}, def float32) float32 {
+	if f, err := ToFloat32(v); err == nil {
+		return f
+	}
+	return def
+}
+
+func ToFloat64Or(v interface{}, def float64) float64 {
+	if f, err := ToFloat64(v); err == nil {
+		return f
+	}
+	return def
+}
+
+func ToBoolOr(v interface{}, def bool) bool {
+	if b, err := ToBool(v); err == nil {
+		return b
+	}
+	return def
+}
+
+func ToDurationOr(v interface{}, def time.Duration) time.Duration {
+	if d, err := ToDuration(v); err == nil {
+		return d
+	}
+	return def
+}
+
+func ToTimeOr(v interface{}, def time.Time) time.Time {
+	if t, err := ToTime(v); err == nil {
+		return t
+	}
+	return def
+}
+
+func ToStringOr(v interface{}, def string) string {
+	if s, err := ToString(v); err == nil {
+		return s
+	}
+	return def
+}
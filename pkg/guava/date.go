@@ -0,0 +1,460 @@
+package guava
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Styles 预定义的全部格式，ParseAny 按此顺序尝试，较具体的格式在前
+var Styles = []DateStyle{
+	YYYY_MM_DD_HH_MM_SS_SSS, YYYY_MM_DD_HH_MM_SS, YYYY_MM_DD_HH_MM, YYYY_MM_DD,
+	YYYY_MM_DD_HH_MM_SS_SSS_EN, YYYY_MM_DD_HH_MM_SS_EN, YYYY_MM_DD_HH_MM_EN, YYYY_MM_DD_EN,
+	YYYY_MM_DD_HH_MM_SS_CN, YYYY_MM_DD_HH_MM_CN, YYYY_MM_DD_CN,
+	YYYYMMDDHHMMSS, YYYYMMDDHHMM, YYYYMMDDHH, YYYYMMDD, YYMMDDHHMM,
+	YYYY_MM, YYYY_MM_EN, YYYY_MM_CN, YYYYMM,
+	MM_DD_HH_MM_SS, MM_DD_HH_MM, MM_DD,
+	MM_DD_HH_MM_SS_EN, MM_DD_HH_MM_EN, MM_DD_EN,
+	MM_DD_HH_MM_SS_CN, MM_DD_HH_MM_CN, MM_DD_CN,
+	HH_MM_SS_MS, HH_MM_SS, HH_MM,
+}
+
+// 支持的格式字母，含义与 Java SimpleDateFormat 相同：
+// y 年、M 月、d 日、H 时(0-23)、h 时(1-12)、m 分、s 秒、S 毫秒等小数秒、
+// E 星期、a 上下午、z 时区缩写、Z 时区偏移 -0700、X ISO 8601 时区 Z/-07/-0700/-07:00；
+// 单引号内为字面量，两个单引号表示单引号本身
+const letters = "yMdHhmsSEazZX"
+
+// token 格式中的一个字段，field 为 0 时是字面量 text
+type token struct {
+	field rune
+	count int
+	text  string
+}
+
+var tokenCache sync.Map
+
+func tokenize(style DateStyle) ([]token, error) {
+	if v, ok := tokenCache.Load(style); ok {
+		return v.([]token), nil
+	}
+	s := []rune(string(style))
+	var tokens []token
+	var lit []rune
+	flush := func() {
+		if len(lit) > 0 {
+			tokens = append(tokens, token{text: string(lit)})
+			lit = nil
+		}
+	}
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == '\'':
+			if i+1 < len(s) && s[i+1] == '\'' {
+				lit = append(lit, '\'')
+				i += 2
+				continue
+			}
+			j := i + 1
+			for ; j < len(s); j++ {
+				if s[j] != '\'' {
+					lit = append(lit, s[j])
+				} else if j+1 < len(s) && s[j+1] == '\'' {
+					lit = append(lit, '\'')
+					j++
+				} else {
+					break
+				}
+			}
+			if j >= len(s) {
+				return nil, fmt.Errorf("unterminated quote in date style %q", style)
+			}
+			i = j + 1
+		case c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+			if !strings.ContainsRune(letters, c) {
+				return nil, fmt.Errorf("unsupported letter %q in date style %q", c, style)
+			}
+			flush()
+			j := i
+			for j < len(s) && s[j] == c {
+				j++
+			}
+			tokens = append(tokens, token{field: c, count: j - i})
+			i = j
+		default:
+			lit = append(lit, c)
+			i++
+		}
+	}
+	flush()
+	tokenCache.Store(style, tokens)
+	return tokens, nil
+}
+
+// Layout 将 style 转换为 Go 的 layout；字面量中含数字或 Jan、Mon、MST、PM 等 Go 保留字，
+// 或小数秒前不是 . 或 , 时无法用 layout 表示，返回错误
+func Layout(style DateStyle) (string, error) {
+	tokens, err := tokenize(style)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	for _, t := range tokens {
+		switch t.field {
+		case 0:
+			if strings.ContainsAny(t.text, "0123456789") || containsAny(t.text, "Jan", "Mon", "MST", "PM", "pm") {
+				return b.String(), fmt.Errorf("literal %q in date style %q can not be expressed as go layout", t.text, style)
+			}
+			b.WriteString(t.text)
+		case 'y':
+			b.WriteString(pick(t.count == 2, "06", "2006"))
+		case 'M':
+			b.WriteString(byCount(t.count, "1", "01", "Jan", "January"))
+		case 'd':
+			b.WriteString(byCount(t.count, "2", "02"))
+		case 'H':
+			b.WriteString("15")
+		case 'h':
+			b.WriteString(byCount(t.count, "3", "03"))
+		case 'm':
+			b.WriteString(byCount(t.count, "4", "04"))
+		case 's':
+			b.WriteString(byCount(t.count, "5", "05"))
+		case 'S':
+			if l := b.String(); !strings.HasSuffix(l, ".") && !strings.HasSuffix(l, ",") {
+				return l, fmt.Errorf("fraction in date style %q must follow . or ,", style)
+			}
+			if t.count > 9 {
+				return b.String(), fmt.Errorf("fraction in date style %q exceeds 9 digits", style)
+			}
+			b.WriteString(strings.Repeat("0", t.count))
+		case 'E':
+			b.WriteString(pick(t.count >= 4, "Monday", "Mon"))
+		case 'a':
+			b.WriteString("PM")
+		case 'z':
+			b.WriteString("MST")
+		case 'Z':
+			b.WriteString("-0700")
+		case 'X':
+			b.WriteString(byCount(t.count, "Z07", "Z0700", "Z07:00"))
+		}
+	}
+	return b.String(), nil
+}
+
+func containsAny(s string, words ...string) bool {
+	for _, w := range words {
+		if strings.Contains(s, w) {
+			return true
+		}
+	}
+	return false
+}
+
+func pick(cond bool, a, b string) string {
+	if cond {
+		return a
+	}
+	return b
+}
+
+// byCount 按字母重复次数选择，次数超过候选数量时使用最后一个
+func byCount(count int, choices ...string) string {
+	if count > len(choices) {
+		count = len(choices)
+	}
+	return choices[count-1]
+}
+
+func pad(n, width int) string {
+	s := strconv.Itoa(n)
+	if len(s) < width {
+		s = strings.Repeat("0", width-len(s)) + s
+	}
+	return s
+}
+
+func format(t time.Time, tokens []token) string {
+	var b strings.Builder
+	for _, tk := range tokens {
+		switch tk.field {
+		case 0:
+			b.WriteString(tk.text)
+		case 'y':
+			if tk.count == 2 {
+				b.WriteString(pad(t.Year()%100, 2))
+			} else {
+				b.WriteString(pad(t.Year(), tk.count))
+			}
+		case 'M':
+			switch {
+			case tk.count >= 4:
+				b.WriteString(t.Month().String())
+			case tk.count == 3:
+				b.WriteString(t.Month().String()[:3])
+			default:
+				b.WriteString(pad(int(t.Month()), tk.count))
+			}
+		case 'd':
+			b.WriteString(pad(t.Day(), tk.count))
+		case 'H':
+			b.WriteString(pad(t.Hour(), tk.count))
+		case 'h':
+			h := t.Hour() % 12
+			if h == 0 {
+				h = 12
+			}
+			b.WriteString(pad(h, tk.count))
+		case 'm':
+			b.WriteString(pad(t.Minute(), tk.count))
+		case 's':
+			b.WriteString(pad(t.Second(), tk.count))
+		case 'S':
+			frac := pad(t.Nanosecond(), 9)
+			if tk.count <= 9 {
+				b.WriteString(frac[:tk.count])
+			} else {
+				b.WriteString(frac + strings.Repeat("0", tk.count-9))
+			}
+		case 'E':
+			day := t.Weekday().String()
+			b.WriteString(pick(tk.count >= 4, day, day[:3]))
+		case 'a':
+			b.WriteString(pick(t.Hour() < 12, "AM", "PM"))
+		case 'z':
+			name, _ := t.Zone()
+			b.WriteString(name)
+		case 'Z':
+			b.WriteString(t.Format("-0700"))
+		case 'X':
+			b.WriteString(t.Format(byCount(tk.count, "Z07", "Z0700", "Z07:00")))
+		}
+	}
+	return b.String()
+}
+
+// ParseDate 按 style 解析 value，没有时区信息时使用 loc（nil 为 time.Local），
+// 含时区信息时结果转换到 loc；两位年份 69-99 为 19xx，其余为 20xx；缺少的年份为 0，月和日为 1
+func ParseDate(value string, style DateStyle, loc *time.Location) (time.Time, error) {
+	tokens, err := tokenize(style)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if loc == nil {
+		loc = time.Local
+	}
+	p := dateParser{s: value}
+	year, month, day := 0, 1, 1
+	var hour, minute, sec, nsec int
+	pm, half := false, false
+	var zone *time.Location
+	for _, tk := range tokens {
+		switch tk.field {
+		case 0:
+			if !strings.HasPrefix(p.s[p.i:], tk.text) {
+				return time.Time{}, p.errorf(style, "expect %q", tk.text)
+			}
+			p.i += len(tk.text)
+		case 'y':
+			if year, err = p.number(tk, 4); err == nil && tk.count == 2 {
+				year += pickInt(year >= 69, 1900, 2000)
+			}
+		case 'M':
+			if tk.count >= 3 {
+				var m int
+				m, err = p.name(monthNames)
+				month = m + 1
+			} else {
+				month, err = p.number(tk, 2)
+			}
+		case 'd':
+			day, err = p.number(tk, 2)
+		case 'H':
+			hour, err = p.number(tk, 2)
+		case 'h':
+			hour, err = p.number(tk, 2)
+			half = true
+		case 'm':
+			minute, err = p.number(tk, 2)
+		case 's':
+			sec, err = p.number(tk, 2)
+		case 'S':
+			start := p.i
+			var frac int
+			if frac, err = p.number(tk, 9); err == nil {
+				nsec = frac
+				for n := p.i - start; n < 9; n++ {
+					nsec *= 10
+				}
+			}
+		case 'E':
+			_, err = p.name(dayNames)
+		case 'a':
+			var v int
+			v, err = p.name([]string{"AM", "PM"})
+			pm = v == 1
+		case 'z':
+			zone, err = p.zoneName(loc)
+		case 'Z', 'X':
+			zone, err = p.offset(tk)
+		}
+		if err != nil {
+			return time.Time{}, p.errorf(style, "%v", err)
+		}
+	}
+	if p.i < len(p.s) {
+		return time.Time{}, p.errorf(style, "extra text %q", p.s[p.i:])
+	}
+	if half {
+		if hour < 1 || hour > 12 {
+			return time.Time{}, p.errorf(style, "hour %d out of range", hour)
+		}
+		hour %= 12
+	}
+	if pm {
+		hour += 12
+	}
+	switch {
+	case month < 1 || month > 12:
+		return time.Time{}, p.errorf(style, "month %d out of range", month)
+	case hour > 23 || minute > 59 || sec > 59:
+		return time.Time{}, p.errorf(style, "time %02d:%02d:%02d out of range", hour, minute, sec)
+	}
+	in := loc
+	if zone != nil {
+		in = zone
+	}
+	t := time.Date(year, time.Month(month), day, hour, minute, sec, nsec, in)
+	if t.Day() != day {
+		return time.Time{}, p.errorf(style, "day %d out of range", day)
+	}
+	return t.In(loc), nil
+}
+
+func pickInt(cond bool, a, b int) int {
+	if cond {
+		return a
+	}
+	return b
+}
+
+var (
+	monthNames = []string{"January", "February", "March", "April", "May", "June",
+		"July", "August", "September", "October", "November", "December"}
+	dayNames = []string{"Sunday", "Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday"}
+)
+
+type dateParser struct {
+	s string
+	i int
+}
+
+func (p *dateParser) errorf(style DateStyle, format string, args ...interface{}) error {
+	return fmt.Errorf("parse %q as %q: %s", p.s, style, fmt.Sprintf(format, args...))
+}
+
+// number 读取数字字段：字母重复两次及以上时读取固定位数，因此 yyMMddHHmm 这类无分隔的格式可以解析；
+// 单个字母时读取 1 到 max 位
+func (p *dateParser) number(tk token, max int) (int, error) {
+	least, most := 1, max
+	if tk.count >= 2 {
+		least, most = tk.count, tk.count
+	}
+	j := p.i
+	for j < len(p.s) && j-p.i < most && p.s[j] >= '0' && p.s[j] <= '9' {
+		j++
+	}
+	if j-p.i < least {
+		return 0, fmt.Errorf("expect %d digits for %s at %d", least, strings.Repeat(string(tk.field), tk.count), p.i)
+	}
+	n, _ := strconv.Atoi(p.s[p.i:j])
+	p.i = j
+	return n, nil
+}
+
+// name 不区分大小写匹配全称或三个字母的缩写，返回下标
+func (p *dateParser) name(names []string) (int, error) {
+	rest := strings.ToLower(p.s[p.i:])
+	for i, n := range names {
+		if strings.HasPrefix(rest, strings.ToLower(n)) {
+			p.i += len(n)
+			return i, nil
+		}
+	}
+	for i, n := range names {
+		if len(n) > 3 && strings.HasPrefix(rest, strings.ToLower(n[:3])) {
+			p.i += 3
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown name at %d", p.i)
+}
+
+func (p *dateParser) zoneName(loc *time.Location) (*time.Location, error) {
+	j := p.i
+	for j < len(p.s) && (p.s[j] >= 'A' && p.s[j] <= 'Z' || p.s[j] >= 'a' && p.s[j] <= 'z') {
+		j++
+	}
+	name := p.s[p.i:j]
+	if name == "" {
+		return nil, fmt.Errorf("expect zone name at %d", p.i)
+	}
+	p.i = j
+	switch name {
+	case "UTC", "GMT", "Z":
+		return time.UTC, nil
+	}
+	if abbr, _ := time.Now().In(loc).Zone(); abbr == name {
+		return loc, nil
+	}
+	// 与 time.Parse 相同，未知的缩写视为零偏移
+	return time.FixedZone(name, 0), nil
+}
+
+// offset 解析 Z 的 -0700 或 X 的 Z、-07、-0700、-07:00
+func (p *dateParser) offset(tk token) (*time.Location, error) {
+	rest := p.s[p.i:]
+	if tk.field == 'X' && strings.HasPrefix(rest, "Z") {
+		p.i++
+		return time.UTC, nil
+	}
+	if rest == "" || rest[0] != '+' && rest[0] != '-' {
+		return nil, fmt.Errorf("expect zone offset at %d", p.i)
+	}
+	sign := pickInt(rest[0] == '-', -1, 1)
+	digits := rest[1:]
+	var hh, mm string
+	switch {
+	case tk.field == 'X' && tk.count == 1 && len(digits) >= 2:
+		hh = digits[:2]
+		p.i += 3
+	case (tk.field == 'Z' || tk.count == 2) && len(digits) >= 4:
+		hh, mm = digits[:2], digits[2:4]
+		p.i += 5
+	case tk.field == 'X' && tk.count >= 3 && len(digits) >= 5 && digits[2] == ':':
+		hh, mm = digits[:2], digits[3:5]
+		p.i += 6
+	default:
+		return nil, fmt.Errorf("invalid zone offset at %d", p.i)
+	}
+	h, err1 := strconv.Atoi(hh)
+	m, err2 := strconv.Atoi(pick(mm == "", "0", mm))
+	if err1 != nil || err2 != nil || h > 23 || m > 59 {
+		return nil, fmt.Errorf("invalid zone offset at %d", p.i)
+	}
+	return time.FixedZone("", sign*(h*3600+m*60)), nil
+}
+
+// ParseAny 依次按 Styles 解析 value，返回第一个成功的结果及其格式
+func ParseAny(value string, loc *time.Location) (time.Time, DateStyle, error) {
+	for _, style := range Styles {
+		if t, err := ParseDate(value, style, loc); err == nil {
+			return t, style, nil
+		}
+	}
+	return time.Time{}, "", fmt.Errorf("parse %q: no matching date style", value)
+}
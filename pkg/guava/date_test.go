@@ -0,0 +1,180 @@
+package guava
+
+import (
+	"testing"
+	"time"
+)
+
+var shanghai = time.FixedZone("CST", 8*3600)
+
+var ref = time.Date(2021, 3, 4, 15, 6, 7, 89000000, shanghai)
+
+func TestStylesRoundTrip(t *testing.T) {
+	for _, style := range Styles {
+		s := FormatDate(ref, style)
+		layout, err := Layout(style)
+		if err != nil {
+			t.Errorf("Layout(%q): %v", style, err)
+		} else if want := ref.Format(layout); s != want {
+			t.Errorf("FormatDate(%q) = %q, go layout gives %q", style, s, want)
+		}
+		parsed, err := ParseDate(s, style, shanghai)
+		if err != nil {
+			t.Errorf("ParseDate(%q, %q): %v", s, style, err)
+			continue
+		}
+		if again := FormatDate(parsed, style); again != s {
+			t.Errorf("round trip %q: %q != %q", style, again, s)
+		}
+	}
+}
+
+func TestFormatDate(t *testing.T) {
+	tests := []struct {
+		style DateStyle
+		want  string
+	}{
+		{YYMMDDHHMM, "2103041506"},
+		{YYYYMMDDHH, "2021030415"},
+		{"yyyy-MM-dd'T'HH:mm:ss.SSSXXX", "2021-03-04T15:06:07.089+08:00"},
+		{"yyyy-MM-dd'T'HH:mm:ssX", "2021-03-04T15:06:07+08"},
+		{"yyyyMMddHHmmssSSS", "20210304150607089"},
+		{"EEE, d MMM yyyy hh:mm a z", "Thu, 4 Mar 2021 03:06 PM CST"},
+		{"EEEE MMMM dd", "Thursday March 04"},
+		{"h:m:s.S Z", "3:6:7.0 +0800"},
+		{"'Day' d 'of' M', it''s' yy", "Day 4 of 3, it's 21"},
+		{"''HH''", "'15'"},
+		{"yyyy年M月d日 第'Q1'季度", "2021年3月4日 第Q1季度"},
+	}
+	for _, tt := range tests {
+		if got := FormatDate(ref, tt.style); got != tt.want {
+			t.Errorf("FormatDate(%q) = %q, want %q", tt.style, got, tt.want)
+		}
+	}
+}
+
+func TestParseDate(t *testing.T) {
+	utc := time.UTC
+	tests := []struct {
+		value string
+		style DateStyle
+		want  time.Time
+	}{
+		{"2103041506", YYMMDDHHMM, time.Date(2021, 3, 4, 15, 6, 0, 0, utc)},
+		{"9912310000", YYMMDDHHMM, time.Date(1999, 12, 31, 0, 0, 0, 0, utc)},
+		{"20210304", YYYYMMDD, time.Date(2021, 3, 4, 0, 0, 0, 0, utc)},
+		{"2021-3-4 5:6", "yyyy-M-d H:m", time.Date(2021, 3, 4, 5, 6, 0, 0, utc)},
+		{"2021-03-04 15:06:07.5", "yyyy-MM-dd HH:mm:ss.S", time.Date(2021, 3, 4, 15, 6, 7, 500000000, utc)},
+		{"2021-03-04 15:06:07.089", YYYY_MM_DD_HH_MM_SS_SSS, time.Date(2021, 3, 4, 15, 6, 7, 89000000, utc)},
+		{"2021年03月04日 15:06", YYYY_MM_DD_HH_MM_CN, time.Date(2021, 3, 4, 15, 6, 0, 0, utc)},
+		{"03/04", MM_DD_EN, time.Date(0, 3, 4, 0, 0, 0, 0, utc)},
+		{"15:06", HH_MM, time.Date(0, 1, 1, 15, 6, 0, 0, utc)},
+		{"thu, 4 MAR 2021 03:06 pm", "EEE, d MMM yyyy hh:mm a", time.Date(2021, 3, 4, 15, 6, 0, 0, utc)},
+		{"Thursday March 04 2021 12:00 AM", "EEEE MMMM dd yyyy hh:mm a", time.Date(2021, 3, 4, 0, 0, 0, 0, utc)},
+		{"2021-03-04T15:06:07+08:00", "yyyy-MM-dd'T'HH:mm:ssXXX", time.Date(2021, 3, 4, 7, 6, 7, 0, utc)},
+		{"2021-03-04T15:06:07Z", "yyyy-MM-dd'T'HH:mm:ssXXX", time.Date(2021, 3, 4, 15, 6, 7, 0, utc)},
+		{"2021-03-04 15:06 -0130", "yyyy-MM-dd HH:mm Z", time.Date(2021, 3, 4, 16, 36, 0, 0, utc)},
+		{"2021-03-04 15:06 +08", "yyyy-MM-dd HH:mm X", time.Date(2021, 3, 4, 7, 6, 0, 0, utc)},
+		{"2021-03-04 15:06 GMT", "yyyy-MM-dd HH:mm z", time.Date(2021, 3, 4, 15, 6, 0, 0, utc)},
+		{"Day 4 of 3, it's 21", "'Day' d 'of' M', it''s' yy", time.Date(2021, 3, 4, 0, 0, 0, 0, utc)},
+		{"2020-02-29", YYYY_MM_DD, time.Date(2020, 2, 29, 0, 0, 0, 0, utc)},
+	}
+	for _, tt := range tests {
+		got, err := ParseDate(tt.value, tt.style, utc)
+		if err != nil || !got.Equal(tt.want) {
+			t.Errorf("ParseDate(%q, %q) = %v, %v; want %v", tt.value, tt.style, got, err, tt.want)
+		}
+	}
+	if got, _ := ParseDate("2021-03-04", YYYY_MM_DD, shanghai); got.Location() != shanghai {
+		t.Errorf("location = %v", got.Location())
+	}
+	if got, _ := ParseDate("2021-03-04", YYYY_MM_DD, nil); got.Location() != time.Local {
+		t.Errorf("nil location = %v", got.Location())
+	}
+}
+
+func TestParseDateErrors(t *testing.T) {
+	tests := []struct {
+		value string
+		style DateStyle
+	}{
+		{"2021-13-01", YYYY_MM_DD},
+		{"2021-02-29", YYYY_MM_DD},
+		{"2021-04-31", YYYY_MM_DD},
+		{"2021-03-04 24:00", YYYY_MM_DD_HH_MM},
+		{"2021-03-04 23:60", YYYY_MM_DD_HH_MM},
+		{"2021-03-04 23:59:60", YYYY_MM_DD_HH_MM_SS},
+		{"2021-03-04x", YYYY_MM_DD},
+		{"2021/03/04", YYYY_MM_DD},
+		{"21-03-04", YYYY_MM_DD},
+		{"2021-3-04", YYYY_MM_DD},
+		{"", YYYY_MM_DD},
+		{"13:00 PM", "hh:mm a"},
+		{"00:00 AM", "hh:mm a"},
+		{"2021 Foo", "yyyy MMM"},
+		{"2021-03-04 +8", "yyyy-MM-dd Z"},
+		{"2021-03-04 +25:00", "yyyy-MM-dd XXX"},
+		{"2021-03-04", "yyyy-MM-dd 'T"},
+		{"2021-03-04", "yyyy-MM-dd G"},
+	}
+	for _, tt := range tests {
+		if got, err := ParseDate(tt.value, tt.style, time.UTC); err == nil {
+			t.Errorf("ParseDate(%q, %q) = %v, want error", tt.value, tt.style, got)
+		}
+	}
+}
+
+func TestParseAny(t *testing.T) {
+	tests := []struct {
+		value string
+		style DateStyle
+	}{
+		{"2021-03-04 15:06:07.089", YYYY_MM_DD_HH_MM_SS_SSS},
+		{"2021-03-04 15:06:07", YYYY_MM_DD_HH_MM_SS},
+		{"2021-03-04", YYYY_MM_DD},
+		{"2021/03/04 15:06", YYYY_MM_DD_HH_MM_EN},
+		{"2021年03月04日", YYYY_MM_DD_CN},
+		{"20210304150607", YYYYMMDDHHMMSS},
+		{"202103041506", YYYYMMDDHHMM},
+		{"2021030415", YYYYMMDDHH},
+		{"20210304", YYYYMMDD},
+		{"2021-03", YYYY_MM},
+		{"202103", YYYYMM},
+		{"03-04 15:06", MM_DD_HH_MM},
+		{"03月04日", MM_DD_CN},
+		{"15:06:07.089", HH_MM_SS_MS},
+	}
+	for _, tt := range tests {
+		_, style, err := ParseAny(tt.value, time.UTC)
+		if err != nil || style != tt.style {
+			t.Errorf("ParseAny(%q) = %q, %v; want %q", tt.value, style, err, tt.style)
+		}
+	}
+	if _, _, err := ParseAny("next tuesday", time.UTC); err == nil {
+		t.Error("ParseAny accepted free text")
+	}
+}
+
+func TestLayout(t *testing.T) {
+	tests := []struct {
+		style  DateStyle
+		layout string
+		ok     bool
+	}{
+		{YYMMDDHHMM, "0601021504", true},
+		{YYYY_MM_DD_HH_MM_SS_SSS, "2006-01-02 15:04:05.000", true},
+		{"yyyy-MM-dd'T'HH:mm:ssXXX", "2006-01-02T15:04:05Z07:00", true},
+		{"EEE MMM d h:mm a", "Mon Jan 2 3:04 PM", true},
+		{"yyyy 'Q1'", "2006", false},
+		{"yyyy 'Mon'", "2006", false},
+		{"HHmmssSSS", "150405", false},
+		{"yyyy-MM-dd G", "", false},
+		{"yyyy-MM-dd 'T", "", false},
+	}
+	for _, tt := range tests {
+		layout, err := Layout(tt.style)
+		if (err == nil) != tt.ok || layout != tt.layout {
+			t.Errorf("Layout(%q) = %q, %v; want %q ok=%v", tt.style, layout, err, tt.layout, tt.ok)
+		}
+	}
+}
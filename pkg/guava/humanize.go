@@ -0,0 +1,89 @@
+package guava
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+type unitName struct {
+	d      time.Duration
+	zh, en string
+}
+
+var units = []unitName{
+	{365 * 24 * time.Hour, "年", "year"},
+	{30 * 24 * time.Hour, "个月", "month"},
+	{24 * time.Hour, "天", "day"},
+	{time.Hour, "小时", "hour"},
+	{time.Minute, "分钟", "minute"},
+	{time.Second, "秒", "second"},
+}
+
+func isEn(lang string) bool {
+	return strings.HasPrefix(strings.ToLower(lang), "en")
+}
+
+// HumanizeDuration 以最大的两个单位描述时长，如 "2天3小时"、"2 days 3 hours"；
+// lang 以 en 开头时使用英文，否则使用中文
+func HumanizeDuration(d time.Duration, lang string) string {
+	return humanize(d, isEn(lang), 2)
+}
+
+// HumanizeSince 以最大的单位描述 t 相对 now 的时间，如 "3分钟前"、"in 2 days"，一分钟内为 "刚刚"/"just now"
+func HumanizeSince(t, now time.Time, lang string) string {
+	d := now.Sub(t)
+	en := isEn(lang)
+	switch {
+	case d > -time.Minute && d < time.Minute:
+		return pick(en, "just now", "刚刚")
+	case d > 0:
+		return humanize(d, en, 1) + pick(en, " ago", "前")
+	case en:
+		return "in " + humanize(d, en, 1)
+	}
+	return humanize(d, en, 1) + "后"
+}
+
+func humanize(d time.Duration, en bool, max int) string {
+	if d < 0 {
+		d = -d
+	}
+	var parts []string
+	for _, u := range units {
+		if d < u.d {
+			if len(parts) > 0 {
+				break
+			}
+			continue
+		}
+		n := int(d / u.d)
+		d -= time.Duration(n) * u.d
+		if !en {
+			parts = append(parts, strconv.Itoa(n)+u.zh)
+		} else if n == 1 {
+			parts = append(parts, "1 "+u.en)
+		} else {
+			parts = append(parts, strconv.Itoa(n)+" "+u.en+"s")
+		}
+		if len(parts) == max {
+			break
+		}
+	}
+	if len(parts) == 0 {
+		return pick(en, "0 seconds", "0秒")
+	}
+	return strings.Join(parts, pick(en, " ", ""))
+}
+
+// Age 按生日计算到 now 的周岁
+func Age(birth, now time.Time) int {
+	age := now.Year() - birth.Year()
+	if now.Month() < birth.Month() || now.Month() == birth.Month() && now.Day() < birth.Day() {
+		age--
+	}
+	if age < 0 {
+		return 0
+	}
+	return age
+}
@@ -0,0 +1,7 @@
+//go:build !jsoniter
+
+package json
+
+func defaultCodec() Codec {
+	return NewStd(Options{})
+}
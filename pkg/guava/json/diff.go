@@ -0,0 +1,98 @@
+package json
+
+import "strconv"
+
+// Diff 生成把 a 变为 b 的 JSON Patch，对象按键排序比较，数组按下标比较后在末尾增删
+func Diff(a, b []byte) (Patch, error) {
+	x, err := decode(a)
+	if err != nil {
+		return nil, err
+	}
+	y, err := decode(b)
+	if err != nil {
+		return nil, err
+	}
+	return DiffValues(x, y)
+}
+
+// DiffValues 比较任意两个值，结构体等会先转换为通用结构，[]byte 和 RawMessage 按 JSON 文档解析
+func DiffValues(a, b interface{}) (Patch, error) {
+	x, err := normalize(a)
+	if err != nil {
+		return nil, err
+	}
+	y, err := normalize(b)
+	if err != nil {
+		return nil, err
+	}
+	patch := Patch{}
+	if err = diff(&patch, Pointer{}, x, y); err != nil {
+		return nil, err
+	}
+	return patch, nil
+}
+
+func diff(patch *Patch, path Pointer, a, b interface{}) error {
+	if equal(a, b) {
+		return nil
+	}
+	switch x := a.(type) {
+	case map[string]interface{}:
+		y, ok := b.(map[string]interface{})
+		if !ok {
+			break
+		}
+		for _, k := range sortedKeys(x) {
+			if _, ok := y[k]; !ok {
+				*patch = append(*patch, Operation{Op: "remove", Path: path.Append(k).String()})
+			}
+		}
+		for _, k := range sortedKeys(y) {
+			v, ok := x[k]
+			if !ok {
+				if err := emit(patch, "add", path.Append(k), y[k]); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := diff(patch, path.Append(k), v, y[k]); err != nil {
+				return err
+			}
+		}
+		return nil
+	case []interface{}:
+		y, ok := b.([]interface{})
+		if !ok {
+			break
+		}
+		n := len(x)
+		if len(y) < n {
+			n = len(y)
+		}
+		for i := 0; i < n; i++ {
+			if err := diff(patch, path.Append(strconv.Itoa(i)), x[i], y[i]); err != nil {
+				return err
+			}
+		}
+		// 从末尾删除，保证下标不受前面操作的影响
+		for i := len(x) - 1; i >= n; i-- {
+			*patch = append(*patch, Operation{Op: "remove", Path: path.Append(strconv.Itoa(i)).String()})
+		}
+		for i := n; i < len(y); i++ {
+			if err := emit(patch, "add", path.Append("-"), y[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return emit(patch, "replace", path, b)
+}
+
+func emit(patch *Patch, op string, path Pointer, v interface{}) error {
+	o, err := NewOperation(op, path.String(), v)
+	if err != nil {
+		return err
+	}
+	*patch = append(*patch, o)
+	return nil
+}
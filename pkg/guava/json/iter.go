@@ -0,0 +1,66 @@
+package json
+
+import (
+	"bytes"
+	stdjson "encoding/json"
+	"io"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+type iterCodec struct {
+	o   Options
+	api jsoniter.API
+}
+
+// NewJsoniter 基于 jsoniter 的实现，其余行为与 encoding/json 兼容
+func NewJsoniter(o Options) Codec {
+	return iterCodec{o: o, api: jsoniter.Config{
+		EscapeHTML:             true,
+		SortMapKeys:            true,
+		ValidateJsonRawMessage: true,
+		UseNumber:              o.UseNumber,
+		DisallowUnknownFields:  o.DisallowUnknownFields,
+		CaseSensitive:          o.CaseSensitive,
+	}.Froze()}
+}
+
+func (iterCodec) Name() string {
+	return "jsoniter"
+}
+
+func (c iterCodec) Options() Options {
+	return c.o
+}
+
+func (c iterCodec) Marshal(v interface{}) ([]byte, error) {
+	return c.api.Marshal(v)
+}
+
+func (c iterCodec) MarshalIndent(v interface{}, prefix, indent string) ([]byte, error) {
+	if prefix == "" {
+		return c.api.MarshalIndent(v, prefix, indent)
+	}
+	// jsoniter 不支持 prefix
+	data, err := c.api.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err = stdjson.Indent(&buf, data, prefix, indent); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (c iterCodec) Unmarshal(data []byte, v interface{}) error {
+	return c.api.Unmarshal(data, v)
+}
+
+func (c iterCodec) NewEncoder(w io.Writer) Encoder {
+	return c.api.NewEncoder(w)
+}
+
+func (c iterCodec) NewDecoder(r io.Reader) Decoder {
+	return c.api.NewDecoder(r)
+}
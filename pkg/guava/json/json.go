@@ -1,13 +1,100 @@
+// Package json 提供可替换的 JSON 编解码，默认实现随 jsoniter 构建标签切换：
+// 使用 -tags=jsoniter 构建时与 gin 一样使用 jsoniter，否则使用 encoding/json
 package json
 
-import jsoniter "github.com/json-iterator/go"
+import (
+	stdjson "encoding/json"
+	"io"
+	"sync/atomic"
+)
 
-var json = jsoniter.ConfigCompatibleWithStandardLibrary
+type (
+	RawMessage = stdjson.RawMessage
+	Number     = stdjson.Number
+)
+
+// Codec JSON 编解码实现
+type Codec interface {
+	Name() string
+	Options() Options
+	Marshal(v interface{}) ([]byte, error)
+	MarshalIndent(v interface{}, prefix, indent string) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+	NewEncoder(w io.Writer) Encoder
+	NewDecoder(r io.Reader) Decoder
+}
+
+type Encoder interface {
+	Encode(v interface{}) error
+	SetIndent(prefix, indent string)
+	SetEscapeHTML(on bool)
+}
+
+// Decoder 已按 Codec 的 Options 设置 UseNumber 和 DisallowUnknownFields
+type Decoder interface {
+	Decode(v interface{}) error
+	More() bool
+	Buffered() io.Reader
+	UseNumber()
+	DisallowUnknownFields()
+}
+
+type Options struct {
+	UseNumber             bool // 解码到 interface{} 的数字保留为 Number
+	DisallowUnknownFields bool // 出现结构体中没有的字段时报错
+	// CaseSensitive 字段名区分大小写，默认与 encoding/json 一样忽略大小写；encoding/json 实现不支持
+	CaseSensitive bool
+}
+
+var current atomic.Value
+
+func init() {
+	current.Store(holder{defaultCodec()})
+}
+
+// holder 让不同实现的 Codec 可以存入同一个 atomic.Value
+type holder struct{ Codec }
+
+// SetCodec 替换包级函数使用的实现，应在启动时调用
+func SetCodec(c Codec) {
+	current.Store(holder{c})
+}
+
+func Current() Codec {
+	return current.Load().(holder).Codec
+}
+
+// New 按名称创建实现：std 或 jsoniter
+func New(name string, o Options) (Codec, bool) {
+	switch name {
+	case "std", "encoding/json":
+		return NewStd(o), true
+	case "jsoniter":
+		return NewJsoniter(o), true
+	}
+	return nil, false
+}
 
 func Marshal(v interface{}) ([]byte, error) {
-	return json.Marshal(v)
+	return Current().Marshal(v)
+}
+
+func MarshalIndent(v interface{}, prefix, indent string) ([]byte, error) {
+	return Current().MarshalIndent(v, prefix, indent)
 }
 
 func Unmarshal(data []byte, v interface{}) error {
-	return json.Unmarshal(data, v)
+	return Current().Unmarshal(data, v)
+}
+
+func NewEncoder(w io.Writer) Encoder {
+	return Current().NewEncoder(w)
+}
+
+func NewDecoder(r io.Reader) Decoder {
+	return Current().NewDecoder(r)
+}
+
+func Valid(data []byte) bool {
+	return stdjson.Valid(data)
 }
@@ -0,0 +1,108 @@
+package json
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+type item struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+func codecs(o Options) []Codec {
+	return []Codec{NewStd(o), NewJsoniter(o)}
+}
+
+func TestCodecCompatible(t *testing.T) {
+	for _, c := range codecs(Options{}) {
+		data, err := c.Marshal(map[string]interface{}{"b": "<x>", "a": 1})
+		if err != nil || string(data) != `{"a":1,"b":"\u003cx\u003e"}` {
+			t.Errorf("%s Marshal = %s, %v", c.Name(), data, err)
+		}
+		data, err = c.MarshalIndent(item{"a", 1}, "> ", "  ")
+		if want := "{\n>   \"name\": \"a\",\n>   \"count\": 1\n> }"; err != nil || string(data) != want {
+			t.Errorf("%s MarshalIndent = %q, %v", c.Name(), data, err)
+		}
+		var it item
+		if err = c.Unmarshal([]byte(`{"NAME":"x","count":2}`), &it); err != nil || it.Name != "x" || it.Count != 2 {
+			t.Errorf("%s case-insensitive Unmarshal = %+v, %v", c.Name(), it, err)
+		}
+		if err = c.Unmarshal([]byte(`{"name":"x"} {}`), &it); err == nil {
+			t.Errorf("%s accepted trailing data", c.Name())
+		}
+	}
+}
+
+func TestCodecOptions(t *testing.T) {
+	for _, c := range codecs(Options{UseNumber: true, DisallowUnknownFields: true}) {
+		var m map[string]interface{}
+		if err := c.Unmarshal([]byte(`{"id":12345678901234567890}`), &m); err != nil {
+			t.Fatalf("%s: %v", c.Name(), err)
+		}
+		if n, ok := m["id"].(Number); !ok || n.String() != "12345678901234567890" {
+			t.Errorf("%s UseNumber = %#v", c.Name(), m["id"])
+		}
+		var it item
+		if err := c.Unmarshal([]byte(`{"name":"x","extra":1}`), &it); err == nil {
+			t.Errorf("%s accepted unknown field", c.Name())
+		}
+		if err := c.Unmarshal([]byte(`{"name":"x"} {}`), &it); err == nil {
+			t.Errorf("%s accepted trailing data with options", c.Name())
+		}
+	}
+	c := NewJsoniter(Options{CaseSensitive: true})
+	var it item
+	if err := c.Unmarshal([]byte(`{"NAME":"x"}`), &it); err != nil || it.Name != "" {
+		t.Errorf("case-sensitive Unmarshal = %+v, %v", it, err)
+	}
+}
+
+func TestStreaming(t *testing.T) {
+	for _, c := range codecs(Options{}) {
+		var buf bytes.Buffer
+		enc := c.NewEncoder(&buf)
+		enc.SetEscapeHTML(false)
+		for i := 1; i <= 3; i++ {
+			if err := enc.Encode(item{"<a>", i}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if !strings.Contains(buf.String(), `"<a>"`) {
+			t.Errorf("%s SetEscapeHTML(false) = %s", c.Name(), buf.String())
+		}
+		dec := c.NewDecoder(&buf)
+		sum := 0
+		for dec.More() {
+			var it item
+			if err := dec.Decode(&it); err != nil {
+				t.Fatalf("%s Decode: %v", c.Name(), err)
+			}
+			sum += it.Count
+		}
+		if sum != 6 {
+			t.Errorf("%s streamed sum = %d", c.Name(), sum)
+		}
+	}
+}
+
+func TestSetCodec(t *testing.T) {
+	prev := Current()
+	defer SetCodec(prev)
+	c, ok := New("jsoniter", Options{UseNumber: true})
+	if !ok {
+		t.Fatal("jsoniter codec not found")
+	}
+	SetCodec(c)
+	var v interface{}
+	if err := Unmarshal([]byte(`1`), &v); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok = v.(Number); !ok || Current().Name() != "jsoniter" {
+		t.Errorf("package functions ignore SetCodec: %#v", v)
+	}
+	if _, ok = New("gob", Options{}); ok {
+		t.Error("unknown codec accepted")
+	}
+}
@@ -0,0 +1,82 @@
+package json
+
+import "sort"
+
+// MergePatch 按 RFC 7386 合并：对象逐键合并，null 删除键，其余值整体替换
+func MergePatch(doc, patch []byte) ([]byte, error) {
+	target, err := decode(doc)
+	if err != nil {
+		return nil, err
+	}
+	p, err := decode(patch)
+	if err != nil {
+		return nil, err
+	}
+	return Marshal(merge(target, p))
+}
+
+// MergePatchTo 对结构体等任意值执行 Merge Patch，v 必须是指针；失败时 v 保持不变
+func MergePatchTo(v interface{}, patch []byte) error {
+	return applyTo(v, func(doc []byte) ([]byte, error) { return MergePatch(doc, patch) })
+}
+
+func merge(target, patch interface{}) interface{} {
+	p, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	t, ok := target.(map[string]interface{})
+	if !ok {
+		t = map[string]interface{}{}
+	}
+	for k, v := range p {
+		if v == nil {
+			delete(t, k)
+		} else {
+			t[k] = merge(t[k], v)
+		}
+	}
+	return t
+}
+
+// CreateMergePatch 生成把 a 变为 b 的 Merge Patch；数组只能整体替换，值为 null 的键无法表示
+func CreateMergePatch(a, b []byte) ([]byte, error) {
+	x, err := decode(a)
+	if err != nil {
+		return nil, err
+	}
+	y, err := decode(b)
+	if err != nil {
+		return nil, err
+	}
+	return Marshal(mergeDiff(x, y))
+}
+
+func mergeDiff(a, b interface{}) interface{} {
+	x, okA := a.(map[string]interface{})
+	y, okB := b.(map[string]interface{})
+	if !okA || !okB {
+		return b
+	}
+	out := map[string]interface{}{}
+	for k := range x {
+		if _, ok := y[k]; !ok {
+			out[k] = nil
+		}
+	}
+	for _, k := range sortedKeys(y) {
+		if v, ok := x[k]; !ok || !equal(v, y[k]) {
+			out[k] = mergeDiff(v, y[k])
+		}
+	}
+	return out
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,292 @@
+package json
+
+import (
+	"encoding"
+	stdjson "encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Operation RFC 6902 JSON Patch 的一个操作，Op 为 add、remove、replace、move、copy 或 test
+type Operation struct {
+	Op    string     `json:"op"`
+	Path  string     `json:"path"`
+	From  string     `json:"from,omitempty"`
+	Value RawMessage `json:"value,omitempty"`
+}
+
+// Patch RFC 6902 JSON Patch，按顺序执行，任一操作失败时整个补丁不生效
+type Patch []Operation
+
+// UnmarshalJSON 保留值为 null 的 value，与缺少 value 区分
+func (op *Operation) UnmarshalJSON(data []byte) error {
+	var fields map[string]RawMessage
+	if err := stdjson.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	*op = Operation{Value: fields["value"]}
+	for key, dest := range map[string]*string{"op": &op.Op, "path": &op.Path, "from": &op.From} {
+		if raw, ok := fields[key]; ok {
+			if err := stdjson.Unmarshal(raw, dest); err != nil {
+				return fmt.Errorf("json patch %s: %w", key, err)
+			}
+		}
+	}
+	return nil
+}
+
+// NewOperation 创建带值的操作，value 会被序列化
+func NewOperation(op, path string, value interface{}) (Operation, error) {
+	raw, err := Marshal(value)
+	if err != nil {
+		return Operation{}, err
+	}
+	return Operation{Op: op, Path: path, Value: raw}, nil
+}
+
+// DecodePatch 解析 application/json-patch+json 请求体
+func DecodePatch(data []byte) (Patch, error) {
+	var p Patch
+	if err := Unmarshal(data, &p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Apply 对文档执行补丁，返回新文档
+func (p Patch) Apply(doc []byte) ([]byte, error) {
+	v, err := decode(doc)
+	if err != nil {
+		return nil, err
+	}
+	if v, err = p.ApplyValue(v); err != nil {
+		return nil, err
+	}
+	return Marshal(v)
+}
+
+// ApplyTo 对结构体等任意值执行补丁，v 必须是指针；失败时 v 保持不变
+func (p Patch) ApplyTo(v interface{}) error {
+	return applyTo(v, func(doc []byte) ([]byte, error) { return p.Apply(doc) })
+}
+
+// ApplyValue 对解码后的通用结构执行补丁，doc 可能被修改
+func (p Patch) ApplyValue(doc interface{}) (interface{}, error) {
+	for i, op := range p {
+		var err error
+		if doc, err = op.apply(doc); err != nil {
+			return nil, fmt.Errorf("json patch operation %d (%s %s): %w", i, op.Op, op.Path, err)
+		}
+	}
+	return doc, nil
+}
+
+func (op Operation) value() (interface{}, error) {
+	if len(op.Value) == 0 {
+		return nil, fmt.Errorf("missing value")
+	}
+	return decode(op.Value)
+}
+
+func (op Operation) apply(doc interface{}) (interface{}, error) {
+	path, err := ParsePointer(op.Path)
+	if err != nil {
+		return nil, err
+	}
+	switch op.Op {
+	case "add", "replace", "test":
+		v, err := op.value()
+		if err != nil {
+			return nil, err
+		}
+		switch op.Op {
+		case "add":
+			return add(doc, path, v)
+		case "replace":
+			return replace(doc, path, v)
+		}
+		cur, err := path.Get(doc)
+		if err != nil {
+			return nil, err
+		}
+		if !Equal(cur, v) {
+			return nil, fmt.Errorf("test failed")
+		}
+		return doc, nil
+	case "remove":
+		if len(path) == 0 {
+			return nil, fmt.Errorf("cannot remove the whole document")
+		}
+		return path.update(doc, remove)
+	case "move", "copy":
+		from, err := ParsePointer(op.From)
+		if err != nil {
+			return nil, err
+		}
+		v, err := from.Get(doc)
+		if err != nil {
+			return nil, err
+		}
+		if op.Op == "copy" {
+			return add(doc, path, deepCopy(v))
+		}
+		if op.From == op.Path {
+			return doc, nil
+		}
+		if strings.HasPrefix(op.Path, op.From+"/") || len(from) == 0 {
+			return nil, fmt.Errorf("cannot move %s into its own child", op.From)
+		}
+		if doc, err = from.update(doc, remove); err != nil {
+			return nil, err
+		}
+		return add(doc, path, v)
+	}
+	return nil, fmt.Errorf("unknown op %q", op.Op)
+}
+
+func add(doc interface{}, path Pointer, v interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return v, nil
+	}
+	return path.update(doc, func(parent interface{}, key string) (interface{}, error) {
+		return insert(parent, key, v)
+	})
+}
+
+func replace(doc interface{}, path Pointer, v interface{}) (interface{}, error) {
+	if _, err := path.Get(doc); err != nil {
+		return nil, err
+	}
+	return path.Set(doc, v)
+}
+
+func deepCopy(v interface{}) interface{} {
+	switch n := v.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(n))
+		for k, e := range n {
+			m[k] = deepCopy(e)
+		}
+		return m
+	case []interface{}:
+		a := make([]interface{}, len(n))
+		for i, e := range n {
+			a[i] = deepCopy(e)
+		}
+		return a
+	}
+	return v
+}
+
+// Equal 按 JSON 语义比较两个值：数字按数值比较，对象忽略键的顺序
+func Equal(a, b interface{}) bool {
+	a, errA := normalize(a)
+	b, errB := normalize(b)
+	if errA != nil || errB != nil {
+		return false
+	}
+	return equal(a, b)
+}
+
+func equal(a, b interface{}) bool {
+	switch x := a.(type) {
+	case map[string]interface{}:
+		y, ok := b.(map[string]interface{})
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for k, v := range x {
+			w, ok := y[k]
+			if !ok || !equal(v, w) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		y, ok := b.([]interface{})
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for i := range x {
+			if !equal(x[i], y[i]) {
+				return false
+			}
+		}
+		return true
+	case Number:
+		y, ok := b.(Number)
+		if !ok {
+			return false
+		}
+		if x == y {
+			return true
+		}
+		fx, errX := x.Float64()
+		fy, errY := y.Float64()
+		return errX == nil && errY == nil && fx == fy
+	}
+	return a == b
+}
+
+// applyTo 序列化 v、执行 fn 后解码到新零值，使补丁删除的字段恢复为零值，再通过 overlay 写回 v 的副本，
+// 使 json:"-" 和未导出等不参与编解码的字段保持原值
+func applyTo(v interface{}, fn func(doc []byte) ([]byte, error)) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return fmt.Errorf("json: patch target %T is not a non-nil pointer", v)
+	}
+	doc, err := Marshal(v)
+	if err != nil {
+		return err
+	}
+	if doc, err = fn(doc); err != nil {
+		return err
+	}
+	fresh := reflect.New(rv.Elem().Type())
+	if err = Unmarshal(doc, fresh.Interface()); err != nil {
+		return err
+	}
+	result := reflect.New(rv.Elem().Type()).Elem()
+	result.Set(rv.Elem())
+	overlay(result, fresh.Elem())
+	rv.Elem().Set(result)
+	return nil
+}
+
+var (
+	marshalerType       = reflect.TypeOf((*stdjson.Marshaler)(nil)).Elem()
+	unmarshalerType     = reflect.TypeOf((*stdjson.Unmarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// overlay 将 src 中参与 JSON 编解码的字段写入 dst，结构体逐字段处理；
+// 自定义编解码的类型、切片和 map 整体替换，其元素中不参与编解码的字段不保留
+func overlay(dst, src reflect.Value) {
+	t := dst.Type()
+	switch {
+	case t.Kind() == reflect.Struct && !custom(t):
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if f.PkgPath != "" && !f.Anonymous || f.Tag.Get("json") == "-" {
+				continue
+			}
+			overlay(dst.Field(i), src.Field(i))
+		}
+		return
+	case t.Kind() == reflect.Ptr && t.Elem().Kind() == reflect.Struct && !custom(t.Elem()) && !dst.IsNil() && !src.IsNil():
+		// 复制一份再写入，不修改 dst 原来指向的值
+		p := reflect.New(t.Elem())
+		p.Elem().Set(dst.Elem())
+		overlay(p.Elem(), src.Elem())
+		src = p
+	}
+	if dst.CanSet() {
+		dst.Set(src)
+	}
+}
+
+func custom(t reflect.Type) bool {
+	p := reflect.PtrTo(t)
+	return t.Implements(marshalerType) || p.Implements(unmarshalerType) || p.Implements(textUnmarshalerType)
+}
@@ -0,0 +1,311 @@
+package json
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func jsonEqual(t *testing.T, got []byte, want string) bool {
+	t.Helper()
+	a, err := decode(got)
+	if err != nil {
+		t.Fatalf("invalid output %s: %v", got, err)
+	}
+	b, err := decode([]byte(want))
+	if err != nil {
+		t.Fatalf("invalid want %s: %v", want, err)
+	}
+	return Equal(a, b)
+}
+
+func TestPointer(t *testing.T) {
+	doc := []byte(`{"foo":["bar","baz"],"":0,"a/b":1,"m~n":8,"k\"l":6}`)
+	tests := []struct {
+		ptr  string
+		want interface{}
+	}{
+		{"/foo/0", "bar"},
+		{"/", Number("0")},
+		{"/a~1b", Number("1")},
+		{"/m~0n", Number("8")},
+		{"/k\"l", Number("6")},
+	}
+	for _, tt := range tests {
+		if got, err := Get(doc, tt.ptr); err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Get(%q) = %#v, %v", tt.ptr, got, err)
+		}
+	}
+	for _, ptr := range []string{"/foo/2", "/foo/01", "/foo/-", "/missing/x"} {
+		if _, err := Get(doc, ptr); err == nil {
+			t.Errorf("Get(%q) succeeded", ptr)
+		}
+	}
+	if _, err := ParsePointer("foo"); err == nil {
+		t.Error("pointer without leading / accepted")
+	}
+	if p := (Pointer{"a/b", "m~n"}); p.String() != "/a~1b/m~0n" {
+		t.Errorf("String = %s", p)
+	}
+	out, err := Set([]byte(`{"a":{"b":[1,2]}}`), "/a/b/-", map[string]int{"c": 3})
+	if err != nil || !jsonEqual(t, out, `{"a":{"b":[1,2,{"c":3}]}}`) {
+		t.Errorf("Set append = %s, %v", out, err)
+	}
+	if out, err = Set([]byte(`{"a":[1,2]}`), "/a/0", "x"); err != nil || !jsonEqual(t, out, `{"a":["x",2]}`) {
+		t.Errorf("Set index = %s, %v", out, err)
+	}
+	if _, err = Set([]byte(`{}`), "/a/b", 1); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Set without parent err = %v", err)
+	}
+}
+
+// 用例取自 RFC 6902 附录 A
+func TestPatchApply(t *testing.T) {
+	tests := []struct {
+		doc, patch, want string
+	}{
+		{`{"foo":"bar"}`, `[{"op":"add","path":"/baz","value":"qux"}]`, `{"baz":"qux","foo":"bar"}`},
+		{`{"foo":["bar","baz"]}`, `[{"op":"add","path":"/foo/1","value":"qux"}]`, `{"foo":["bar","qux","baz"]}`},
+		{`{"baz":"qux","foo":"bar"}`, `[{"op":"remove","path":"/baz"}]`, `{"foo":"bar"}`},
+		{`{"foo":["bar","qux","baz"]}`, `[{"op":"remove","path":"/foo/1"}]`, `{"foo":["bar","baz"]}`},
+		{`{"baz":"qux","foo":"bar"}`, `[{"op":"replace","path":"/baz","value":"boo"}]`, `{"baz":"boo","foo":"bar"}`},
+		{`{"foo":{"bar":"baz","waldo":"fred"},"qux":{"corge":"grault"}}`,
+			`[{"op":"move","from":"/foo/waldo","path":"/qux/thud"}]`,
+			`{"foo":{"bar":"baz"},"qux":{"corge":"grault","thud":"fred"}}`},
+		{`{"foo":["all","grass","cows","eat"]}`, `[{"op":"move","from":"/foo/1","path":"/foo/3"}]`, `{"foo":["all","cows","eat","grass"]}`},
+		{`{"baz":"qux","foo":["a",2,"c"]}`,
+			`[{"op":"test","path":"/baz","value":"qux"},{"op":"test","path":"/foo/1","value":2.0}]`,
+			`{"baz":"qux","foo":["a",2,"c"]}`},
+		{`{"foo":"bar"}`, `[{"op":"add","path":"/child","value":{"grandchild":{}}}]`, `{"foo":"bar","child":{"grandchild":{}}}`},
+		{`{"foo":["bar"]}`, `[{"op":"add","path":"/foo/-","value":["abc","def"]}]`, `{"foo":["bar",["abc","def"]]}`},
+		{`{"foo":null}`, `[{"op":"copy","from":"/foo","path":"/bar"}]`, `{"foo":null,"bar":null}`},
+		{`{"foo":1}`, `[{"op":"add","path":"/foo","value":null}]`, `{"foo":null}`},
+		{`{"a":1}`, `[{"op":"replace","path":"","value":[1]}]`, `[1]`},
+	}
+	for _, c := range codecs(Options{}) {
+		prev := Current()
+		SetCodec(c)
+		for _, tt := range tests {
+			p, err := DecodePatch([]byte(tt.patch))
+			if err != nil {
+				t.Fatalf("%s DecodePatch(%s): %v", c.Name(), tt.patch, err)
+			}
+			out, err := p.Apply([]byte(tt.doc))
+			if err != nil || !jsonEqual(t, out, tt.want) {
+				t.Errorf("%s Apply(%s, %s) = %s, %v; want %s", c.Name(), tt.doc, tt.patch, out, err, tt.want)
+			}
+		}
+		SetCodec(prev)
+	}
+}
+
+func TestPatchErrors(t *testing.T) {
+	tests := []struct {
+		doc, patch string
+	}{
+		{`{"baz":"qux"}`, `[{"op":"test","path":"/baz","value":"bar"}]`},
+		{`{"foo":"bar"}`, `[{"op":"add","path":"/baz/bat","value":"qux"}]`},
+		{`{"foo":"bar"}`, `[{"op":"replace","path":"/baz","value":1}]`},
+		{`{"foo":"bar"}`, `[{"op":"remove","path":"/baz"}]`},
+		{`{"foo":"bar"}`, `[{"op":"add","path":"/baz"}]`},
+		{`{"foo":{"a":1}}`, `[{"op":"move","from":"/foo","path":"/foo/a/b"}]`},
+		{`{"foo":[1]}`, `[{"op":"add","path":"/foo/2","value":1}]`},
+		{`{"foo":1}`, `[{"op":"jump","path":"/foo"}]`},
+	}
+	for _, tt := range tests {
+		p, err := DecodePatch([]byte(tt.patch))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if out, err := p.Apply([]byte(tt.doc)); err == nil {
+			t.Errorf("Apply(%s, %s) = %s, want error", tt.doc, tt.patch, out)
+		}
+	}
+}
+
+type station struct {
+	Name    string   `json:"name"`
+	Voltage int      `json:"voltage"`
+	Tags    []string `json:"tags,omitempty"`
+}
+
+func TestPatchStruct(t *testing.T) {
+	s := station{Name: "A", Voltage: 110, Tags: []string{"x"}}
+	p := Patch{
+		{Op: "replace", Path: "/voltage", Value: RawMessage(`220`)},
+		{Op: "remove", Path: "/tags"},
+	}
+	if err := p.ApplyTo(&s); err != nil {
+		t.Fatal(err)
+	}
+	if s.Voltage != 220 || s.Tags != nil || s.Name != "A" {
+		t.Errorf("ApplyTo = %+v", s)
+	}
+	bad := Patch{{Op: "replace", Path: "/voltage", Value: RawMessage(`"high"`)}}
+	if err := bad.ApplyTo(&s); err == nil || s.Voltage != 220 {
+		t.Errorf("failed patch changed target: %+v, %v", s, err)
+	}
+	if err := MergePatchTo(&s, []byte(`{"name":"B","tags":["y"]}`)); err != nil || s.Name != "B" || s.Voltage != 220 || s.Tags[0] != "y" {
+		t.Errorf("MergePatchTo = %+v, %v", s, err)
+	}
+}
+
+type audited struct {
+	Id      int64     `json:"id"`
+	Deleted time.Time `json:"-"`
+}
+
+type account struct {
+	audited
+	Name     string   `json:"name"`
+	Password string   `json:"-"`
+	Owner    *account `json:"owner,omitempty"`
+	secret   string
+}
+
+func TestPatchKeepsHiddenFields(t *testing.T) {
+	deleted := time.Date(2021, 3, 4, 0, 0, 0, 0, time.UTC)
+	owner := &account{Name: "root", Password: "root-hash"}
+	a := account{audited: audited{Id: 1, Deleted: deleted}, Name: "a", Password: "hash", Owner: owner, secret: "s"}
+	if err := MergePatchTo(&a, []byte(`{"name":"b","owner":{"name":"admin"}}`)); err != nil {
+		t.Fatal(err)
+	}
+	if a.Name != "b" || a.Password != "hash" || a.secret != "s" || !a.Deleted.Equal(deleted) || a.Id != 1 {
+		t.Errorf("MergePatchTo = %+v", a)
+	}
+	if a.Owner.Name != "admin" || a.Owner.Password != "root-hash" || owner.Name != "root" {
+		t.Errorf("owner = %+v, original owner = %+v", a.Owner, owner)
+	}
+	p := Patch{{Op: "remove", Path: "/owner"}, {Op: "replace", Path: "/id", Value: RawMessage(`2`)}}
+	if err := p.ApplyTo(&a); err != nil {
+		t.Fatal(err)
+	}
+	if a.Owner != nil || a.Id != 2 || a.Password != "hash" {
+		t.Errorf("ApplyTo = %+v", a)
+	}
+}
+
+// 用例取自 RFC 7386 附录 A
+func TestMergePatch(t *testing.T) {
+	tests := []struct {
+		doc, patch, want string
+	}{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`["a","b"]`, `["c","d"]`, `["c","d"]`},
+		{`{"a":"b"}`, `["c"]`, `["c"]`},
+		{`{"a":"foo"}`, `null`, `null`},
+		{`{"e":null}`, `{"a":1}`, `{"e":null,"a":1}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+	}
+	for _, tt := range tests {
+		out, err := MergePatch([]byte(tt.doc), []byte(tt.patch))
+		if err != nil || !jsonEqual(t, out, tt.want) {
+			t.Errorf("MergePatch(%s, %s) = %s, %v; want %s", tt.doc, tt.patch, out, err, tt.want)
+		}
+	}
+	a, b := `{"a":1,"b":{"c":2,"d":3},"e":[1]}`, `{"a":1,"b":{"c":4},"e":[1,2],"f":"x"}`
+	mp, err := CreateMergePatch([]byte(a), []byte(b))
+	if err != nil || !jsonEqual(t, mp, `{"b":{"c":4,"d":null},"e":[1,2],"f":"x"}`) {
+		t.Errorf("CreateMergePatch = %s, %v", mp, err)
+	}
+	if out, _ := MergePatch([]byte(a), mp); !jsonEqual(t, out, b) {
+		t.Errorf("merge patch round trip = %s", out)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	pairs := [][2]string{
+		{`{"a":1,"b":{"c":[1,2,3]},"d":"x"}`, `{"a":1.0,"b":{"c":[1,5]},"e":null}`},
+		{`[1,{"a":2}]`, `[1,{"a":3},4,5]`},
+		{`{"a/b":{"m~n":1}}`, `{"a/b":{"m~n":2}}`},
+		{`{"a":[1]}`, `{"a":{"0":1}}`},
+		{`"x"`, `{"x":1}`},
+	}
+	for _, p := range pairs {
+		patch, err := Diff([]byte(p[0]), []byte(p[1]))
+		if err != nil {
+			t.Fatal(err)
+		}
+		out, err := patch.Apply([]byte(p[0]))
+		if err != nil || !jsonEqual(t, out, p[1]) {
+			raw, _ := Marshal(patch)
+			t.Errorf("Diff(%s, %s) = %s applied to %s, %v", p[0], p[1], raw, out, err)
+		}
+	}
+	patch, _ := Diff([]byte(`{"a":1,"b":2}`), []byte(`{"a":1,"b":2}`))
+	if len(patch) != 0 {
+		t.Errorf("Diff of equal documents = %+v", patch)
+	}
+	patch, err := DiffValues(station{Name: "A", Voltage: 110}, station{Name: "A", Voltage: 220})
+	if err != nil || len(patch) != 1 || patch[0].Path != "/voltage" || string(patch[0].Value) != "220" {
+		t.Errorf("DiffValues = %+v, %v", patch, err)
+	}
+}
+
+func TestDiffDocuments(t *testing.T) {
+	for _, pair := range [][2]interface{}{
+		{[]byte(`{"a":1}`), []byte(`{"a":2}`)},
+		{RawMessage(`{"a":1}`), RawMessage(`{"a":2}`)},
+	} {
+		patch, err := DiffValues(pair[0], pair[1])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(patch) != 1 || patch[0].Op != "replace" || patch[0].Path != "/a" || string(patch[0].Value) != "2" {
+			t.Errorf("DiffValues(%T) = %+v", pair[0], patch)
+		}
+	}
+}
+
+func TestQuery(t *testing.T) {
+	doc := []byte(`{
+		"name": "grid",
+		"lines": [
+			{"type": "ac", "voltage": 220, "stations": [{"name": "A"}, {"name": "B"}]},
+			{"type": "dc", "voltage": 500, "stations": [{"name": "C"}]},
+			{"type": "ac", "voltage": 110, "enabled": true, "stations": []}
+		],
+		"meta": {"a.b": 1, "owner": {"name": "ops"}}
+	}`)
+	tests := []struct {
+		path string
+		want []interface{}
+	}{
+		{"$.name", []interface{}{"grid"}},
+		{"name", []interface{}{"grid"}},
+		{"lines[0].type", []interface{}{"ac"}},
+		{"$.lines[-1].voltage", []interface{}{Number("110")}},
+		{"lines[*].voltage", []interface{}{Number("220"), Number("500"), Number("110")}},
+		{"lines[type=ac].stations[*].name", []interface{}{"A", "B"}},
+		{"lines[voltage=500].stations.*.name", []interface{}{"C"}},
+		{"lines[enabled=true].voltage", []interface{}{Number("110")}},
+		{`lines[type="dc"].voltage`, []interface{}{Number("500")}},
+		{"meta['a.b']", []interface{}{Number("1")}},
+		{"$..name", []interface{}{"grid", "A", "B", "C", "ops"}},
+		{"lines[9].type", []interface{}{}},
+		{"missing.field", []interface{}{}},
+	}
+	for _, tt := range tests {
+		got, err := Query(doc, tt.path)
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Query(%q) = %#v, %v; want %#v", tt.path, got, err, tt.want)
+		}
+	}
+	for _, path := range []string{"lines[0", "lines[x]", "a..", "a.[0]"} {
+		if _, err := Query(doc, path); err == nil {
+			t.Errorf("Query(%q) accepted", path)
+		}
+	}
+	if _, err := QueryOne(doc, "lines[type=hv]"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("QueryOne without match err = %v", err)
+	}
+}
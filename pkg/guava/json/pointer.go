@@ -0,0 +1,249 @@
+package json
+
+import (
+	"bytes"
+	stdjson "encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ErrNotFound JSON Pointer 指向的值不存在
+var ErrNotFound = errors.New("json: path not found")
+
+// Pointer RFC 6901 JSON Pointer，如 /items/0/name，键中的 ~ 和 / 转义为 ~0 和 ~1
+type Pointer []string
+
+var (
+	unescaper = strings.NewReplacer("~1", "/", "~0", "~")
+	escaper   = strings.NewReplacer("~", "~0", "/", "~1")
+)
+
+// ParsePointer 解析 JSON Pointer，空字符串表示整个文档
+func ParsePointer(s string) (Pointer, error) {
+	if s == "" {
+		return Pointer{}, nil
+	}
+	if s[0] != '/' {
+		return nil, fmt.Errorf("json: pointer %q must start with /", s)
+	}
+	p := strings.Split(s[1:], "/")
+	for i := range p {
+		p[i] = unescaper.Replace(p[i])
+	}
+	return p, nil
+}
+
+func (p Pointer) String() string {
+	var b strings.Builder
+	for _, key := range p {
+		b.WriteByte('/')
+		b.WriteString(escaper.Replace(key))
+	}
+	return b.String()
+}
+
+// Append 返回追加了 key 的新 Pointer
+func (p Pointer) Append(key string) Pointer {
+	return append(p[:len(p):len(p)], key)
+}
+
+// Get 取出 doc 中 p 指向的值，doc 为解码后的 map[string]interface{}/[]interface{} 文档
+func (p Pointer) Get(doc interface{}) (interface{}, error) {
+	cur := doc
+	for i, key := range p {
+		next, err := child(cur, key)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", err, p[:i+1])
+		}
+		cur = next
+	}
+	return cur, nil
+}
+
+// Set 设置 p 指向的值，父节点必须存在；数组使用 - 表示追加，返回新的根节点
+func (p Pointer) Set(doc, value interface{}) (interface{}, error) {
+	if len(p) == 0 {
+		return value, nil
+	}
+	return p.update(doc, func(parent interface{}, key string) (interface{}, error) {
+		if a, ok := parent.([]interface{}); ok && key != "-" {
+			i, err := index(key, len(a), false)
+			if err != nil {
+				return nil, err
+			}
+			a[i] = value
+			return a, nil
+		}
+		return insert(parent, key, value)
+	})
+}
+
+// update 沿非空的 p 找到父节点调用 fn，并依次写回修改后的节点，数组长度变化时需要替换父节点中的切片
+func (p Pointer) update(doc interface{}, fn func(parent interface{}, key string) (interface{}, error)) (interface{}, error) {
+	var walk func(node interface{}, rest Pointer) (interface{}, error)
+	walk = func(node interface{}, rest Pointer) (interface{}, error) {
+		if len(rest) == 1 {
+			return fn(node, rest[0])
+		}
+		c, err := child(node, rest[0])
+		if err != nil {
+			return nil, err
+		}
+		if c, err = walk(c, rest[1:]); err != nil {
+			return nil, err
+		}
+		return setChild(node, rest[0], c), nil
+	}
+	out, err := walk(doc, p)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", err, p)
+	}
+	return out, nil
+}
+
+func child(node interface{}, key string) (interface{}, error) {
+	switch n := node.(type) {
+	case map[string]interface{}:
+		v, ok := n[key]
+		if !ok {
+			return nil, ErrNotFound
+		}
+		return v, nil
+	case []interface{}:
+		i, err := index(key, len(n), false)
+		if err != nil {
+			return nil, err
+		}
+		return n[i], nil
+	}
+	return nil, ErrNotFound
+}
+
+func setChild(node interface{}, key string, v interface{}) interface{} {
+	switch n := node.(type) {
+	case map[string]interface{}:
+		n[key] = v
+	case []interface{}:
+		i, _ := strconv.Atoi(key)
+		n[i] = v
+	}
+	return node
+}
+
+// insert 向对象添加或覆盖键，向数组的 key 位置插入，- 表示末尾
+func insert(node interface{}, key string, v interface{}) (interface{}, error) {
+	switch n := node.(type) {
+	case map[string]interface{}:
+		n[key] = v
+		return n, nil
+	case []interface{}:
+		i, err := index(key, len(n), true)
+		if err != nil {
+			return nil, err
+		}
+		n = append(n, nil)
+		copy(n[i+1:], n[i:])
+		n[i] = v
+		return n, nil
+	}
+	return nil, ErrNotFound
+}
+
+func remove(node interface{}, key string) (interface{}, error) {
+	switch n := node.(type) {
+	case map[string]interface{}:
+		if _, ok := n[key]; !ok {
+			return nil, ErrNotFound
+		}
+		delete(n, key)
+		return n, nil
+	case []interface{}:
+		i, err := index(key, len(n), false)
+		if err != nil {
+			return nil, err
+		}
+		return append(n[:i:i], n[i+1:]...), nil
+	}
+	return nil, ErrNotFound
+}
+
+// index 解析数组下标，不允许前导 0；end 为 true 时允许 - 和等于长度的下标
+func index(key string, n int, end bool) (int, error) {
+	if key == "-" && end {
+		return n, nil
+	}
+	i, err := strconv.Atoi(key)
+	if err != nil || i < 0 || key != strconv.Itoa(i) {
+		return 0, fmt.Errorf("json: invalid array index %q", key)
+	}
+	if i > n || i == n && !end {
+		return 0, ErrNotFound
+	}
+	return i, nil
+}
+
+// decode 将文档解码为通用结构，数字保留为 Number 以免丢失精度
+func decode(data []byte) (interface{}, error) {
+	dec := stdjson.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, errors.New("json: invalid character after top-level value")
+	}
+	return v, nil
+}
+
+// Get 按 JSON Pointer 取出文档中的值
+func Get(doc []byte, pointer string) (interface{}, error) {
+	p, err := ParsePointer(pointer)
+	if err != nil {
+		return nil, err
+	}
+	v, err := decode(doc)
+	if err != nil {
+		return nil, err
+	}
+	return p.Get(v)
+}
+
+// Set 按 JSON Pointer 设置文档中的值
+func Set(doc []byte, pointer string, value interface{}) ([]byte, error) {
+	p, err := ParsePointer(pointer)
+	if err != nil {
+		return nil, err
+	}
+	v, err := decode(doc)
+	if err != nil {
+		return nil, err
+	}
+	if value, err = normalize(value); err != nil {
+		return nil, err
+	}
+	if v, err = p.Set(v, value); err != nil {
+		return nil, err
+	}
+	return Marshal(v)
+}
+
+// normalize 将任意值转换为通用结构，使结构体等值可以与文档比较和合并；[]byte 和 RawMessage 视为 JSON 文档
+func normalize(v interface{}) (interface{}, error) {
+	switch d := v.(type) {
+	case nil, string, bool, Number:
+		return v, nil
+	case []byte:
+		return decode(d)
+	case RawMessage:
+		return decode(d)
+	}
+	data, err := Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return decode(data)
+}
@@ -0,0 +1,235 @@
+package json
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type stepKind int
+
+const (
+	stepKey stepKind = iota
+	stepIndex
+	stepAll
+	stepFilter
+	stepDeep
+)
+
+type step struct {
+	kind  stepKind
+	key   string
+	index int
+	value string // stepFilter 比较的值
+}
+
+// Query 按路径表达式从文档中提取值，语法：
+//
+//	$                 根，可省略
+//	.name 或 ['name']  对象字段
+//	[n]               数组下标，负数从末尾计算
+//	[*] 或 .*          全部元素或字段值
+//	[key=value]       对象数组中 key 字段等于 value 的元素，value 可加引号
+//	..name            递归查找所有名为 name 的字段
+//
+// 如 $.lines[type=ac].stations[*].name，没有匹配时返回空切片
+func Query(doc []byte, path string) ([]interface{}, error) {
+	v, err := decode(doc)
+	if err != nil {
+		return nil, err
+	}
+	return QueryValue(v, path)
+}
+
+// QueryOne 返回第一个匹配的值，没有匹配时返回 ErrNotFound
+func QueryOne(doc []byte, path string) (interface{}, error) {
+	list, err := Query(doc, path)
+	if err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, path)
+	}
+	return list[0], nil
+}
+
+// QueryValue 对解码后的通用结构执行 Query
+func QueryValue(doc interface{}, path string) ([]interface{}, error) {
+	steps, err := parseQuery(path)
+	if err != nil {
+		return nil, err
+	}
+	nodes := []interface{}{doc}
+	for _, s := range steps {
+		var next []interface{}
+		for _, n := range nodes {
+			next = s.apply(n, next)
+		}
+		nodes = next
+	}
+	if nodes == nil {
+		nodes = []interface{}{}
+	}
+	return nodes, nil
+}
+
+func (s step) apply(n interface{}, out []interface{}) []interface{} {
+	switch s.kind {
+	case stepKey:
+		if m, ok := n.(map[string]interface{}); ok {
+			if v, ok := m[s.key]; ok {
+				out = append(out, v)
+			}
+		}
+	case stepIndex:
+		if a, ok := n.([]interface{}); ok {
+			i := s.index
+			if i < 0 {
+				i += len(a)
+			}
+			if i >= 0 && i < len(a) {
+				out = append(out, a[i])
+			}
+		}
+	case stepAll:
+		switch c := n.(type) {
+		case []interface{}:
+			out = append(out, c...)
+		case map[string]interface{}:
+			for _, k := range sortedKeys(c) {
+				out = append(out, c[k])
+			}
+		}
+	case stepFilter:
+		if a, ok := n.([]interface{}); ok {
+			for _, e := range a {
+				if m, ok := e.(map[string]interface{}); ok && scalar(m[s.key]) == s.value {
+					out = append(out, e)
+				}
+			}
+		}
+	case stepDeep:
+		switch c := n.(type) {
+		case map[string]interface{}:
+			if v, ok := c[s.key]; ok {
+				out = append(out, v)
+			}
+			for _, k := range sortedKeys(c) {
+				out = s.apply(c[k], out)
+			}
+		case []interface{}:
+			for _, e := range c {
+				out = s.apply(e, out)
+			}
+		}
+	}
+	return out
+}
+
+// scalar 过滤时比较的文本形式
+func scalar(v interface{}) string {
+	switch x := v.(type) {
+	case string:
+		return x
+	case Number:
+		return x.String()
+	case bool:
+		return strconv.FormatBool(x)
+	case nil:
+		return "null"
+	}
+	return fmt.Sprint(v)
+}
+
+func parseQuery(path string) ([]step, error) {
+	var steps []step
+	s := strings.TrimPrefix(strings.TrimSpace(path), "$")
+	bad := func(reason string) error {
+		return fmt.Errorf("json: invalid query %q: %s", path, reason)
+	}
+	for first := true; s != ""; first = false {
+		switch {
+		case strings.HasPrefix(s, ".."):
+			name, rest := readName(s[2:])
+			if name == "" {
+				return nil, bad("missing name after ..")
+			}
+			steps, s = append(steps, step{kind: stepDeep, key: name}), rest
+		case s[0] == '.' || first && s[0] != '[':
+			name, rest := readName(strings.TrimPrefix(s, "."))
+			switch name {
+			case "":
+				return nil, bad("missing name")
+			case "*":
+				steps = append(steps, step{kind: stepAll})
+			default:
+				steps = append(steps, step{kind: stepKey, key: name})
+			}
+			s = rest
+		case s[0] == '[':
+			end := closing(s)
+			if end < 0 {
+				return nil, bad("unclosed [")
+			}
+			st, err := parseBracket(strings.TrimSpace(s[1:end]))
+			if err != nil {
+				return nil, bad(err.Error())
+			}
+			steps, s = append(steps, st), s[end+1:]
+		default:
+			return nil, bad("unexpected " + strconv.Quote(s[:1]))
+		}
+	}
+	return steps, nil
+}
+
+func readName(s string) (string, string) {
+	i := strings.IndexAny(s, ".[")
+	if i < 0 {
+		return s, ""
+	}
+	return s[:i], s[i:]
+}
+
+// closing 返回与 s[0] 的 [ 匹配的 ] 的位置，引号内的 ] 不计
+func closing(s string) int {
+	var quote byte
+	for i := 1; i < len(s); i++ {
+		switch c := s[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == ']':
+			return i
+		}
+	}
+	return -1
+}
+
+func parseBracket(s string) (step, error) {
+	switch {
+	case s == "*":
+		return step{kind: stepAll}, nil
+	case isQuoted(s):
+		return step{kind: stepKey, key: s[1 : len(s)-1]}, nil
+	}
+	if i := strings.Index(s, "="); i > 0 {
+		value := strings.TrimSpace(s[i+1:])
+		if isQuoted(value) {
+			value = value[1 : len(value)-1]
+		}
+		return step{kind: stepFilter, key: strings.TrimSpace(s[:i]), value: value}, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return step{}, fmt.Errorf("invalid index %q", s)
+	}
+	return step{kind: stepIndex, index: n}, nil
+}
+
+func isQuoted(s string) bool {
+	return len(s) >= 2 && (s[0] == '\'' || s[0] == '"') && s[len(s)-1] == s[0]
+}
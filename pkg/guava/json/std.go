@@ -0,0 +1,63 @@
+package json
+
+import (
+	"bytes"
+	stdjson "encoding/json"
+	"errors"
+	"io"
+)
+
+type stdCodec struct {
+	o Options
+}
+
+// NewStd 基于 encoding/json 的实现，忽略 Options.CaseSensitive
+func NewStd(o Options) Codec {
+	return stdCodec{o: o}
+}
+
+func (stdCodec) Name() string {
+	return "std"
+}
+
+func (c stdCodec) Options() Options {
+	return c.o
+}
+
+func (stdCodec) Marshal(v interface{}) ([]byte, error) {
+	return stdjson.Marshal(v)
+}
+
+func (stdCodec) MarshalIndent(v interface{}, prefix, indent string) ([]byte, error) {
+	return stdjson.MarshalIndent(v, prefix, indent)
+}
+
+func (c stdCodec) Unmarshal(data []byte, v interface{}) error {
+	if !c.o.UseNumber && !c.o.DisallowUnknownFields {
+		return stdjson.Unmarshal(data, v)
+	}
+	dec := c.NewDecoder(bytes.NewReader(data))
+	if err := dec.Decode(v); err != nil {
+		return err
+	}
+	// 与 Unmarshal 一致，拒绝顶层值之后的多余内容
+	if _, err := dec.(*stdjson.Decoder).Token(); err != io.EOF {
+		return errors.New("json: invalid character after top-level value")
+	}
+	return nil
+}
+
+func (stdCodec) NewEncoder(w io.Writer) Encoder {
+	return stdjson.NewEncoder(w)
+}
+
+func (c stdCodec) NewDecoder(r io.Reader) Decoder {
+	dec := stdjson.NewDecoder(r)
+	if c.o.UseNumber {
+		dec.UseNumber()
+	}
+	if c.o.DisallowUnknownFields {
+		dec.DisallowUnknownFields()
+	}
+	return dec
+}
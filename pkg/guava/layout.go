@@ -1,7 +1,6 @@
 package guava
 
 import (
-	"strings"
 	"time"
 )
 
@@ -45,19 +44,17 @@ const (
 	HH_MM_SS_MS = "HH:mm:ss.SSS"
 )
 
+// FormatDate 按 Java 风格的格式输出，支持的字母见 ParseDate
 func FormatDate(date time.Time, dateStyle DateStyle) string {
-	return date.Format(DateLayout(dateStyle))
+	tokens, err := tokenize(dateStyle)
+	if err != nil {
+		return date.Format(DateLayout(dateStyle))
+	}
+	return format(date, tokens)
 }
 
+// DateLayout 返回 style 对应的 Go layout，无法完整表示时返回已转换的部分，需要检查时使用 Layout
 func DateLayout(style DateStyle) string {
-	layout := string(style)
-	layout = strings.Replace(layout, "yyyy", "2006", 1)
-	layout = strings.Replace(layout, "yy", "06", 1)
-	layout = strings.Replace(layout, "MM", "01", 1)
-	layout = strings.Replace(layout, "dd", "02", 1)
-	layout = strings.Replace(layout, "HH", "15", 1)
-	layout = strings.Replace(layout, "mm", "04", 1)
-	layout = strings.Replace(layout, "ss", "05", 1)
-	layout = strings.Replace(layout, "SSS", "000", -1)
+	layout, _ := Layout(style)
 	return layout
-}
\ No newline at end of file
+}
@@ -0,0 +1,63 @@
+package guava
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// Mask 保留前 head 个和后 tail 个字符，其余替换为 *；字符数不足时全部替换
+func Mask(s string, head, tail int) string {
+	runes := []rune(s)
+	if head < 0 {
+		head = 0
+	}
+	if tail < 0 {
+		tail = 0
+	}
+	if head+tail >= len(runes) {
+		return strings.Repeat("*", len(runes))
+	}
+	return string(runes[:head]) + strings.Repeat("*", len(runes)-head-tail) + string(runes[len(runes)-tail:])
+}
+
+// MaskPhone 手机号保留前 3 位和后 4 位：138****5678
+func MaskPhone(s string) string {
+	return Mask(s, 3, 4)
+}
+
+// MaskIDCard 身份证号保留前 6 位和后 4 位
+func MaskIDCard(s string) string {
+	return Mask(s, 6, 4)
+}
+
+// MaskEmail 邮箱名保留首尾各一个字符，域名不变：z******n@example.com
+func MaskEmail(s string) string {
+	i := strings.LastIndex(s, "@")
+	if i < 0 {
+		return Mask(s, 1, 1)
+	}
+	name := s[:i]
+	if utf8.RuneCountInString(name) <= 2 {
+		return Mask(name, 1, 0) + s[i:]
+	}
+	return Mask(name, 1, 1) + s[i:]
+}
+
+// MaskName 姓名只保留第一个字：张**
+func MaskName(s string) string {
+	return Mask(s, 1, 0)
+}
+
+var (
+	emailPattern  = regexp.MustCompile(`[\w.+-]+@[\w-]+(\.[\w-]+)+`)
+	idCardPattern = regexp.MustCompile(`\b\d{17}[\dXx]\b`)
+	phonePattern  = regexp.MustCompile(`\b1[3-9]\d{9}\b`)
+)
+
+// MaskText 脱敏文本中出现的邮箱、身份证号和手机号，用于写入日志前处理
+func MaskText(s string) string {
+	s = emailPattern.ReplaceAllStringFunc(s, MaskEmail)
+	s = idCardPattern.ReplaceAllStringFunc(s, MaskIDCard)
+	return phonePattern.ReplaceAllStringFunc(s, MaskPhone)
+}
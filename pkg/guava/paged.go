@@ -1,19 +1,117 @@
 package guava
 
+import (
+	"fmt"
+	"strings"
+)
+
+var (
+	// DefaultLimit 未指定 limit 时每页的条数
+	DefaultLimit = 20
+	// MaxLimit 每页条数的上限，超出时校验失败，Lim 截断为该值
+	MaxLimit = 500
+)
+
+// Paged 分页和排序参数，可从 query 或 JSON 绑定，如 ?page=2&limit=10&sort=-created,name；
+// Start 大于 0 时按 start/limit 偏移，否则按从 1 开始的 page 计算；
+// 旧版客户端以字符串传递的 {"page":"2"} 由 bind.Bind 转换为数字
 type Paged struct {
-	Page  string `json:"page"`
-	Start string `json:"start"`
-	Limit string `json:"limit"`
+	Page  int    `json:"page"`
+	Start int    `json:"start"`
+	Limit int    `json:"limit"`
+	Sort  string `json:"sort"` // 逗号分隔的排序字段，- 前缀表示降序
+}
+
+// Sort 排序字段
+type Sort struct {
+	Field string
+	Desc  bool
 }
 
+// Pag 从 0 开始的页号，按 Start 偏移时为 Start 所在的页
 func (p Paged) Pag() int {
-	pag := ToInt(p.Page)
-	if pag > 0 {
-		return pag - 1
+	return p.Offset() / p.Lim()
+}
+
+// Lim 每页条数，未指定时为 DefaultLimit，不超过 MaxLimit
+func (p Paged) Lim() int {
+	switch {
+	case p.Limit <= 0:
+		return DefaultLimit
+	case p.Limit > MaxLimit:
+		return MaxLimit
+	}
+	return p.Limit
+}
+
+// Offset 跳过的条数
+func (p Paged) Offset() int {
+	if p.Start > 0 {
+		return p.Start
+	}
+	if p.Page > 1 {
+		return (p.Page - 1) * p.Lim()
 	}
 	return 0
 }
 
-func (p Paged) Lim() int {
-	return ToInt(p.Limit)
+// Validate 校验分页范围和排序字段，allowed 为空时不允许排序
+func (p Paged) Validate(allowed ...string) error {
+	switch {
+	case p.Page < 0:
+		return fmt.Errorf("page must not be negative")
+	case p.Start < 0:
+		return fmt.Errorf("start must not be negative")
+	case p.Limit < 0:
+		return fmt.Errorf("limit must not be negative")
+	case p.Limit > MaxLimit:
+		return fmt.Errorf("limit must not exceed %d", MaxLimit)
+	}
+	_, err := p.Sorts(allowed...)
+	return err
+}
+
+// Sorts 解析排序字段，不在 allowed 中的字段返回错误，重复的字段只保留第一次
+func (p Paged) Sorts(allowed ...string) ([]Sort, error) {
+	parts := SplitWith(p.Sort, ",", SplitOpts{Trim: true, OmitEmpty: true})
+	sorts := make([]Sort, 0, len(parts))
+	seen := map[string]bool{}
+	for _, s := range parts {
+		sort := Sort{Field: s}
+		switch {
+		case strings.HasPrefix(s, "-"):
+			sort = Sort{Field: strings.TrimSpace(s[1:]), Desc: true}
+		case strings.HasPrefix(s, "+"):
+			sort.Field = strings.TrimSpace(s[1:])
+		}
+		if !In(sort.Field, allowed...) {
+			return nil, fmt.Errorf("cannot sort by %q", sort.Field)
+		}
+		if !seen[sort.Field] {
+			seen[sort.Field] = true
+			sorts = append(sorts, sort)
+		}
+	}
+	return sorts, nil
+}
+
+// Result 生成分页响应
+func (p Paged) Result(total int64, items interface{}) PageResult {
+	limit := p.Lim()
+	return PageResult{
+		Items: items,
+		Total: total,
+		Page:  p.Pag() + 1,
+		Limit: limit,
+		Pages: int((total + int64(limit) - 1) / int64(limit)),
+	}
+}
+
+// PageResult 分页响应，Page 从 1 开始，Pages 为总页数
+type PageResult struct {
+	Items interface{} `json:"items"`
+	Total int64       `json:"total"`
+	Page  int         `json:"page"`
+	Limit int         `json:"limit"`
+	Pages int         `json:"pages"`
 }
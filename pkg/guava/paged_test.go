@@ -0,0 +1,69 @@
+package guava
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPagedOffset(t *testing.T) {
+	tests := []struct {
+		p                Paged
+		lim, offset, pag int
+	}{
+		{Paged{}, DefaultLimit, 0, 0},
+		{Paged{Page: 1, Limit: 10}, 10, 0, 0},
+		{Paged{Page: 3, Limit: 10}, 10, 20, 2},
+		{Paged{Start: 30, Limit: 10}, 10, 30, 3},
+		{Paged{Page: 5, Start: 15, Limit: 10}, 10, 15, 1},
+		{Paged{Page: 2, Limit: MaxLimit + 1}, MaxLimit, MaxLimit, 1},
+		{Paged{Page: -1, Limit: -5}, DefaultLimit, 0, 0},
+	}
+	for _, tt := range tests {
+		if lim, offset, pag := tt.p.Lim(), tt.p.Offset(), tt.p.Pag(); lim != tt.lim || offset != tt.offset || pag != tt.pag {
+			t.Errorf("%+v: lim %d offset %d pag %d, want %d %d %d", tt.p, lim, offset, pag, tt.lim, tt.offset, tt.pag)
+		}
+	}
+}
+
+func TestPagedValidateSorts(t *testing.T) {
+	allowed := []string{"name", "createdAt"}
+	invalid := []Paged{
+		{Page: -1},
+		{Start: -1},
+		{Limit: -1},
+		{Limit: MaxLimit + 1},
+		{Sort: "password"},
+		{Sort: "name"},
+	}
+	for i, p := range invalid {
+		args := allowed
+		if i == len(invalid)-1 {
+			args = nil
+		}
+		if err := p.Validate(args...); err == nil {
+			t.Errorf("%+v accepted", p)
+		}
+	}
+	sorts, err := Paged{Sort: " -createdAt, +name,, name "}.Sorts(allowed...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Sort{{Field: "createdAt", Desc: true}, {Field: "name"}}
+	if !reflect.DeepEqual(sorts, want) {
+		t.Errorf("Sorts = %+v", sorts)
+	}
+	if err = (Paged{Page: 2, Limit: MaxLimit}).Validate(); err != nil {
+		t.Errorf("valid paging rejected: %v", err)
+	}
+}
+
+func TestPagedResult(t *testing.T) {
+	items := []string{"a", "b"}
+	r := Paged{Page: 3, Limit: 10}.Result(21, items)
+	if r.Page != 3 || r.Limit != 10 || r.Pages != 3 || r.Total != 21 || !reflect.DeepEqual(r.Items, items) {
+		t.Errorf("Result = %+v", r)
+	}
+	if r = (Paged{}).Result(0, nil); r.Pages != 0 || r.Page != 1 {
+		t.Errorf("empty Result = %+v", r)
+	}
+}
@@ -1,9 +1,14 @@
 package guava
 
 import (
+	"fmt"
 	"os"
+	"reflect"
 	"strconv"
 	"strings"
+
+	"github.com/ilooky/go-layout/pkg/guava/collections"
+	"github.com/ilooky/go-layout/pkg/guava/conv"
 )
 
 func GetEnv(key string, fallVal string) string {
@@ -21,14 +26,17 @@ func GetStr(find string, fallVal string) string {
 	return fallVal
 }
 
+// Deprecated: 使用 conv.ToString
 func Int64ToStr(i int64) string {
 	return strconv.FormatInt(i, 10)
 }
 
+// Deprecated: 使用 conv.ToString
 func Uint64ToStr(i uint64) string {
 	return strconv.FormatUint(i, 10)
 }
 
+// Deprecated: 返回的是 uint64 且解析失败时 panic，使用 conv.ToInt64 或 conv.ToUint64
 func ToInt64(s string) uint64 {
 	n, err := strconv.ParseUint(s, 10, 64)
 	if err != nil {
@@ -37,6 +45,60 @@ func ToInt64(s string) uint64 {
 	return n
 }
 
+// SplitOpts SplitWith 的选项
+type SplitOpts struct {
+	Limit     int  // 最多返回的段数，最后一段包含剩余内容，小于 1 表示不限
+	Trim      bool // 去掉每段首尾的空白
+	OmitEmpty bool // 去掉空段（在 Trim 之后判断）
+}
+
+// SplitWith 按 sep 切分，空字符串返回空切片；OmitEmpty 时 Limit 只计算非空段
+func SplitWith(s, sep string, o SplitOpts) []string {
+	out := make([]string, 0)
+	if s == "" {
+		return out
+	}
+	for {
+		if o.OmitEmpty && sep != "" {
+			for strings.HasPrefix(s, sep) {
+				s = s[len(sep):]
+			}
+		}
+		part, rest, found := s, "", false
+		if o.Limit < 1 || len(out) < o.Limit-1 {
+			if i := strings.Index(s, sep); sep != "" && i >= 0 {
+				part, rest, found = s[:i], s[i+len(sep):], true
+			}
+		}
+		if o.Trim {
+			part = strings.TrimSpace(part)
+		}
+		if part != "" || !o.OmitEmpty {
+			out = append(out, part)
+		}
+		if !found {
+			return out
+		}
+		s = rest
+	}
+}
+
+// Interpolate 替换模板中的 {name} 占位符，与错误提示模板的格式相同；未提供的占位符原样保留
+func Interpolate(tpl string, vars map[string]interface{}) string {
+	if len(vars) == 0 || !strings.Contains(tpl, "{") {
+		return tpl
+	}
+	pairs := make([]string, 0, len(vars)*2)
+	for k, v := range vars {
+		s, err := conv.ToString(v)
+		if err != nil {
+			s = fmt.Sprint(v)
+		}
+		pairs = append(pairs, "{"+k+"}", s)
+	}
+	return strings.NewReplacer(pairs...).Replace(tpl)
+}
+
 func Split(s, sep string) []string {
 	if len(s) == 0 {
 		return make([]string, 0)
@@ -45,10 +107,9 @@ func Split(s, sep string) []string {
 	return strings.Split(str, sep)
 }
 
+// Deprecated: 解析失败时返回 0 无法与 "0" 区分，使用 conv.ToInt 或 conv.ToIntOr。
+// 保持原有行为，只接受 strconv.Atoi 的格式，conv.ToInt 还会接受 " 12 "、"3.0" 等
 func ToInt(v string) int {
-	if v == "" {
-		return 0
-	}
 	i, err := strconv.Atoi(v)
 	if err != nil {
 		return 0
@@ -56,11 +117,28 @@ func ToInt(v string) int {
 	return i
 }
 
-func In(val interface{}, slice ...interface{}) bool {
+// In 判断 val 是否在 slice 中，等同于 collections.Contains
+func In[T comparable](val T, slice ...T) bool {
+	return collections.Contains(slice, val)
+}
+
+// InAny 判断 val 是否在 slice 中，元素类型不同时按 interface{} 比较，用于升级前 In(val, a, b) 混合类型的调用；
+// map、切片等不可比较的值按 reflect.DeepEqual 比较，不会像 == 一样 panic
+func InAny(val interface{}, slice ...interface{}) bool {
 	for _, v := range slice {
-		if v == val {
+		if equal(v, val) {
 			return true
 		}
 	}
 	return false
 }
+
+// equal 按 == 比较，值不可比较导致 panic 时改用 reflect.DeepEqual
+func equal(a, b interface{}) (eq bool) {
+	defer func() {
+		if recover() != nil {
+			eq = reflect.DeepEqual(a, b)
+		}
+	}()
+	return a == b
+}
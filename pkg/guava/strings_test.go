@@ -0,0 +1,188 @@
+package guava
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"xorm.io/xorm/names"
+)
+
+func TestCaseConversion(t *testing.T) {
+	mapper := names.SnakeMapper{}
+	for _, s := range []string{"UserId", "UserID", "ID", "createdAt", "Base", "HTTPServer", "Name2"} {
+		if got, want := ToSnake(s), mapper.Obj2Table(s); got != want {
+			t.Errorf("ToSnake(%s) = %s, SnakeMapper = %s", s, got, want)
+		}
+		if got, want := ToCamel(mapper.Obj2Table(s)), mapper.Table2Obj(mapper.Obj2Table(s)); got != want {
+			t.Errorf("ToCamel(%s) = %s, SnakeMapper = %s", mapper.Obj2Table(s), got, want)
+		}
+	}
+	tests := []struct {
+		in, snake, kebab, camel, lower string
+	}{
+		{"user name", "user_name", "user-name", "UserName", "userName"},
+		{"order-item.id", "order_item_id", "order-item-id", "OrderItemId", "orderItemId"},
+		{"  Über  Straße ", "über_straße", "über-straße", "ÜberStraße", "überStraße"},
+		{"设备_名称", "设备_名称", "设备-名称", "设备名称", "设备名称"},
+		{"", "", "", "", ""},
+	}
+	for _, tt := range tests {
+		if got := ToSnake(tt.in); got != tt.snake {
+			t.Errorf("ToSnake(%q) = %q, want %q", tt.in, got, tt.snake)
+		}
+		if got := ToKebab(tt.in); got != tt.kebab {
+			t.Errorf("ToKebab(%q) = %q, want %q", tt.in, got, tt.kebab)
+		}
+		if got := ToCamel(tt.in); got != tt.camel {
+			t.Errorf("ToCamel(%q) = %q, want %q", tt.in, got, tt.camel)
+		}
+		if got := ToLowerCamel(tt.in); got != tt.lower {
+			t.Errorf("ToLowerCamel(%q) = %q, want %q", tt.in, got, tt.lower)
+		}
+	}
+}
+
+func TestWidthTruncate(t *testing.T) {
+	if w := Width("设备A1，"); w != 8 {
+		t.Errorf("Width = %d, want 8", w)
+	}
+	if w := Width("é"); w != 1 {
+		t.Errorf("Width with combining mark = %d", w)
+	}
+	tests := []struct {
+		in    string
+		width int
+		tail  string
+		want  string
+	}{
+		{"短文本", 10, "...", "短文本"},
+		{"变电站设备巡检报告", 10, "...", "变电站..."},
+		{"变电站设备巡检报告", 10, "…", "变电站设…"},
+		{"abcdef", 5, "..", "abc.."},
+		{"abc", 2, "...", ""},
+	}
+	for _, tt := range tests {
+		if got := Truncate(tt.in, tt.width, tt.tail); got != tt.want {
+			t.Errorf("Truncate(%q, %d) = %q, want %q", tt.in, tt.width, got, tt.want)
+		}
+	}
+	if got := PadRight("名称", 6) + "|"; got != "名称  |" {
+		t.Errorf("PadRight = %q", got)
+	}
+}
+
+func TestMask(t *testing.T) {
+	tests := []struct {
+		fn       func(string) string
+		in, want string
+	}{
+		{MaskPhone, "13812345678", "138****5678"},
+		{MaskIDCard, "11010119900307123X", "110101********123X"},
+		{MaskEmail, "zhangsan@example.com", "z******n@example.com"},
+		{MaskEmail, "ab@example.com", "a*@example.com"},
+		{MaskName, "张三丰", "张**"},
+		{MaskPhone, "123", "***"},
+	}
+	for _, tt := range tests {
+		if got := tt.fn(tt.in); got != tt.want {
+			t.Errorf("mask(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+	log := "user zhangsan@example.com phone 13812345678 id 110101199003071234 order 202108181234567890123"
+	want := "user z******n@example.com phone 138****5678 id 110101********1234 order 202108181234567890123"
+	if got := MaskText(log); got != want {
+		t.Errorf("MaskText = %q", got)
+	}
+}
+
+func TestCollator(t *testing.T) {
+	if !Equal(" Admin", "admin") || Equal("a", "b") {
+		t.Error("Equal with FoldCollator")
+	}
+	pinyin := CollatorFunc(func(s string) string {
+		return strings.NewReplacer("张", "zhang", "李", "li", "王", "wang").Replace(s)
+	})
+	list := []string{"王五", "张三", "李四"}
+	SortStrings(list, pinyin)
+	if !reflect.DeepEqual(list, []string{"李四", "王五", "张三"}) {
+		t.Errorf("SortStrings = %v", list)
+	}
+	if !ContainsText("张三", "zhang", pinyin) || Compare("李", "王", pinyin) != -1 {
+		t.Error("pinyin collator")
+	}
+}
+
+func TestSplitWithInterpolate(t *testing.T) {
+	tests := []struct {
+		in   string
+		o    SplitOpts
+		want []string
+	}{
+		{"", SplitOpts{}, []string{}},
+		{"a,b,", SplitOpts{}, []string{"a", "b", ""}},
+		{" a , b ,, c ", SplitOpts{Trim: true, OmitEmpty: true}, []string{"a", "b", "c"}},
+		{"a,b,c", SplitOpts{Limit: 2}, []string{"a", "b,c"}},
+		{",,a,,b,c", SplitOpts{Limit: 2, OmitEmpty: true}, []string{"a", "b,c"}},
+		{"k=v=w", SplitOpts{Limit: 2, Trim: true}, []string{"k", "v=w"}},
+	}
+	for _, tt := range tests {
+		sep := ","
+		if strings.Contains(tt.in, "=") {
+			sep = "="
+		}
+		if got := SplitWith(tt.in, sep, tt.o); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("SplitWith(%q, %+v) = %q, want %q", tt.in, tt.o, got, tt.want)
+		}
+	}
+	got := Interpolate("{name}的{field}不能大于{max}{unit}", map[string]interface{}{"name": "设备", "field": "温度", "max": 85.5})
+	if got != "设备的温度不能大于85.5{unit}" {
+		t.Errorf("Interpolate = %q", got)
+	}
+}
+
+func TestIn(t *testing.T) {
+	if !In("b", "a", "b") || In(3, 1, 2) {
+		t.Error("In")
+	}
+	var status interface{} = int64(1)
+	tests := []struct {
+		val   interface{}
+		slice []interface{}
+		want  bool
+	}{
+		{status, []interface{}{"1", int64(1)}, true},
+		{status, []interface{}{"1", 1}, false},
+		{nil, []interface{}{0, nil}, true},
+		{"a", nil, false},
+		{[]int{1}, []interface{}{"a", []int{1}}, true},
+		{map[string]int{"a": 1}, []interface{}{map[string]int{"a": 2}}, false},
+		{1, []interface{}{[]int{1}, 1}, true},
+		// 可比较的接口值中包含不可比较的动态值
+		{[1]interface{}{[]int{1}}, []interface{}{[1]interface{}{[]int{1}}}, true},
+	}
+	for _, tt := range tests {
+		if got := InAny(tt.val, tt.slice...); got != tt.want {
+			t.Errorf("InAny(%v, %v) = %v, want %v", tt.val, tt.slice, got, tt.want)
+		}
+	}
+}
+
+func TestToInt(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int
+	}{
+		{"12", 12},
+		{"-3", -3},
+		{"", 0},
+		{" 12 ", 0},
+		{"3.0", 0},
+		{"x", 0},
+	}
+	for _, tt := range tests {
+		if got := ToInt(tt.in); got != tt.want {
+			t.Errorf("ToInt(%q) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package guava
+
+import (
+	"strings"
+	"unicode"
+)
+
+// wide 东亚宽字符的区间，显示宽度为 2
+var wide = &unicode.RangeTable{
+	R16: []unicode.Range16{
+		{0x1100, 0x115f, 1},
+		{0x2e80, 0x303e, 1},
+		{0x3041, 0x33ff, 1},
+		{0x3400, 0x4dbf, 1},
+		{0x4e00, 0x9fff, 1},
+		{0xa000, 0xa4cf, 1},
+		{0xac00, 0xd7a3, 1},
+		{0xf900, 0xfaff, 1},
+		{0xfe30, 0xfe4f, 1},
+		{0xff00, 0xff60, 1},
+		{0xffe0, 0xffe6, 1},
+	},
+	R32: []unicode.Range32{
+		{0x1f300, 0x1f64f, 1},
+		{0x1f900, 0x1f9ff, 1},
+		{0x20000, 0x3fffd, 1},
+	},
+}
+
+// RuneWidth 字符的显示宽度：中日韩字符和全角符号为 2，组合符号和控制字符为 0
+func RuneWidth(r rune) int {
+	switch {
+	case unicode.Is(wide, r):
+		return 2
+	case unicode.In(r, unicode.Mn, unicode.Me, unicode.Cc, unicode.Cf):
+		return 0
+	}
+	return 1
+}
+
+// Width 字符串的显示宽度
+func Width(s string) int {
+	n := 0
+	for _, r := range s {
+		n += RuneWidth(r)
+	}
+	return n
+}
+
+// Truncate 按显示宽度截断，超出时保留前面的字符并追加 tail，结果宽度不超过 width（含 tail）
+func Truncate(s string, width int, tail string) string {
+	if Width(s) <= width {
+		return s
+	}
+	limit := width - Width(tail)
+	if limit < 0 {
+		return ""
+	}
+	var b strings.Builder
+	n := 0
+	for _, r := range s {
+		w := RuneWidth(r)
+		if n+w > limit {
+			break
+		}
+		n += w
+		b.WriteRune(r)
+	}
+	return b.String() + tail
+}
+
+// PadRight 用空格按显示宽度补齐，用于对齐表格和日志中的中英文混排
+func PadRight(s string, width int) string {
+	if n := Width(s); n < width {
+		return s + strings.Repeat(" ", width-n)
+	}
+	return s
+}
@@ -0,0 +1,164 @@
+package guava
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Calendar 判断某天是否为工作日
+type Calendar interface {
+	IsWorkday(t time.Time) bool
+}
+
+// WeekendCalendar 周一至周五为工作日
+type WeekendCalendar struct{}
+
+func (WeekendCalendar) IsWorkday(t time.Time) bool {
+	return t.Weekday() != time.Saturday && t.Weekday() != time.Sunday
+}
+
+// DefaultCalendar 工作日计算默认使用的日历，可替换为 LoadHolidays 加载的法定节假日日历
+var DefaultCalendar Calendar = WeekendCalendar{}
+
+// MaxWorkdayGap 查找下一个工作日时最多检查的天数，日历配置错误没有工作日时避免无限循环
+var MaxWorkdayGap = 366
+
+// HolidayCalendar 在周末规则上叠加法定节假日和调休上班日
+type HolidayCalendar struct {
+	holidays map[string]bool
+	workdays map[string]bool
+}
+
+func NewHolidayCalendar() *HolidayCalendar {
+	return &HolidayCalendar{holidays: map[string]bool{}, workdays: map[string]bool{}}
+}
+
+func (c *HolidayCalendar) IsWorkday(t time.Time) bool {
+	key := FormatDate(t, YYYY_MM_DD)
+	if c.workdays[key] {
+		return true
+	}
+	if c.holidays[key] {
+		return false
+	}
+	return WeekendCalendar{}.IsWorkday(t)
+}
+
+// AddHoliday 添加放假日期，格式为 yyyy-MM-dd 或 yyyy-MM-dd~yyyy-MM-dd
+func (c *HolidayCalendar) AddHoliday(days ...string) error {
+	return addDays(c.holidays, days)
+}
+
+// AddWorkday 添加调休上班日期，格式同 AddHoliday
+func (c *HolidayCalendar) AddWorkday(days ...string) error {
+	return addDays(c.workdays, days)
+}
+
+func addDays(set map[string]bool, days []string) error {
+	for _, d := range days {
+		from, to := d, d
+		if i := strings.Index(d, "~"); i >= 0 {
+			from, to = strings.TrimSpace(d[:i]), strings.TrimSpace(d[i+1:])
+		}
+		start, err := ParseDate(from, YYYY_MM_DD, time.UTC)
+		if err != nil {
+			return err
+		}
+		end, err := ParseDate(to, YYYY_MM_DD, time.UTC)
+		if err != nil {
+			return err
+		}
+		if end.Before(start) {
+			return fmt.Errorf("invalid date range %s", d)
+		}
+		for t := start; !t.After(end); t = t.AddDate(0, 0, 1) {
+			set[FormatDate(t, YYYY_MM_DD)] = true
+		}
+	}
+	return nil
+}
+
+// LoadHolidays 从 YAML 或 JSON 文件加载节假日日历，如：
+//
+//	holidays: ["2021-10-01~2021-10-07"]
+//	workdays: ["2021-09-26", "2021-10-09"]
+func LoadHolidays(path string) (*HolidayCalendar, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var doc struct {
+		Holidays []string
+		Workdays []string
+	}
+	if err = yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	c := NewHolidayCalendar()
+	if err = c.AddHoliday(doc.Holidays...); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if err = c.AddWorkday(doc.Workdays...); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return c, nil
+}
+
+func calendarOf(cal []Calendar) Calendar {
+	if len(cal) > 0 && cal[0] != nil {
+		return cal[0]
+	}
+	return DefaultCalendar
+}
+
+// IsWorkday 未指定日历时使用 DefaultCalendar，下同
+func IsWorkday(t time.Time, cal ...Calendar) bool {
+	return calendarOf(cal).IsWorkday(t)
+}
+
+// AddWorkdays 增加 n 个工作日，n 为负数时向前；结果保留 t 的时分秒。
+// 连续 MaxWorkdayGap 天没有工作日时停止，返回已到达的日期
+func AddWorkdays(t time.Time, n int, cal ...Calendar) time.Time {
+	c := calendarOf(cal)
+	step := 1
+	if n < 0 {
+		step, n = -1, -n
+	}
+	for gap := 0; n > 0 && gap < MaxWorkdayGap; gap++ {
+		t = t.AddDate(0, 0, step)
+		if c.IsWorkday(t) {
+			n, gap = n-1, -1
+		}
+	}
+	return t
+}
+
+// NextWorkday t 为工作日时返回 t，否则返回之后的第一个工作日；
+// 之后 MaxWorkdayGap 天内没有工作日时返回最后检查的日期
+func NextWorkday(t time.Time, cal ...Calendar) time.Time {
+	c := calendarOf(cal)
+	for gap := 0; !c.IsWorkday(t) && gap < MaxWorkdayGap; gap++ {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t
+}
+
+// Workdays 统计 [start, end) 之间的工作日天数，end 早于 start 时返回负数
+func Workdays(start, end time.Time, cal ...Calendar) int {
+	c := calendarOf(cal)
+	sign := 1
+	if end.Before(start) {
+		start, end, sign = end, start, -1
+	}
+	n := 0
+	for t := StartOf(start, PeriodDay); t.Before(StartOf(end, PeriodDay)); t = t.AddDate(0, 0, 1) {
+		if c.IsWorkday(t) {
+			n++
+		}
+	}
+	return sign * n
+}
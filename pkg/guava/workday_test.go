@@ -0,0 +1,85 @@
+package guava
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWorkdays(t *testing.T) {
+	fri := day("2021-08-20 09:30:00")
+	if got := AddWorkdays(fri, 1); !got.Equal(day("2021-08-23 09:30:00")) {
+		t.Errorf("AddWorkdays(fri, 1) = %v", got)
+	}
+	if got := AddWorkdays(day("2021-08-23 00:00:00"), -1); !got.Equal(day("2021-08-20 00:00:00")) {
+		t.Errorf("AddWorkdays(mon, -1) = %v", got)
+	}
+	if got := NextWorkday(day("2021-08-21 00:00:00")); !got.Equal(day("2021-08-23 00:00:00")) {
+		t.Errorf("NextWorkday(sat) = %v", got)
+	}
+	if n := Workdays(day("2021-08-16 00:00:00"), day("2021-08-30 00:00:00")); n != 10 {
+		t.Errorf("Workdays = %d, want 10", n)
+	}
+	if n := Workdays(day("2021-08-30 00:00:00"), day("2021-08-16 00:00:00")); n != -10 {
+		t.Errorf("reversed Workdays = %d, want -10", n)
+	}
+}
+
+func TestLoadHolidays(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "holidays.yaml")
+	data := "holidays: [\"2021-10-01~2021-10-07\", \"2021-09-20\", \"2021-09-21\"]\nworkdays: [\"2021-09-18\", \"2021-09-26\", \"2021-10-09\"]\n"
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cal, err := LoadHolidays(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		day     string
+		workday bool
+	}{
+		{"2021-10-01 00:00:00", false}, // 国庆
+		{"2021-10-06 00:00:00", false},
+		{"2021-10-09 00:00:00", true}, // 周六调休上班
+		{"2021-09-26 00:00:00", true}, // 周日调休上班
+		{"2021-09-20 00:00:00", false},
+		{"2021-10-10 00:00:00", false}, // 普通周日
+		{"2021-10-11 00:00:00", true},
+	}
+	for _, tt := range tests {
+		if got := IsWorkday(day(tt.day), cal); got != tt.workday {
+			t.Errorf("IsWorkday(%s) = %v, want %v", tt.day, got, tt.workday)
+		}
+	}
+	if got := AddWorkdays(day("2021-09-30 00:00:00"), 1, cal); !got.Equal(day("2021-10-08 00:00:00")) {
+		t.Errorf("AddWorkdays over national day = %v", got)
+	}
+	if n := Workdays(day("2021-09-27 00:00:00"), day("2021-10-11 00:00:00"), cal); n != 6 {
+		t.Errorf("Workdays over national day = %d, want 6", n)
+	}
+
+	bad := filepath.Join(t.TempDir(), "bad.yaml")
+	_ = os.WriteFile(bad, []byte("holidays: [\"2021-10-07~2021-10-01\"]\n"), 0o644)
+	if _, err = LoadHolidays(bad); err == nil {
+		t.Error("reversed range accepted")
+	}
+}
+
+type noWorkdays struct{}
+
+func (noWorkdays) IsWorkday(time.Time) bool { return false }
+
+func TestWorkdaysWithoutWorkday(t *testing.T) {
+	start := day("2021-08-20 00:00:00")
+	if got := AddWorkdays(start, 1, noWorkdays{}); !got.Equal(start.AddDate(0, 0, MaxWorkdayGap)) {
+		t.Errorf("AddWorkdays = %v", got)
+	}
+	if got := AddWorkdays(start, -2, noWorkdays{}); !got.Equal(start.AddDate(0, 0, -MaxWorkdayGap)) {
+		t.Errorf("AddWorkdays backwards = %v", got)
+	}
+	if got := NextWorkday(start, noWorkdays{}); !got.Equal(start.AddDate(0, 0, MaxWorkdayGap)) {
+		t.Errorf("NextWorkday = %v", got)
+	}
+}
@@ -0,0 +1,155 @@
+package middleware
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"runtime/debug"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/ilooky/go-layout/pkg/errno"
+	"github.com/ilooky/go-layout/pkg/guava/json"
+	"github.com/ilooky/logger"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// ErrorHandler 替代 gin.Recovery：恢复 panic，并将 c.Errors 中最后一个错误
+// 按 errno.FromError 渲染为统一的 Resp。错误详情和堆栈始终记录到日志，
+// 仅在 errno.ShowStacks 开启（非 release）时才出现在响应中。
+func ErrorHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			if r := recover(); r != nil {
+				if brokenPipe(r) {
+					logger.Warnf("%s %s: connection broken: %v", c.Request.Method, c.Request.URL.Path, r)
+					_ = c.Error(fmt.Errorf("%v", r))
+					c.Abort()
+					return
+				}
+				err := fmt.Errorf("panic: %v\n%s", r, debug.Stack())
+				_ = c.Error(err)
+				Abort(c, errno.Internal.Wrap(err))
+			}
+		}()
+		c.Next()
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+		Abort(c, c.Errors.Last().Err)
+	}
+}
+
+// Abort 终止请求并渲染 err 对应的响应
+func Abort(c *gin.Context, err error) {
+	status, resp := errno.FromError(err, Lang(c))
+	resp.WithID(GetRequestID(c)).WithStacks(fmt.Errorf("%s %s: %+v", c.Request.Method, c.Request.URL.Path, err))
+	c.Abort()
+	Render(c, status, resp)
+}
+
+// Guard 返回管理接口使用的鉴权中间件，h 为 nil 时拒绝所有请求，避免忘记配置鉴权时接口对外开放
+func Guard(h gin.HandlerFunc) gin.HandlerFunc {
+	if h != nil {
+		return h
+	}
+	return func(c *gin.Context) {
+		Abort(c, errno.Forbidden.Errorf("no guard configured"))
+	}
+}
+
+// Lang 按 Accept-Language 选择提示语言
+func Lang(c *gin.Context) string {
+	return errno.Lang(c.GetHeader("Accept-Language"))
+}
+
+// Render 按 Accept 头协商返回 JSON、XML 或 protobuf 格式的响应，默认 JSON
+func Render(c *gin.Context, status int, resp errno.Resp) {
+	switch c.NegotiateFormat(binding.MIMEJSON, binding.MIMEXML, binding.MIMEXML2, binding.MIMEPROTOBUF) {
+	case binding.MIMEXML, binding.MIMEXML2:
+		// encoding/xml 不支持 map（gin.H 除外），Content 无法编码为 XML 时改用 JSON
+		data, err := xml.Marshal(resp)
+		if err != nil {
+			c.Render(status, JSON{Data: resp})
+			return
+		}
+		c.Data(status, "application/xml; charset=utf-8", data)
+	case binding.MIMEPROTOBUF:
+		msg, err := toProto(resp)
+		if err != nil {
+			logger.Error(err)
+			c.Render(status, JSON{Data: resp})
+			return
+		}
+		c.ProtoBuf(status, msg)
+	default:
+		c.Render(status, JSON{Data: resp})
+	}
+}
+
+// toProto 将响应转换为 google.protobuf.Struct，客户端按通用结构解析
+func toProto(resp errno.Resp) (*structpb.Struct, error) {
+	raw, err := json.Marshal(resp)
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]interface{}
+	if err = json.Unmarshal(raw, &m); err != nil {
+		return nil, err
+	}
+	return toStruct(m), nil
+}
+
+func toStruct(m map[string]interface{}) *structpb.Struct {
+	s := &structpb.Struct{Fields: make(map[string]*structpb.Value, len(m))}
+	for k, v := range m {
+		s.Fields[k] = toValue(v)
+	}
+	return s
+}
+
+func toValue(v interface{}) *structpb.Value {
+	switch v := v.(type) {
+	case nil:
+		return &structpb.Value{Kind: &structpb.Value_NullValue{}}
+	case bool:
+		return &structpb.Value{Kind: &structpb.Value_BoolValue{BoolValue: v}}
+	case float64:
+		return &structpb.Value{Kind: &structpb.Value_NumberValue{NumberValue: v}}
+	case json.Number:
+		f, _ := v.Float64()
+		return &structpb.Value{Kind: &structpb.Value_NumberValue{NumberValue: f}}
+	case string:
+		return &structpb.Value{Kind: &structpb.Value_StringValue{StringValue: v}}
+	case []interface{}:
+		list := &structpb.ListValue{Values: make([]*structpb.Value, 0, len(v))}
+		for _, e := range v {
+			list.Values = append(list.Values, toValue(e))
+		}
+		return &structpb.Value{Kind: &structpb.Value_ListValue{ListValue: list}}
+	case map[string]interface{}:
+		return &structpb.Value{Kind: &structpb.Value_StructValue{StructValue: toStruct(v)}}
+	}
+	return &structpb.Value{Kind: &structpb.Value_StringValue{StringValue: fmt.Sprint(v)}}
+}
+
+// brokenPipe 客户端断开连接时无需返回响应
+func brokenPipe(r interface{}) bool {
+	err, ok := r.(error)
+	if !ok {
+		return false
+	}
+	var ne *net.OpError
+	if !errors.As(err, &ne) {
+		return false
+	}
+	var se *os.SyscallError
+	if errors.As(ne, &se) {
+		msg := strings.ToLower(se.Error())
+		return strings.Contains(msg, "broken pipe") || strings.Contains(msg, "connection reset by peer")
+	}
+	return false
+}
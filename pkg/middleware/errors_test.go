@@ -0,0 +1,122 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ilooky/go-layout/pkg/errno"
+	"github.com/ilooky/go-layout/pkg/guava/json"
+)
+
+type body struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Stacks  string `json:"stacks"`
+	ID      string `json:"id"`
+}
+
+func newTestEngine() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	h := gin.New()
+	h.Use(RequestID(), ErrorHandler())
+	h.GET("/panic", func(c *gin.Context) { panic("boom") })
+	h.GET("/missing", func(c *gin.Context) { _ = c.Error(errno.NotFound.Errorf("user 7")) })
+	h.GET("/plain", func(c *gin.Context) { _ = c.Error(errors.New("db password leaked")) })
+	h.GET("/map", func(c *gin.Context) { Render(c, http.StatusOK, errno.Ok().WithData(map[string]int{"replayed": 2})) })
+	return h
+}
+
+func serve(h http.Handler, path, accept string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, path, nil)
+	if accept != "" {
+		r.Header.Set("Accept", accept)
+	}
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func TestErrorHandler(t *testing.T) {
+	defer func() { errno.ShowStacks = true }()
+	h := newTestEngine()
+	tests := []struct {
+		path   string
+		status int
+		code   int
+	}{
+		{"/panic", http.StatusInternalServerError, errno.Internal.Code},
+		{"/missing", http.StatusNotFound, errno.NotFound.Code},
+		{"/plain", http.StatusInternalServerError, errno.Internal.Code},
+	}
+	for _, release := range []bool{false, true} {
+		errno.ShowStacks = !release
+		for _, test := range tests {
+			w := serve(h, test.path, "")
+			var b body
+			if err := json.Unmarshal(w.Body.Bytes(), &b); err != nil {
+				t.Fatalf("%s: %v", test.path, err)
+			}
+			if w.Code != test.status || b.Code != test.code {
+				t.Errorf("%s: got %d/%d, want %d/%d", test.path, w.Code, b.Code, test.status, test.code)
+			}
+			if b.ID == "" || b.ID != w.Header().Get(RequestIDHeader) {
+				t.Errorf("%s: request id %q not rendered", test.path, b.ID)
+			}
+			if release && b.Stacks != "" {
+				t.Errorf("%s: stacks leaked in release mode: %s", test.path, b.Stacks)
+			}
+			if !release && b.Stacks == "" {
+				t.Errorf("%s: stacks missing in debug mode", test.path)
+			}
+		}
+	}
+}
+
+func TestRenderNegotiation(t *testing.T) {
+	h := newTestEngine()
+	w := serve(h, "/missing", "application/xml")
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "application/xml") {
+		t.Errorf("content type = %s", ct)
+	}
+	if !strings.Contains(w.Body.String(), "<code>40400</code>") {
+		t.Errorf("unexpected xml body %s", w.Body.String())
+	}
+	w = serve(h, "/map", "application/xml")
+	if ct := w.Header().Get("Content-Type"); w.Code != http.StatusOK || !strings.HasPrefix(ct, "application/json") ||
+		!strings.Contains(w.Body.String(), `"replayed":2`) {
+		t.Errorf("map content in xml = %d %s %s, want json fallback", w.Code, ct, w.Body.String())
+	}
+	w = serve(h, "/missing", "application/x-protobuf")
+	if ct := w.Header().Get("Content-Type"); ct != "application/x-protobuf" || w.Body.Len() == 0 {
+		t.Errorf("protobuf content type = %s, len %d", ct, w.Body.Len())
+	}
+}
+
+func TestRequestID(t *testing.T) {
+	h := newTestEngine()
+	tests := []struct {
+		upstream string
+		keep     bool
+	}{
+		{"", false},
+		{"trace-01.a_b:c", true},
+		{"id\ninjected", false},
+		{"<script>", false},
+		{strings.Repeat("a", MaxRequestIDLen), true},
+		{strings.Repeat("a", MaxRequestIDLen+1), false},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/missing", nil)
+		r.Header.Set(RequestIDHeader, tt.upstream)
+		h.ServeHTTP(w, r)
+		id := w.Header().Get(RequestIDHeader)
+		if tt.keep && id != tt.upstream || !tt.keep && (id == tt.upstream || len(id) != 32) {
+			t.Errorf("upstream %q: request id = %q", tt.upstream, id)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/ilooky/go-layout/pkg/guava/json"
+)
+
+// JSON 使用 guava/json 当前的实现渲染响应，替代 c.JSON 使替换的实现和选项对响应生效
+type JSON struct {
+	Data interface{}
+}
+
+func (r JSON) Render(w http.ResponseWriter) error {
+	r.WriteContentType(w)
+	data, err := json.Marshal(r.Data)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+func (r JSON) WriteContentType(w http.ResponseWriter) {
+	if h := w.Header(); len(h["Content-Type"]) == 0 {
+		h["Content-Type"] = []string{"application/json; charset=utf-8"}
+	}
+}
+
+// Binding 使用 guava/json 当前的实现解码请求体，替代 binding.JSON：c.ShouldBindWith(&obj, middleware.Binding)
+var Binding binding.BindingBody = jsonBinding{}
+
+type jsonBinding struct{}
+
+func (jsonBinding) Name() string {
+	return "json"
+}
+
+func (jsonBinding) Bind(req *http.Request, obj interface{}) error {
+	if req == nil || req.Body == nil {
+		return errors.New("invalid request")
+	}
+	return decode(req.Body, obj)
+}
+
+func (jsonBinding) BindBody(body []byte, obj interface{}) error {
+	return decode(bytes.NewReader(body), obj)
+}
+
+func decode(r io.Reader, obj interface{}) error {
+	if err := json.NewDecoder(r).Decode(obj); err != nil {
+		return err
+	}
+	if binding.Validator == nil {
+		return nil
+	}
+	return binding.Validator.ValidateStruct(obj)
+}
+
+// BindJSON 使用 Binding 绑定请求体，替代 c.ShouldBindJSON
+func BindJSON(c *gin.Context, obj interface{}) error {
+	return c.ShouldBindWith(obj, Binding)
+}
+
+// UseCodec 切换 guava/json 的包级函数及 JSON、Binding、bind.Bind 使用的 JSON 实现；
+// gin 1.7 的 binding.JSON 和 render.JSON 无法替换，c.JSON、ShouldBindJSON 仍使用构建标签选择的实现，
+// 这里同步 UseNumber 和 DisallowUnknownFields 两个选项，完全一致需改用 Render 和 BindJSON
+func UseCodec(c json.Codec) {
+	json.SetCodec(c)
+	o := c.Options()
+	binding.EnableDecoderUseNumber = o.UseNumber
+	binding.EnableDecoderDisallowUnknownFields = o.DisallowUnknownFields
+}
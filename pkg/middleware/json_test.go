@@ -0,0 +1,98 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/ilooky/go-layout/pkg/errno"
+	"github.com/ilooky/go-layout/pkg/guava/json"
+)
+
+func TestUseCodec(t *testing.T) {
+	prev := json.Current()
+	defer UseCodec(prev)
+	UseCodec(json.NewStd(json.Options{UseNumber: true, DisallowUnknownFields: true}))
+	if !binding.EnableDecoderUseNumber || !binding.EnableDecoderDisallowUnknownFields {
+		t.Error("gin binding options not applied")
+	}
+	gin.SetMode(gin.TestMode)
+	h := gin.New()
+	h.POST("/", func(c *gin.Context) {
+		var body struct{ Id interface{} }
+		if err := c.ShouldBindJSON(&body); err != nil {
+			Abort(c, errno.Param.Wrap(err))
+			return
+		}
+		Render(c, http.StatusOK, errno.Ok().WithData(body.Id))
+	})
+	send := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body)))
+		return w
+	}
+	w := send(`{"Id":12345678901234567890}`)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"content":12345678901234567890`) {
+		t.Errorf("UseNumber round trip: %d %s", w.Code, w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/json; charset=utf-8" {
+		t.Errorf("Content-Type = %s", ct)
+	}
+	if w = send(`{"Id":1,"Other":2}`); w.Code != http.StatusBadRequest {
+		t.Errorf("unknown field: %d %s", w.Code, w.Body.String())
+	}
+}
+
+// counting 记录 NewDecoder 的调用次数
+type counting struct {
+	json.Codec
+	decoders *int
+}
+
+func (c counting) NewDecoder(r io.Reader) json.Decoder {
+	*c.decoders++
+	return c.Codec.NewDecoder(r)
+}
+
+func TestBindJSON(t *testing.T) {
+	prev := json.Current()
+	defer UseCodec(prev)
+	decoders := 0
+	UseCodec(counting{Codec: json.NewStd(json.Options{UseNumber: true}), decoders: &decoders})
+	gin.SetMode(gin.TestMode)
+	h := gin.New()
+	h.POST("/", func(c *gin.Context) {
+		var body struct {
+			Id   interface{}
+			Name string `binding:"required"`
+		}
+		if err := BindJSON(c, &body); err != nil {
+			Abort(c, errno.Param.Wrap(err))
+			return
+		}
+		Render(c, http.StatusOK, errno.Ok().WithData(body.Id))
+	})
+	tests := []struct {
+		body string
+		code int
+		want string
+	}{
+		{`{"Id":12345678901234567890,"Name":"a"}`, http.StatusOK, `"content":12345678901234567890`},
+		{`{"Id":1}`, http.StatusBadRequest, ""},
+		{`{"Id":`, http.StatusBadRequest, ""},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body)))
+		if w.Code != tt.code || !strings.Contains(w.Body.String(), tt.want) {
+			t.Errorf("%s: %d %s, want %d", tt.body, w.Code, w.Body.String(), tt.code)
+		}
+	}
+	if decoders != len(tests) {
+		t.Errorf("codec decoded %d requests, want %d", decoders, len(tests))
+	}
+}
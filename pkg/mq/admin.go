@@ -0,0 +1,166 @@
+package mq
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ilooky/go-layout/pkg/errno"
+	"github.com/ilooky/go-layout/pkg/middleware"
+)
+
+const (
+	defaultAdminLimit = 100
+	// maxAdminLimit 单次请求拉取的死信上限，避免一次把整个死信队列取出
+	maxAdminLimit = 1000
+)
+
+type DeadLetter struct {
+	ID         string                 `json:"id"`
+	Exchange   string                 `json:"exchange"`
+	RoutingKey string                 `json:"routingKey"`
+	Queue      string                 `json:"queue"`
+	Reason     string                 `json:"reason"`
+	Stack      string                 `json:"stack,omitempty"` // panic 或错误自带的堆栈
+	FailedAt   string                 `json:"failedAt"`
+	Retries    int64                  `json:"retries"`
+	Poison     bool                   `json:"poison"`
+	Headers    map[string]interface{} `json:"headers"`
+	Body       string                 `json:"body"`
+}
+
+// RegisterAdmin 注册死信管理接口，guard 为鉴权中间件，如 auth.Require("manage", "admin:mq")，
+// 为 nil 时拒绝所有请求：
+//
+//	GET  /admin/mq/dead-letters/:queue?limit=100         查看死信消息，limit 至多 1000
+//	POST /admin/mq/dead-letters/:queue/replay?id=a&id=b  重放指定（缺省为全部）死信消息
+func RegisterAdmin(r gin.IRouter, b Broker, guard gin.HandlerFunc) {
+	g := r.Group("/admin/mq", middleware.Guard(guard))
+	g.GET("/dead-letters/:queue", func(c *gin.Context) {
+		letters, err := ListDeadLetters(c.Request.Context(), b, c.Param("queue"), adminLimit(c))
+		if err != nil {
+			middleware.Render(c, http.StatusInternalServerError, errno.ServerErr().WithStacks(err))
+			return
+		}
+		middleware.Render(c, http.StatusOK, errno.Ok().WithData(letters))
+	})
+	g.POST("/dead-letters/:queue/replay", func(c *gin.Context) {
+		n, err := ReplayDeadLetters(c.Request.Context(), b, c.Param("queue"), adminLimit(c), c.QueryArray("id")...)
+		if err != nil {
+			middleware.Render(c, http.StatusInternalServerError, errno.ServerErr().WithStacks(err))
+			return
+		}
+		middleware.Render(c, http.StatusOK, errno.Ok().WithData(gin.H{"replayed": n}))
+	})
+}
+
+func adminLimit(c *gin.Context) int {
+	if n, err := strconv.Atoi(c.Query("limit")); err == nil && n > 0 {
+		if n > maxAdminLimit {
+			return maxAdminLimit
+		}
+		return n
+	}
+	return defaultAdminLimit
+}
+
+// ListDeadLetters 查看队列的死信消息，消息查看后放回死信队列
+func ListDeadLetters(ctx context.Context, b Broker, queue string, limit int) ([]DeadLetter, error) {
+	deliveries, err := drain(ctx, b, DeadLetterQueue(queue), limit)
+	defer requeue(deliveries)
+	if err != nil {
+		return nil, err
+	}
+	letters := make([]DeadLetter, 0, len(deliveries))
+	for _, d := range deliveries {
+		letters = append(letters, toDeadLetter(d))
+	}
+	return letters, nil
+}
+
+// ReplayDeadLetters 将死信消息重新发布到原交换机，ids 为空时重放全部
+func ReplayDeadLetters(ctx context.Context, b Broker, queue string, limit int, ids ...string) (int, error) {
+	deliveries, err := drain(ctx, b, DeadLetterQueue(queue), limit)
+	if err != nil {
+		requeue(deliveries)
+		return 0, err
+	}
+	want := map[string]bool{}
+	for _, id := range ids {
+		want[id] = true
+	}
+	var rest []*Delivery
+	replayed := 0
+	for i, d := range deliveries {
+		if len(want) > 0 && !want[d.ID] {
+			rest = append(rest, d)
+			continue
+		}
+		if err = replay(ctx, b, d); err != nil {
+			requeue(append(rest, deliveries[i:]...))
+			return replayed, err
+		}
+		replayed++
+	}
+	requeue(rest)
+	return replayed, nil
+}
+
+func replay(ctx context.Context, b Broker, d *Delivery) error {
+	msg := copyMessage(d.Message)
+	exchange, _ := msg.Headers[HeaderOriginExch].(string)
+	key, _ := msg.Headers[HeaderOriginKey].(string)
+	for _, h := range []string{HeaderRetryCount, HeaderFailReason, HeaderFailStack, HeaderFailedAt,
+		HeaderOriginExch, HeaderOriginKey, HeaderOriginQueue, HeaderPoison} {
+		delete(msg.Headers, h)
+	}
+	if err := b.Publish(ctx, exchange, key, msg); err != nil {
+		return err
+	}
+	return d.Ack()
+}
+
+// drain 拉取至多 limit 条消息，拉取期间消息处于未确认状态
+func drain(ctx context.Context, b Broker, queue string, limit int) ([]*Delivery, error) {
+	var deliveries []*Delivery
+	for len(deliveries) < limit {
+		d, ok, err := b.Get(ctx, queue)
+		if err != nil {
+			return deliveries, err
+		}
+		if !ok {
+			break
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, nil
+}
+
+// requeue 逆序放回，保持消息在队列中的原有顺序
+func requeue(deliveries []*Delivery) {
+	for i := len(deliveries) - 1; i >= 0; i-- {
+		_ = deliveries[i].Nack(true)
+	}
+}
+
+func toDeadLetter(d *Delivery) DeadLetter {
+	str := func(key string) string {
+		s, _ := d.Headers[key].(string)
+		return s
+	}
+	poison, _ := d.Headers[HeaderPoison].(bool)
+	return DeadLetter{
+		ID:         d.ID,
+		Exchange:   str(HeaderOriginExch),
+		RoutingKey: str(HeaderOriginKey),
+		Queue:      str(HeaderOriginQueue),
+		Reason:     str(HeaderFailReason),
+		Stack:      str(HeaderFailStack),
+		FailedAt:   str(HeaderFailedAt),
+		Retries:    argInt(d.Headers[HeaderRetryCount]),
+		Poison:     poison,
+		Headers:    d.Headers,
+		Body:       string(d.Body),
+	}
+}
@@ -0,0 +1,158 @@
+package mq
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sync"
+
+	"github.com/ilooky/go-layout/pkg/config"
+	"github.com/ilooky/logger"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+var _ Broker = (*rabbit)(nil)
+
+type rabbit struct {
+	mu   sync.Mutex
+	conn *amqp.Connection
+	ch   *amqp.Channel
+	wg   sync.WaitGroup
+}
+
+func Url(c config.Mq) string {
+	u := url.URL{
+		Scheme: "amqp",
+		User:   url.UserPassword(c.Username, c.Password),
+		Host:   c.Host + ":" + c.Port,
+		Path:   "/" + c.VirtualHost,
+	}
+	return u.String()
+}
+
+// NewAMQP 连接 RabbitMQ，发布共用一个 channel，每个订阅独占一个 channel
+func NewAMQP(c config.Mq) (Broker, error) {
+	conn, err := amqp.Dial(Url(c))
+	if err != nil {
+		return nil, err
+	}
+	ch, err := conn.Channel()
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	return &rabbit{conn: conn, ch: ch}, nil
+}
+
+func (r *rabbit) Declare(t Topology) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, e := range t.Exchanges {
+		if err := r.ch.ExchangeDeclare(e.Name, e.Kind, e.Durable, e.AutoDelete, false, false, nil); err != nil {
+			return fmt.Errorf("declare exchange %s: %w", e.Name, err)
+		}
+	}
+	for _, q := range t.Queues {
+		if _, err := r.ch.QueueDeclare(q.Name, q.Durable, q.AutoDelete, q.Exclusive, false, q.Args); err != nil {
+			return fmt.Errorf("declare queue %s: %w", q.Name, err)
+		}
+	}
+	for _, b := range t.Bindings {
+		if err := r.ch.QueueBind(b.Queue, b.Key, b.Exchange, false, nil); err != nil {
+			return fmt.Errorf("bind queue %s to %s: %w", b.Queue, b.Exchange, err)
+		}
+	}
+	return nil
+}
+
+func (r *rabbit) Publish(ctx context.Context, exchange, key string, msg Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.ch.Publish(exchange, key, false, false, amqp.Publishing{
+		Headers:      msg.Headers,
+		ContentType:  msg.ContentType,
+		DeliveryMode: amqp.Persistent,
+		MessageId:    msg.ID,
+		Timestamp:    msg.Timestamp,
+		Body:         msg.Body,
+	})
+}
+
+func (r *rabbit) Subscribe(ctx context.Context, queue string, handler Handler) error {
+	ch, err := r.conn.Channel()
+	if err != nil {
+		return err
+	}
+	if err = ch.Qos(1, 0, false); err != nil {
+		_ = ch.Close()
+		return err
+	}
+	deliveries, err := ch.Consume(queue, "", false, false, false, false, nil)
+	if err != nil {
+		_ = ch.Close()
+		return err
+	}
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		defer ch.Close()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case raw, ok := <-deliveries:
+				if !ok {
+					logger.Warnf("mq: consumer of %s stopped, channel closed", queue)
+					return
+				}
+				if err := handle(ctx, handler, fromAMQP(queue, raw)); err != nil {
+					logger.Errorf("mq: handle message %s from %s: %v", raw.MessageId, queue, err)
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+func (r *rabbit) Get(ctx context.Context, queue string) (*Delivery, bool, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, false, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	raw, ok, err := r.ch.Get(queue, false)
+	if err != nil || !ok {
+		return nil, false, err
+	}
+	return fromAMQP(queue, raw), true, nil
+}
+
+func (r *rabbit) Close() error {
+	r.mu.Lock()
+	_ = r.ch.Close()
+	err := r.conn.Close()
+	r.mu.Unlock()
+	r.wg.Wait()
+	return err
+}
+
+func fromAMQP(queue string, raw amqp.Delivery) *Delivery {
+	return &Delivery{
+		Message: Message{
+			ID:          raw.MessageId,
+			ContentType: raw.ContentType,
+			Headers:     raw.Headers,
+			Body:        raw.Body,
+			Timestamp:   raw.Timestamp,
+		},
+		Exchange:    raw.Exchange,
+		RoutingKey:  raw.RoutingKey,
+		Queue:       queue,
+		Redelivered: raw.Redelivered,
+		ack:         func() error { return raw.Ack(false) },
+		nack:        func(requeue bool) error { return raw.Nack(false, requeue) },
+	}
+}
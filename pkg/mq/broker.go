@@ -0,0 +1,140 @@
+package mq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ilooky/go-layout/pkg/config"
+)
+
+const (
+	Direct = "direct"
+	Fanout = "fanout"
+	Topic  = "topic"
+)
+
+// 与 RabbitMQ 一致的队列参数
+const (
+	ArgMessageTTL           = "x-message-ttl"
+	ArgDeadLetterExchange   = "x-dead-letter-exchange"
+	ArgDeadLetterRoutingKey = "x-dead-letter-routing-key"
+)
+
+var (
+	ErrClosed        = errors.New("mq: broker closed")
+	ErrUnknownQueue  = errors.New("mq: queue not declared")
+	ErrUnknownExch   = errors.New("mq: exchange not declared")
+	ErrAlreadyAcked  = errors.New("mq: delivery already acknowledged")
+	ErrUnsupportKind = errors.New("mq: unsupported exchange kind")
+)
+
+// Broker 消息中间件抽象，AMQP 与进程内实现共用
+type Broker interface {
+	// Declare 声明交换机、队列及绑定关系，重复声明是幂等的
+	Declare(t Topology) error
+	Publish(ctx context.Context, exchange, key string, msg Message) error
+	// Subscribe 在后台消费 queue，直到 ctx 结束或 Broker 关闭。
+	// handler 返回 nil 时自动 Ack，返回 error 时 Nack 并重新入队。
+	Subscribe(ctx context.Context, queue string, handler Handler) error
+	// Get 主动拉取一条消息，队列为空时 ok 为 false，拿到的消息必须 Ack 或 Nack
+	Get(ctx context.Context, queue string) (d *Delivery, ok bool, err error)
+	Close() error
+}
+
+type Handler func(ctx context.Context, d *Delivery) error
+
+type Message struct {
+	ID          string
+	ContentType string
+	Headers     map[string]interface{}
+	Body        []byte
+	Timestamp   time.Time
+}
+
+type Delivery struct {
+	Message
+	Exchange    string
+	RoutingKey  string
+	Queue       string
+	Redelivered bool
+
+	acked   bool
+	ack     func() error
+	nack    func(requeue bool) error
+	expires time.Time // Memory 中按 x-message-ttl 过期的时间，重投时沿用
+}
+
+func (d *Delivery) Ack() error {
+	if d.acked {
+		return ErrAlreadyAcked
+	}
+	d.acked = true
+	return d.ack()
+}
+
+func (d *Delivery) Nack(requeue bool) error {
+	if d.acked {
+		return ErrAlreadyAcked
+	}
+	d.acked = true
+	return d.nack(requeue)
+}
+
+type Exchange struct {
+	Name       string
+	Kind       string
+	Durable    bool
+	AutoDelete bool
+}
+
+type Queue struct {
+	Name       string
+	Durable    bool
+	AutoDelete bool
+	Exclusive  bool
+	Args       map[string]interface{}
+}
+
+type Binding struct {
+	Queue    string
+	Exchange string
+	Key      string
+}
+
+type Topology struct {
+	Exchanges []Exchange
+	Queues    []Queue
+	Bindings  []Binding
+}
+
+// TopologyFromConfig 根据 config.Mq 生成拓扑：一个交换机，每个队列以队列名作为路由键绑定
+func TopologyFromConfig(c config.Mq) Topology {
+	t := Topology{
+		Exchanges: []Exchange{{Name: c.Exchange, Kind: c.ExchangeType, Durable: true}},
+	}
+	for _, q := range c.Queues {
+		t.Queues = append(t.Queues, Queue{Name: q, Durable: true})
+		t.Bindings = append(t.Bindings, Binding{Queue: q, Exchange: c.Exchange, Key: q})
+	}
+	return t
+}
+
+// handle 执行 handler 并根据结果确认消息，handler 中的 panic 视为处理失败
+func handle(ctx context.Context, h Handler, d *Delivery) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("mq: handler panic: %v", r)
+		}
+		if d.acked {
+			return
+		}
+		if err != nil {
+			_ = d.Nack(true)
+		} else {
+			_ = d.Ack()
+		}
+	}()
+	return h(ctx, d)
+}
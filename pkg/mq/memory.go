@@ -0,0 +1,368 @@
+package mq
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ilooky/logger"
+)
+
+var _ Broker = (*Memory)(nil)
+
+// Memory 进程内的 Broker 实现，语义与 RabbitMQ 保持一致：
+// 默认交换机按队列名路由，direct/fanout/topic 交换机按绑定路由，
+// 未确认的消息在 Nack(requeue) 后以 Redelivered 标记重新投递，
+// 被拒绝或超过 x-message-ttl 的消息按 x-dead-letter-* 参数转投。
+type Memory struct {
+	mu        sync.Mutex
+	exchanges map[string]*memExchange
+	queues    map[string]*memQueue
+	closed    bool
+	done      chan struct{}
+	wg        sync.WaitGroup
+}
+
+type memExchange struct {
+	Exchange
+	bindings []Binding
+}
+
+type memQueue struct {
+	Queue
+	ready  []*Delivery
+	signal chan struct{}
+}
+
+func NewMemory() *Memory {
+	return &Memory{
+		exchanges: map[string]*memExchange{},
+		queues:    map[string]*memQueue{},
+		done:      make(chan struct{}),
+	}
+}
+
+func (m *Memory) Declare(t Topology) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return ErrClosed
+	}
+	for _, e := range t.Exchanges {
+		switch e.Kind {
+		case Direct, Fanout, Topic:
+		default:
+			return ErrUnsupportKind
+		}
+		if _, ok := m.exchanges[e.Name]; !ok {
+			m.exchanges[e.Name] = &memExchange{Exchange: e}
+		}
+	}
+	for _, q := range t.Queues {
+		if _, ok := m.queues[q.Name]; !ok {
+			m.queues[q.Name] = &memQueue{Queue: q, signal: make(chan struct{}, 1)}
+		}
+	}
+	for _, b := range t.Bindings {
+		e, ok := m.exchanges[b.Exchange]
+		if !ok {
+			return ErrUnknownExch
+		}
+		if _, ok := m.queues[b.Queue]; !ok {
+			return ErrUnknownQueue
+		}
+		exists := false
+		for _, eb := range e.bindings {
+			if eb == b {
+				exists = true
+				break
+			}
+		}
+		if !exists {
+			e.bindings = append(e.bindings, b)
+		}
+	}
+	return nil
+}
+
+func (m *Memory) Publish(ctx context.Context, exchange, key string, msg Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return ErrClosed
+	}
+	if msg.Timestamp.IsZero() {
+		msg.Timestamp = time.Now()
+	}
+	for _, q := range m.route(exchange, key) {
+		m.enqueue(q, &Delivery{Message: copyMessage(msg), Exchange: exchange, RoutingKey: key, Queue: q.Name})
+	}
+	return nil
+}
+
+func (m *Memory) route(exchange, key string) []*memQueue {
+	if exchange == "" {
+		if q, ok := m.queues[key]; ok {
+			return []*memQueue{q}
+		}
+		return nil
+	}
+	e, ok := m.exchanges[exchange]
+	if !ok {
+		return nil
+	}
+	var matched []*memQueue
+	seen := map[string]bool{}
+	for _, b := range e.bindings {
+		if seen[b.Queue] || !matchKey(e.Kind, b.Key, key) {
+			continue
+		}
+		seen[b.Queue] = true
+		matched = append(matched, m.queues[b.Queue])
+	}
+	return matched
+}
+
+func (m *Memory) enqueue(q *memQueue, d *Delivery) {
+	q.ready = append(q.ready, d)
+	notify(q)
+	if ttl := argInt(q.Args[ArgMessageTTL]); ttl > 0 {
+		d.expires = time.Now().Add(time.Duration(ttl) * time.Millisecond)
+		m.schedule(q, d)
+	}
+}
+
+// schedule 在 d.expires 到达时将仍在队列中的消息过期，已过期的消息尽快处理
+func (m *Memory) schedule(q *memQueue, d *Delivery) {
+	if d.expires.IsZero() {
+		return
+	}
+	time.AfterFunc(time.Until(d.expires), func() { m.expire(q, d) })
+}
+
+// expire 将过期且尚未被取走的消息移出队列并死信
+func (m *Memory) expire(q *memQueue, d *Delivery) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return
+	}
+	for i, r := range q.ready {
+		if r == d {
+			q.ready = append(q.ready[:i], q.ready[i+1:]...)
+			m.deadLetter(q, d, "expired")
+			return
+		}
+	}
+}
+
+// deadLetter 按队列的 x-dead-letter-* 参数转投消息，未配置时丢弃
+func (m *Memory) deadLetter(q *memQueue, d *Delivery, reason string) {
+	dlx, ok := q.Args[ArgDeadLetterExchange].(string)
+	if !ok {
+		return
+	}
+	key := d.RoutingKey
+	if k, ok := q.Args[ArgDeadLetterRoutingKey].(string); ok {
+		key = k
+	}
+	// 每个目标队列持有独立的副本，避免修改头部时相互影响
+	for _, target := range m.route(dlx, key) {
+		msg := copyMessage(d.Message)
+		if msg.Headers == nil {
+			msg.Headers = map[string]interface{}{}
+		}
+		msg.Headers["x-first-death-reason"] = reason
+		msg.Headers["x-first-death-queue"] = q.Name
+		m.enqueue(target, &Delivery{Message: msg, Exchange: dlx, RoutingKey: key, Queue: target.Name})
+	}
+}
+
+func (m *Memory) Subscribe(ctx context.Context, queue string, handler Handler) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return ErrClosed
+	}
+	q, ok := m.queues[queue]
+	if !ok {
+		return ErrUnknownQueue
+	}
+	m.wg.Add(1)
+	go m.consume(ctx, q, handler)
+	return nil
+}
+
+func (m *Memory) consume(ctx context.Context, q *memQueue, handler Handler) {
+	defer m.wg.Done()
+	for {
+		// ctx 结束后不再取出消息，留在队列中的消息由其他消费者继续处理
+		if ctx.Err() != nil {
+			return
+		}
+		m.mu.Lock()
+		var d *Delivery
+		if len(q.ready) > 0 && !m.closed {
+			d = q.ready[0]
+			q.ready = q.ready[1:]
+		}
+		m.mu.Unlock()
+		if d == nil {
+			select {
+			case <-ctx.Done():
+				return
+			case <-m.done:
+				return
+			case <-q.signal:
+				continue
+			}
+		}
+		m.bind(q, d)
+		if err := handle(ctx, handler, d); err != nil {
+			logger.Errorf("mq: handle message %s from %s: %v", d.ID, q.Name, err)
+		}
+		// 保证其他消费者也能被唤醒
+		m.mu.Lock()
+		if len(q.ready) > 0 {
+			notify(q)
+		}
+		m.mu.Unlock()
+	}
+}
+
+// bind 为投递绑定确认回调，Nack(true) 时放回队首并标记为重投，过期时间沿用首次入队时的计算结果
+func (m *Memory) bind(q *memQueue, d *Delivery) {
+	d.acked = false
+	d.ack = func() error { return nil }
+	d.nack = func(requeue bool) error {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		if m.closed {
+			return ErrClosed
+		}
+		if !requeue {
+			m.deadLetter(q, d, "rejected")
+			return nil
+		}
+		redo := &Delivery{Message: d.Message, Exchange: d.Exchange, RoutingKey: d.RoutingKey, Queue: q.Name,
+			Redelivered: true, expires: d.expires}
+		q.ready = append([]*Delivery{redo}, q.ready...)
+		notify(q)
+		m.schedule(q, redo)
+		return nil
+	}
+}
+
+func (m *Memory) Get(ctx context.Context, queue string) (*Delivery, bool, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, false, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return nil, false, ErrClosed
+	}
+	q, ok := m.queues[queue]
+	if !ok {
+		return nil, false, ErrUnknownQueue
+	}
+	if len(q.ready) == 0 {
+		return nil, false, nil
+	}
+	d := q.ready[0]
+	q.ready = q.ready[1:]
+	m.bind(q, d)
+	return d, true, nil
+}
+
+// Len 返回队列中待投递的消息数量，便于测试断言
+func (m *Memory) Len(queue string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if q, ok := m.queues[queue]; ok {
+		return len(q.ready)
+	}
+	return 0
+}
+
+func (m *Memory) Close() error {
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return nil
+	}
+	m.closed = true
+	close(m.done)
+	m.mu.Unlock()
+	m.wg.Wait()
+	return nil
+}
+
+func notify(q *memQueue) {
+	select {
+	case q.signal <- struct{}{}:
+	default:
+	}
+}
+
+func copyMessage(msg Message) Message {
+	if msg.Headers != nil {
+		headers := make(map[string]interface{}, len(msg.Headers))
+		for k, v := range msg.Headers {
+			headers[k] = v
+		}
+		msg.Headers = headers
+	}
+	if msg.Body != nil {
+		msg.Body = append([]byte(nil), msg.Body...)
+	}
+	return msg
+}
+
+func argInt(v interface{}) int64 {
+	switch n := v.(type) {
+	case int:
+		return int64(n)
+	case int32:
+		return int64(n)
+	case int64:
+		return n
+	}
+	return 0
+}
+
+func matchKey(kind, pattern, key string) bool {
+	switch kind {
+	case Fanout:
+		return true
+	case Direct:
+		return pattern == key
+	case Topic:
+		return matchTopic(strings.Split(pattern, "."), strings.Split(key, "."))
+	}
+	return false
+}
+
+// matchTopic 实现 AMQP topic 匹配，* 匹配一个单词，# 匹配零或多个单词
+func matchTopic(pattern, words []string) bool {
+	if len(pattern) == 0 {
+		return len(words) == 0
+	}
+	switch pattern[0] {
+	case "#":
+		for i := 0; i <= len(words); i++ {
+			if matchTopic(pattern[1:], words[i:]) {
+				return true
+			}
+		}
+		return false
+	case "*":
+		return len(words) > 0 && matchTopic(pattern[1:], words[1:])
+	default:
+		return len(words) > 0 && pattern[0] == words[0] && matchTopic(pattern[1:], words[1:])
+	}
+}
@@ -0,0 +1,193 @@
+package mq
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ilooky/go-layout/pkg/config"
+)
+
+func newTestBroker(t *testing.T) *Memory {
+	m := NewMemory()
+	t.Cleanup(func() { _ = m.Close() })
+	err := m.Declare(TopologyFromConfig(config.Mq{
+		Exchange:     "push",
+		ExchangeType: Topic,
+		Queues:       []string{"line", "diagram"},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestMatchTopic(t *testing.T) {
+	tests := []struct {
+		pattern, key string
+		want         bool
+	}{
+		{"line", "line", true},
+		{"line", "diagram", false},
+		{"line.*", "line.add", true},
+		{"line.*", "line.add.more", false},
+		{"line.#", "line", true},
+		{"line.#", "line.add.more", true},
+		{"#.del", "line.add.del", true},
+		{"*.del", "del", false},
+	}
+	for _, test := range tests {
+		if got := matchKey(Topic, test.pattern, test.key); got != test.want {
+			t.Errorf("matchKey(%q, %q) = %v, want %v", test.pattern, test.key, got, test.want)
+		}
+	}
+}
+
+func TestMemoryRouting(t *testing.T) {
+	m := newTestBroker(t)
+	ctx := context.Background()
+	if err := m.Publish(ctx, "push", "line", Message{Body: []byte("a")}); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Publish(ctx, "", "diagram", Message{Body: []byte("b")}); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Publish(ctx, "push", "global", Message{Body: []byte("c")}); err != nil {
+		t.Fatal(err)
+	}
+	if n := m.Len("line"); n != 1 {
+		t.Errorf("line has %d messages, want 1", n)
+	}
+	if n := m.Len("diagram"); n != 1 {
+		t.Errorf("diagram has %d messages, want 1", n)
+	}
+}
+
+func TestMemoryRedelivery(t *testing.T) {
+	m := newTestBroker(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	got := make(chan *Delivery, 2)
+	err := m.Subscribe(ctx, "line", func(ctx context.Context, d *Delivery) error {
+		got <- d
+		if !d.Redelivered {
+			return errors.New("fail first time")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = m.Publish(ctx, "push", "line", Message{ID: "1", Body: []byte("a")}); err != nil {
+		t.Fatal(err)
+	}
+	for i, redelivered := range []bool{false, true} {
+		select {
+		case d := <-got:
+			if d.Redelivered != redelivered || d.ID != "1" {
+				t.Errorf("delivery %d: id=%s redelivered=%v", i, d.ID, d.Redelivered)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("delivery %d not received", i)
+		}
+	}
+	cancel()
+	if n := m.Len("line"); n != 0 {
+		t.Errorf("line has %d messages after ack, want 0", n)
+	}
+}
+
+func TestMemoryUnknownQueue(t *testing.T) {
+	m := newTestBroker(t)
+	err := m.Subscribe(context.Background(), "nope", func(ctx context.Context, d *Delivery) error { return nil })
+	if !errors.Is(err, ErrUnknownQueue) {
+		t.Errorf("got %v, want ErrUnknownQueue", err)
+	}
+}
+
+func TestMemoryStopOnCancel(t *testing.T) {
+	m := newTestBroker(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	for _, id := range []string{"1", "2", "3"} {
+		if err := m.Publish(ctx, "push", "line", Message{ID: id}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	handled := 0
+	err := m.Subscribe(ctx, "line", func(context.Context, *Delivery) error {
+		handled++
+		cancel()
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-ctx.Done()
+	_ = m.Close()
+	if handled != 1 {
+		t.Errorf("handled %d messages after cancel, want 1", handled)
+	}
+	if n := m.Len("line"); n != 2 {
+		t.Errorf("line has %d messages, want 2", n)
+	}
+}
+
+func TestMemoryRequeueExpires(t *testing.T) {
+	m := NewMemory()
+	t.Cleanup(func() { _ = m.Close() })
+	err := m.Declare(Topology{Queues: []Queue{
+		{Name: "wait", Args: map[string]interface{}{ArgMessageTTL: 50, ArgDeadLetterExchange: "", ArgDeadLetterRoutingKey: "line"}},
+		{Name: "line"},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	_ = m.Publish(ctx, "", "wait", Message{ID: "1"})
+	d, ok, err := m.Get(ctx, "wait")
+	if err != nil || !ok {
+		t.Fatalf("Get() = %v, %v", ok, err)
+	}
+	_ = d.Nack(true)
+	deadline := time.Now().Add(time.Second)
+	for m.Len("line") == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("requeued message did not expire")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if n := m.Len("wait"); n != 0 {
+		t.Errorf("wait has %d messages, want 0", n)
+	}
+}
+
+func TestMemoryDeadLetterHeaders(t *testing.T) {
+	m := NewMemory()
+	t.Cleanup(func() { _ = m.Close() })
+	err := m.Declare(Topology{
+		Exchanges: []Exchange{{Name: "dlx", Kind: Fanout}},
+		Queues:    []Queue{{Name: "line", Args: map[string]interface{}{ArgDeadLetterExchange: "dlx"}}, {Name: "a"}, {Name: "b"}},
+		Bindings:  []Binding{{Exchange: "dlx", Queue: "a"}, {Exchange: "dlx", Queue: "b"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	_ = m.Publish(ctx, "", "line", Message{ID: "1", Headers: map[string]interface{}{"x-tenant": "t1"}})
+	d, _, _ := m.Get(ctx, "line")
+	_ = d.Nack(false)
+	if _, ok := d.Headers["x-first-death-reason"]; ok {
+		t.Error("dead-lettering modified the original headers")
+	}
+	a, _, _ := m.Get(ctx, "a")
+	b, _, _ := m.Get(ctx, "b")
+	if a == nil || b == nil {
+		t.Fatal("dead letter not routed to both queues")
+	}
+	a.Headers["x-tenant"] = "t2"
+	if b.Headers["x-tenant"] != "t1" || b.Headers["x-first-death-reason"] != "rejected" {
+		t.Errorf("b headers = %v, want an independent copy", b.Headers)
+	}
+}
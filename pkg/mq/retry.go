@@ -0,0 +1,211 @@
+package mq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"runtime/debug"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ilooky/go-layout/pkg/config"
+	"github.com/ilooky/logger"
+)
+
+// 死信消息携带的头信息
+const (
+	HeaderRetryCount  = "x-retry-count"
+	HeaderFailReason  = "x-failure-reason"
+	HeaderFailStack   = "x-failure-stack"
+	HeaderFailedAt    = "x-failed-at"
+	HeaderOriginExch  = "x-original-exchange"
+	HeaderOriginKey   = "x-original-routing-key"
+	HeaderOriginQueue = "x-original-queue"
+	HeaderPoison      = "x-poison"
+)
+
+func DeadLetterQueue(queue string) string {
+	return queue + ".dlq"
+}
+
+func retryQueue(queue string, attempt int) string {
+	return queue + ".retry." + strconv.Itoa(attempt)
+}
+
+// RetryDelay 返回第 attempt 次（从 1 开始）重试的指数退避延迟
+func RetryDelay(p config.MqPolicy, attempt int) time.Duration {
+	d := float64(p.Delay) * math.Pow(p.Multiplier, float64(attempt-1))
+	if d > float64(p.MaxDelay) {
+		return p.MaxDelay
+	}
+	return time.Duration(d)
+}
+
+// RetryTopology 为队列声明逐级延迟队列和死信队列。
+// 第 n 次重试的消息进入 <queue>.retry.<n>，TTL 到期后经默认交换机回到原队列；
+// 由于 RabbitMQ 只在队首检查过期，每一级延迟使用独立队列保证顺序过期。
+func RetryTopology(queue string, p config.MqPolicy) Topology {
+	t := Topology{Queues: []Queue{{Name: DeadLetterQueue(queue), Durable: true}}}
+	for n := 1; n <= p.MaxRetries; n++ {
+		t.Queues = append(t.Queues, Queue{
+			Name:    retryQueue(queue, n),
+			Durable: true,
+			Args: map[string]interface{}{
+				ArgMessageTTL:           RetryDelay(p, n).Milliseconds(),
+				ArgDeadLetterExchange:   "",
+				ArgDeadLetterRoutingKey: queue,
+			},
+		})
+	}
+	return t
+}
+
+type permanent struct {
+	err error
+}
+
+func (p permanent) Error() string { return p.err.Error() }
+func (p permanent) Unwrap() error { return p.err }
+
+// Permanent 标记不可恢复的错误（如消息体无法解析），消息将跳过重试直接进入死信队列
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return permanent{err: err}
+}
+
+// PoisonDetector 识别毒消息：同一消息 ID 在未经重试流程的情况下被反复重投
+// （通常是消费者处理时崩溃），超过 Limit 次即判定为毒消息
+type PoisonDetector struct {
+	Limit int
+	mu    sync.Mutex
+	seen  map[string]int
+}
+
+func NewPoisonDetector(limit int) *PoisonDetector {
+	return &PoisonDetector{Limit: limit, seen: map[string]int{}}
+}
+
+// Observe 记录一次投递并返回是否为毒消息
+func (p *PoisonDetector) Observe(d *Delivery) bool {
+	if d.ID == "" || !d.Redelivered {
+		return false
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	// 只保留近期的记录，避免无限增长
+	if len(p.seen) > 10000 {
+		p.seen = map[string]int{}
+	}
+	p.seen[d.ID]++
+	return p.seen[d.ID] > p.Limit
+}
+
+func (p *PoisonDetector) forget(id string) {
+	p.mu.Lock()
+	delete(p.seen, id)
+	p.mu.Unlock()
+}
+
+// Consumer 按 config.MqPolicy 处理消费失败的消息：指数退避重试，
+// 超过次数、不可恢复错误或毒消息转入死信队列并记录原因与堆栈
+type Consumer struct {
+	broker   Broker
+	queue    string
+	policy   config.MqPolicy
+	detector *PoisonDetector
+}
+
+func NewConsumer(b Broker, queue string, p config.MqPolicy) *Consumer {
+	return &Consumer{broker: b, queue: queue, policy: p, detector: NewPoisonDetector(3)}
+}
+
+// Declare 声明重试与死信队列，原队列需已通过 TopologyFromConfig 声明
+func (c *Consumer) Declare() error {
+	return c.broker.Declare(RetryTopology(c.queue, c.policy))
+}
+
+func (c *Consumer) Subscribe(ctx context.Context, handler Handler) error {
+	return c.broker.Subscribe(ctx, c.queue, c.Wrap(handler))
+}
+
+// Wrap 返回带有重试和死信策略的 Handler
+func (c *Consumer) Wrap(handler Handler) Handler {
+	return func(ctx context.Context, d *Delivery) error {
+		if c.detector.Observe(d) {
+			return c.dead(ctx, d, "poison message: redelivered too many times", "", true)
+		}
+		stack, err := invoke(ctx, handler, d)
+		if err == nil {
+			c.detector.forget(d.ID)
+			return nil
+		}
+		var p permanent
+		if errors.As(err, &p) {
+			return c.dead(ctx, d, err.Error(), stack, true)
+		}
+		attempt := int(argInt(d.Headers[HeaderRetryCount])) + 1
+		if attempt > c.policy.MaxRetries {
+			return c.dead(ctx, d, err.Error(), stack, false)
+		}
+		logger.Warnf("mq: message %s from %s failed, retry %d/%d: %v", d.ID, c.queue, attempt, c.policy.MaxRetries, err)
+		msg := withOrigin(d)
+		msg.Headers[HeaderRetryCount] = int64(attempt)
+		if err := c.broker.Publish(ctx, "", retryQueue(c.queue, attempt), msg); err != nil {
+			return err
+		}
+		c.detector.forget(d.ID)
+		return d.Ack()
+	}
+}
+
+func (c *Consumer) dead(ctx context.Context, d *Delivery, reason, stack string, poison bool) error {
+	logger.Errorf("mq: message %s from %s dead-lettered: %s", d.ID, c.queue, reason)
+	msg := withOrigin(d)
+	msg.Headers[HeaderFailReason] = reason
+	msg.Headers[HeaderFailStack] = stack
+	msg.Headers[HeaderFailedAt] = time.Now().Format(time.RFC3339)
+	msg.Headers[HeaderOriginQueue] = c.queue
+	if poison {
+		msg.Headers[HeaderPoison] = true
+	}
+	if err := c.broker.Publish(ctx, "", DeadLetterQueue(c.queue), msg); err != nil {
+		return err
+	}
+	c.detector.forget(d.ID)
+	return d.Ack()
+}
+
+// invoke 执行 handler，发生 panic 时转换为错误并保留 panic 处的堆栈；
+// 返回的错误只有自带堆栈（%+v 输出多于 Error()，如 github.com/pkg/errors）时才记录，否则 stack 为空
+func invoke(ctx context.Context, h Handler, d *Delivery) (stack string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+			stack = string(debug.Stack())
+		}
+	}()
+	err = h(ctx, d)
+	if err != nil {
+		if s := fmt.Sprintf("%+v", err); s != err.Error() {
+			stack = s
+		}
+	}
+	return
+}
+
+// withOrigin 复制消息并记录首次投递的交换机和路由键，重试回流后仍能据此重放
+func withOrigin(d *Delivery) Message {
+	msg := copyMessage(d.Message)
+	if msg.Headers == nil {
+		msg.Headers = map[string]interface{}{}
+	}
+	if _, ok := msg.Headers[HeaderOriginExch]; !ok {
+		msg.Headers[HeaderOriginExch] = d.Exchange
+		msg.Headers[HeaderOriginKey] = d.RoutingKey
+	}
+	return msg
+}
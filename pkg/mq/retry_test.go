@@ -0,0 +1,171 @@
+package mq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ilooky/go-layout/pkg/config"
+)
+
+func TestRetryDelay(t *testing.T) {
+	p := config.Mq{}.Policy("line")
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second}
+	for i, w := range want {
+		if got := RetryDelay(p, i+1); got != w {
+			t.Errorf("RetryDelay(%d) = %v, want %v", i+1, got, w)
+		}
+	}
+	p.MaxDelay = 3 * time.Second
+	if got := RetryDelay(p, 3); got != p.MaxDelay {
+		t.Errorf("RetryDelay capped = %v, want %v", got, p.MaxDelay)
+	}
+}
+
+func TestConsumerDeadLetter(t *testing.T) {
+	m := newTestBroker(t)
+	policy := config.MqPolicy{MaxRetries: 2, Delay: 10 * time.Millisecond, MaxDelay: 20 * time.Millisecond, Multiplier: 2}
+	c := NewConsumer(m, "line", policy)
+	if err := c.Declare(); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var calls int32
+	err := c.Subscribe(ctx, func(ctx context.Context, d *Delivery) error {
+		atomic.AddInt32(&calls, 1)
+		return errors.New("boom")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = m.Publish(ctx, "push", "line", Message{ID: "m1", Body: []byte("x")}); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for m.Len(DeadLetterQueue("line")) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("message not dead-lettered")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if n := atomic.LoadInt32(&calls); n != 3 {
+		t.Errorf("handler called %d times, want 3", n)
+	}
+	letters, err := ListDeadLetters(ctx, m, "line", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(letters) != 1 {
+		t.Fatalf("got %d dead letters, want 1", len(letters))
+	}
+	l := letters[0]
+	if l.ID != "m1" || l.Reason != "boom" || l.Retries != 2 || l.Exchange != "push" || l.RoutingKey != "line" {
+		t.Errorf("unexpected dead letter %+v", l)
+	}
+	if m.Len(DeadLetterQueue("line")) != 1 {
+		t.Error("listing should keep messages in the dead-letter queue")
+	}
+}
+
+func TestPermanentAndReplay(t *testing.T) {
+	m := newTestBroker(t)
+	c := NewConsumer(m, "line", config.Mq{}.Policy("line"))
+	if err := c.Declare(); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	fail := int32(1)
+	done := make(chan string, 1)
+	err := c.Subscribe(ctx, func(ctx context.Context, d *Delivery) error {
+		if atomic.LoadInt32(&fail) == 1 {
+			return Permanent(errors.New("bad payload"))
+		}
+		done <- d.ID
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = m.Publish(ctx, "push", "line", Message{ID: "m2"})
+	deadline := time.Now().Add(time.Second)
+	for m.Len(DeadLetterQueue("line")) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("permanent failure not dead-lettered")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	atomic.StoreInt32(&fail, 0)
+	n, err := ReplayDeadLetters(ctx, m, "line", 10, "m2")
+	if err != nil || n != 1 {
+		t.Fatalf("replayed %d, err %v", n, err)
+	}
+	select {
+	case id := <-done:
+		if id != "m2" {
+			t.Errorf("replayed %s, want m2", id)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("replayed message not consumed")
+	}
+}
+
+func TestAdminLimit(t *testing.T) {
+	tests := []struct {
+		query string
+		want  int
+	}{
+		{"", defaultAdminLimit},
+		{"limit=abc", defaultAdminLimit},
+		{"limit=-1", defaultAdminLimit},
+		{"limit=50", 50},
+		{"limit=1000", maxAdminLimit},
+		{"limit=100000000", maxAdminLimit},
+	}
+	for _, tt := range tests {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest(http.MethodGet, "/?"+tt.query, nil)
+		if got := adminLimit(c); got != tt.want {
+			t.Errorf("adminLimit(%q) = %d, want %d", tt.query, got, tt.want)
+		}
+	}
+}
+
+// stacked 模拟自带堆栈的错误
+type stacked struct{ error }
+
+func (e stacked) Format(s fmt.State, verb rune) {
+	_, _ = fmt.Fprint(s, e.Error())
+	if s.Flag('+') {
+		_, _ = fmt.Fprint(s, "\nmain.handle\n\tmain.go:10")
+	}
+}
+
+func TestInvokeStack(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler Handler
+		want    string
+	}{
+		{"plain", func(context.Context, *Delivery) error { return errors.New("boom") }, ""},
+		{"stacked", func(context.Context, *Delivery) error { return stacked{errors.New("boom")} }, "main.go:10"},
+		{"panic", func(context.Context, *Delivery) error { panic("boom") }, "TestInvokeStack"},
+	}
+	for _, tt := range tests {
+		stack, err := invoke(context.Background(), tt.handler, &Delivery{})
+		if err == nil {
+			t.Errorf("%s: no error", tt.name)
+		}
+		if tt.want == "" && stack != "" || !strings.Contains(stack, tt.want) {
+			t.Errorf("%s: stack = %q, want %q", tt.name, stack, tt.want)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+// Package outbox 实现事务性发件箱：事件与业务数据在同一个 xorm 事务中写入 outbox 表，
+// 再由 Relay 按写入顺序投递到 MQ，保证业务写入成功的事件至少投递一次。
+package outbox
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"github.com/ilooky/go-layout/pkg/database"
+	"github.com/ilooky/go-layout/pkg/guava/json"
+	"xorm.io/xorm"
+)
+
+const (
+	StatusPending = 0
+	StatusSent    = 1
+)
+
+// HeaderIdempotencyKey 投递时携带的幂等键，同时作为消息 ID，消费者据此去重
+const HeaderIdempotencyKey = "x-idempotency-key"
+
+type Outbox struct {
+	Id          int64     `json:"id"`
+	Key         string    `json:"key"        xorm:"varchar(64) notnull unique 'idempotency_key'"`
+	Exchange    string    `json:"exchange"   xorm:"varchar(128)"`
+	RoutingKey  string    `json:"routingKey" xorm:"varchar(128)"`
+	ContentType string    `json:"-"          xorm:"varchar(64)"`
+	Headers     string    `json:"headers"    xorm:"text"`
+	Payload     []byte    `json:"-"          xorm:"blob"`
+	Status      int       `json:"status"     xorm:"index(idx_outbox_status)"`
+	Attempts    int       `json:"attempts"`
+	LastError   string    `json:"lastError"  xorm:"varchar(512)"`
+	Created     time.Time `json:"created"    xorm:"created"`
+	SentAt      time.Time `json:"sentAt"     xorm:"index(idx_outbox_status)"`
+}
+
+// Sync 在 ctx 对应的数据库中创建或同步 outbox 表
+func Sync(ctx context.Context) error {
+	return database.SyncTableContext(ctx, new(Outbox))
+}
+
+// Add 在业务事务 session 中写入事件，Key 为空时自动生成幂等键
+func Add(session *xorm.Session, event *Outbox) error {
+	if event.Key == "" {
+		event.Key = NewKey()
+	}
+	event.Status = StatusPending
+	_, err := session.InsertOne(event)
+	return err
+}
+
+// AddJSON 将 v 序列化为 JSON 作为事件内容写入 outbox
+func AddJSON(session *xorm.Session, exchange, routingKey string, v interface{}) (*Outbox, error) {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	event := &Outbox{Exchange: exchange, RoutingKey: routingKey, ContentType: "application/json", Payload: payload}
+	return event, Add(session, event)
+}
+
+func NewKey() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,232 @@
+package outbox
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/ilooky/go-layout/pkg/database/dbtest"
+	"github.com/ilooky/go-layout/pkg/mq"
+	"xorm.io/xorm"
+)
+
+var columns = []string{"id", "idempotency_key", "exchange", "routing_key", "content_type", "headers",
+	"payload", "status", "attempts", "last_error", "created", "sent_at"}
+
+func pending(ids ...int64) [][]driver.Value {
+	var rows [][]driver.Value
+	for _, id := range ids {
+		key := "k" + string(rune('0'+id))
+		rows = append(rows, []driver.Value{id, key, "", "line", "application/json", `{"x-tenant":"t1"}`,
+			[]byte(`{"id":` + string(rune('0'+id)) + `}`), int64(StatusPending), int64(0), "", time.Now(), time.Time{}})
+	}
+	return rows
+}
+
+func memory(t *testing.T) *mq.Memory {
+	m := mq.NewMemory()
+	if err := m.Declare(mq.Topology{Queues: []mq.Queue{{Name: "line"}}}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = m.Close() })
+	return m
+}
+
+// failing 在投递指定幂等键的消息时返回错误
+type failing struct {
+	mq.Broker
+	key string
+	err error
+}
+
+func (f failing) Publish(ctx context.Context, exchange, key string, msg mq.Message) error {
+	if msg.ID == f.key {
+		return f.err
+	}
+	return f.Broker.Publish(ctx, exchange, key, msg)
+}
+
+func sqls(stmts []dbtest.Stmt) []string {
+	var list []string
+	for _, s := range stmts {
+		list = append(list, strings.Fields(s.SQL)[0])
+	}
+	return list
+}
+
+func TestAddInTx(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want []string
+	}{
+		{"commit", nil, []string{"BEGIN", "INSERT", "COMMIT"}},
+		{"rollback", errors.New("business failed"), []string{"BEGIN", "INSERT", "ROLLBACK"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &dbtest.Driver{Affected: func(string, []driver.Value) int64 { return 1 }}
+			e := dbtest.Open(t, d)
+			var event *Outbox
+			_, err := e.Transaction(func(s *xorm.Session) (interface{}, error) {
+				var err error
+				if event, err = AddJSON(s, "push", "line.add", map[string]int{"id": 1}); err != nil {
+					return nil, err
+				}
+				return nil, tt.err
+			})
+			if err != tt.err {
+				t.Fatalf("Transaction() error = %v, want %v", err, tt.err)
+			}
+			if got := sqls(d.Execs()); strings.Join(got, " ") != strings.Join(tt.want, " ") {
+				t.Fatalf("statements = %v, want %v", got, tt.want)
+			}
+			insert := d.Execs()[1]
+			if !strings.Contains(insert.SQL, "`outbox`") {
+				t.Errorf("insert = %s, want outbox table", insert.SQL)
+			}
+			if len(event.Key) != 32 || event.Status != StatusPending || string(event.Payload) != `{"id":1}` {
+				t.Errorf("event = %+v", event)
+			}
+		})
+	}
+}
+
+func TestRelayFlushOrder(t *testing.T) {
+	d := &dbtest.Driver{Columns: columns, Rows: pending(1, 2, 3), Affected: func(string, []driver.Value) int64 { return 1 }}
+	m := memory(t)
+	r := NewRelay(dbtest.Open(t, d), m)
+	n, err := r.Flush(context.Background())
+	if err != nil || n != 3 {
+		t.Fatalf("Flush() = %d, %v, want 3, nil", n, err)
+	}
+	query := d.Queries()[0].SQL
+	if !strings.Contains(query, "ORDER BY `id` ASC") || !strings.Contains(query, "FOR UPDATE") {
+		t.Errorf("query = %s, want ordered by id and locked", query)
+	}
+	execs := d.Execs()
+	if got := sqls(execs); strings.Join(got, " ") != "BEGIN UPDATE UPDATE UPDATE COMMIT" {
+		t.Fatalf("statements = %v", got)
+	}
+	for i, s := range execs[1:4] {
+		if id := s.Args[len(s.Args)-1]; id != int64(i+1) || s.Args[0] != int64(StatusSent) {
+			t.Errorf("update %d args = %v, want status sent for id %d", i, s.Args, i+1)
+		}
+	}
+	for _, key := range []string{"k1", "k2", "k3"} {
+		msg, ok, err := m.Get(context.Background(), "line")
+		if err != nil || !ok {
+			t.Fatalf("Get() = %v, %v", ok, err)
+		}
+		if msg.ID != key || msg.Headers[HeaderIdempotencyKey] != key || msg.Headers["x-tenant"] != "t1" {
+			t.Errorf("message = %+v, want %s", msg.Message, key)
+		}
+		_ = msg.Ack()
+	}
+}
+
+func TestRelayFlushRetry(t *testing.T) {
+	d := &dbtest.Driver{Columns: columns, Rows: pending(1, 2, 3), Affected: func(string, []driver.Value) int64 { return 1 }}
+	m := memory(t)
+	cause := errors.New(strings.Repeat("错", 200))
+	r := NewRelay(dbtest.Open(t, d), failing{Broker: m, key: "k2", err: cause})
+	n, err := r.Flush(context.Background())
+	if n != 1 || !errors.Is(err, cause) {
+		t.Fatalf("Flush() = %d, %v, want 1, %v", n, err, cause)
+	}
+	execs := d.Execs()
+	if got := sqls(execs); strings.Join(got, " ") != "BEGIN UPDATE UPDATE COMMIT" {
+		t.Fatalf("statements = %v, want the batch to stop at the failed event", got)
+	}
+	failed := execs[2]
+	if !strings.Contains(failed.SQL, "`attempts`") || failed.Args[0] != int64(1) || failed.Args[2] != int64(2) {
+		t.Errorf("failed update = %s %v, want attempts 1 for id 2", failed.SQL, failed.Args)
+	}
+	lastError := failed.Args[1].(string)
+	if len(lastError) > 512 || !utf8.ValidString(lastError) {
+		t.Errorf("last_error has %d bytes, valid utf8 %v", len(lastError), utf8.ValidString(lastError))
+	}
+	if got := m.Len("line"); got != 1 {
+		t.Errorf("published %d messages, want 1", got)
+	}
+
+	// 恢复后再次投递从失败的事件开始
+	d.Rows = pending(2, 3)
+	d.Reset()
+	r.broker = m
+	if n, err = r.Flush(context.Background()); err != nil || n != 2 {
+		t.Fatalf("Flush() = %d, %v, want 2, nil", n, err)
+	}
+	if got := m.Len("line"); got != 3 {
+		t.Errorf("published %d messages, want 3", got)
+	}
+}
+
+func TestRelayBackoff(t *testing.T) {
+	r := &Relay{Interval: time.Second, MaxBackoff: 10 * time.Second}
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{0, time.Second},
+		{1, 2 * time.Second},
+		{3, 8 * time.Second},
+		{4, 10 * time.Second},
+		{100, 10 * time.Second},
+	}
+	for _, tt := range tests {
+		if got := r.backoff(tt.failures); got != tt.want {
+			t.Errorf("backoff(%d) = %v, want %v", tt.failures, got, tt.want)
+		}
+	}
+}
+
+func TestRelayCleanup(t *testing.T) {
+	calls := 0
+	d := &dbtest.Driver{Affected: func(string, []driver.Value) int64 {
+		calls++
+		if calls < 3 {
+			return 2
+		}
+		return 1
+	}}
+	r := NewRelay(dbtest.Open(t, d), mq.NewMemory())
+	r.BatchSize = 2
+	n, err := r.Cleanup(context.Background())
+	if err != nil || n != 5 {
+		t.Fatalf("Cleanup() = %d, %v, want 5, nil", n, err)
+	}
+	execs := d.Execs()
+	if len(execs) != 3 {
+		t.Fatalf("executed %d statements, want 3", len(execs))
+	}
+	for _, s := range execs {
+		if !strings.HasPrefix(s.SQL, "DELETE FROM outbox ") || !strings.HasSuffix(s.SQL, "LIMIT ?") ||
+			s.Args[0] != int64(StatusSent) || s.Args[2] != int64(2) {
+			t.Errorf("statement = %s %v", s.SQL, s.Args)
+		}
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		s    string
+		n    int
+		want string
+	}{
+		{"abc", 5, "abc"},
+		{"abcdef", 3, "abc"},
+		{"错误信息", 7, "错误"},
+		{"错误信息", 6, "错误"},
+		{"错误信息", 2, ""},
+	}
+	for _, tt := range tests {
+		if got := truncate(tt.s, tt.n); got != tt.want {
+			t.Errorf("truncate(%q, %d) = %q, want %q", tt.s, tt.n, got, tt.want)
+		}
+	}
+}
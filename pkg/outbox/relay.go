@@ -0,0 +1,176 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"time"
+	"unicode/utf8"
+
+	"github.com/ilooky/go-layout/pkg/guava/json"
+	"github.com/ilooky/go-layout/pkg/mq"
+	"github.com/ilooky/logger"
+	"xorm.io/xorm"
+)
+
+// Relay 轮询 outbox 表并按 id 顺序投递待发送事件。
+// 选取事件时使用 SELECT ... FOR UPDATE，多个实例同时运行也不会乱序；
+// 投递成功后才标记为已发送，进程在两步之间崩溃会导致重复投递，由消费者按幂等键去重。
+type Relay struct {
+	db         *xorm.Engine
+	broker     mq.Broker
+	Interval   time.Duration // 轮询间隔
+	MaxBackoff time.Duration // 投递连续失败时轮询间隔逐次翻倍的上限
+	BatchSize  int           // 每次投递的最大事件数
+	Retention  time.Duration // 已发送事件的保留时间
+}
+
+// NewRelay 创建投递 db 中 outbox 表的 Relay；多租户时事件写在租户库中，
+// 需为每个租户库分别创建，db 可由 database.Engine(tenant.WithTenant(ctx, t)) 取得
+func NewRelay(db *xorm.Engine, b mq.Broker) *Relay {
+	return &Relay{
+		db:         db,
+		broker:     b,
+		Interval:   time.Second,
+		MaxBackoff: time.Minute,
+		BatchSize:  100,
+		Retention:  7 * 24 * time.Hour,
+	}
+}
+
+// Run 持续投递事件并定期清理已发送事件，直到 ctx 结束；投递失败时按 backoff 延长下次轮询的间隔
+func (r *Relay) Run(ctx context.Context) error {
+	timer := time.NewTimer(r.Interval)
+	defer timer.Stop()
+	lastCleanup := time.Time{}
+	failures := 0
+	for {
+		for {
+			n, err := r.Flush(ctx)
+			if err != nil {
+				failures++
+				logger.Errorf("outbox: flush failed %d times: %v", failures, err)
+				break
+			}
+			failures = 0
+			if n < r.BatchSize {
+				break
+			}
+		}
+		if time.Since(lastCleanup) > time.Hour {
+			if n, err := r.Cleanup(ctx); err != nil {
+				logger.Errorf("outbox: cleanup failed: %v", err)
+			} else if n > 0 {
+				logger.Infof("outbox: removed %d sent events", n)
+			}
+			lastCleanup = time.Now()
+		}
+		timer.Reset(r.backoff(failures))
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// backoff 连续失败 failures 次后的轮询间隔，从 Interval 起逐次翻倍，不超过 MaxBackoff
+func (r *Relay) backoff(failures int) time.Duration {
+	d := r.Interval
+	for i := 0; i < failures && d < r.MaxBackoff; i++ {
+		d *= 2
+	}
+	if failures > 0 && d > r.MaxBackoff {
+		d = r.MaxBackoff
+	}
+	return d
+}
+
+// Flush 投递一批待发送事件，遇到失败即停止以保证顺序，返回成功投递的数量
+func (r *Relay) Flush(ctx context.Context) (int, error) {
+	session := r.db.NewSession().Context(ctx)
+	defer session.Close()
+	if err := session.Begin(); err != nil {
+		return 0, err
+	}
+	var events []Outbox
+	err := session.Where("status = ?", StatusPending).Asc("id").Limit(r.BatchSize).ForUpdate().Find(&events)
+	if err != nil {
+		_ = session.Rollback()
+		return 0, err
+	}
+	sent := 0
+	var publishErr error
+	for i := range events {
+		e := &events[i]
+		if publishErr = r.publish(ctx, e); publishErr != nil {
+			e.Attempts++
+			e.LastError = truncate(publishErr.Error(), 512)
+			if _, err = session.ID(e.Id).Cols("attempts", "last_error").Update(e); err != nil {
+				_ = session.Rollback()
+				return 0, err
+			}
+			break
+		}
+		e.Status = StatusSent
+		e.SentAt = time.Now()
+		if _, err = session.ID(e.Id).Cols("status", "sent_at").Update(e); err != nil {
+			_ = session.Rollback()
+			return 0, err
+		}
+		sent++
+	}
+	if err = session.Commit(); err != nil {
+		return 0, err
+	}
+	if publishErr != nil {
+		return sent, fmt.Errorf("publish outbox event %d: %w", events[sent].Id, publishErr)
+	}
+	return sent, nil
+}
+
+func (r *Relay) publish(ctx context.Context, e *Outbox) error {
+	headers := map[string]interface{}{}
+	if e.Headers != "" {
+		if err := json.Unmarshal([]byte(e.Headers), &headers); err != nil {
+			return err
+		}
+	}
+	headers[HeaderIdempotencyKey] = e.Key
+	return r.broker.Publish(ctx, e.Exchange, e.RoutingKey, mq.Message{
+		ID:          e.Key,
+		ContentType: e.ContentType,
+		Headers:     headers,
+		Body:        e.Payload,
+		Timestamp:   e.Created,
+	})
+}
+
+// Cleanup 分批删除超过保留时间的已发送事件，使用 DELETE ... LIMIT，仅支持 MySQL
+func (r *Relay) Cleanup(ctx context.Context) (int64, error) {
+	table := r.db.TableName(new(Outbox), true)
+	before := time.Now().Add(-r.Retention)
+	var total int64
+	for {
+		res, err := r.db.Context(ctx).Exec(
+			"DELETE FROM "+table+" WHERE status = ? AND sent_at < ? LIMIT ?", StatusSent, before, r.BatchSize)
+		if err != nil {
+			return total, err
+		}
+		n, _ := res.RowsAffected()
+		total += n
+		if n < int64(r.BatchSize) {
+			return total, nil
+		}
+	}
+}
+
+// truncate 截断为不超过 n 字节，不拆分 UTF-8 字符
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
@@ -0,0 +1,113 @@
+package tenant
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/ilooky/go-layout/pkg/config"
+	"github.com/ilooky/go-layout/pkg/database"
+	"github.com/ilooky/go-layout/pkg/mq"
+	"github.com/ilooky/logger"
+	"xorm.io/xorm"
+)
+
+// Pool 按租户懒加载并缓存数据库连接和 MQ 连接
+type Pool struct {
+	conf    *config.Config
+	mu      sync.Mutex
+	engines map[string]*xorm.Engine
+	brokers map[string]mq.Broker
+}
+
+// Default 由 Init 初始化
+var Default *Pool
+
+func NewPool(conf *config.Config) *Pool {
+	return &Pool{conf: conf, engines: map[string]*xorm.Engine{}, brokers: map[string]mq.Broker{}}
+}
+
+// Init 创建 Default 并注册为 database 的连接选择器
+func Init(conf *config.Config) *Pool {
+	Default = NewPool(conf)
+	database.SetResolver(Default.Engine)
+	return Default
+}
+
+// Engine 返回 ctx 中租户的数据库连接，ctx 中没有租户时返回 nil，使用默认连接
+func (p *Pool) Engine(ctx context.Context) (*xorm.Engine, error) {
+	t, ok := FromContext(ctx)
+	if !ok {
+		return nil, nil
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if e, ok := p.engines[t.Id]; ok {
+		return e, nil
+	}
+	c := p.conf.Mysql
+	c.Database = t.DbPrefix + strings.TrimPrefix(c.Database, p.conf.Prefix)
+	if t.TablePrefix != "" {
+		c.TablePrefix = t.TablePrefix
+	}
+	e, err := database.NewEngine(c)
+	if err != nil {
+		return nil, err
+	}
+	p.engines[t.Id] = e
+	return e, nil
+}
+
+// Broker 返回 ctx 中租户所在 vhost 的 MQ 连接，ctx 中没有租户时返回 nil
+func (p *Pool) Broker(ctx context.Context) (mq.Broker, error) {
+	t, ok := FromContext(ctx)
+	if !ok {
+		return nil, nil
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if b, ok := p.brokers[t.Id]; ok {
+		return b, nil
+	}
+	c := p.conf.Mq
+	c.VirtualHost = VirtualHost(p.conf, t)
+	b, err := mq.NewAMQP(c)
+	if err != nil {
+		return nil, err
+	}
+	p.brokers[t.Id] = b
+	return b, nil
+}
+
+// VirtualHost 租户的 MQ vhost，未配置时将全局 DB_PREFIX 替换为租户的库名前缀
+func VirtualHost(conf *config.Config, t Tenant) string {
+	if t.VirtualHost != "" {
+		return t.VirtualHost
+	}
+	return t.DbPrefix + strings.TrimPrefix(conf.Mq.VirtualHost, conf.Prefix)
+}
+
+// Key 为 Redis 键加上 ctx 中租户的前缀
+func Key(ctx context.Context, key string) string {
+	if t, ok := FromContext(ctx); ok {
+		return t.RedisPrefix + key
+	}
+	return key
+}
+
+func (p *Pool) Close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for id, e := range p.engines {
+		if err := e.Close(); err != nil {
+			logger.Errorf("tenant %s: close engine: %v", id, err)
+		}
+	}
+	for id, b := range p.brokers {
+		if err := b.Close(); err != nil {
+			logger.Errorf("tenant %s: close broker: %v", id, err)
+		}
+	}
+	p.engines = map[string]*xorm.Engine{}
+	p.brokers = map[string]mq.Broker{}
+}
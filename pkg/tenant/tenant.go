@@ -0,0 +1,115 @@
+// Package tenant 按请求解析租户，并为每个租户提供独立的数据库连接、表前缀、
+// Redis 键前缀和 MQ vhost；带 ctx 的 database 函数会自动使用当前租户的连接。
+package tenant
+
+import (
+	"context"
+	"net"
+	"regexp"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ilooky/go-layout/pkg/auth"
+	"github.com/ilooky/go-layout/pkg/config"
+	"github.com/ilooky/go-layout/pkg/errno"
+	"github.com/ilooky/go-layout/pkg/middleware"
+)
+
+// ContextKey 解析出的租户 ID 在 gin.Context 中的键
+const ContextKey = "tenant"
+
+// 租户 ID 会拼进库名和 vhost，只允许字母、数字、下划线和中划线
+var validId = regexp.MustCompile(`^[A-Za-z0-9_-]{1,32}$`)
+
+// Tenant 解析后的租户及其资源前缀
+type Tenant struct {
+	Id string
+	config.TenantConf
+}
+
+type ctxKey struct{}
+
+func WithTenant(ctx context.Context, t Tenant) context.Context {
+	return context.WithValue(ctx, ctxKey{}, t)
+}
+
+func FromContext(ctx context.Context) (Tenant, bool) {
+	t, ok := ctx.Value(ctxKey{}).(Tenant)
+	return t, ok
+}
+
+// ID 返回 ctx 中的租户 ID，没有租户时为空，可用作 auth.Policy.Scope
+func ID(ctx context.Context) string {
+	t, _ := FromContext(ctx)
+	return t.Id
+}
+
+// Lookup 校验租户 ID 并补全未配置的前缀
+func Lookup(conf config.Tenant, id string) (Tenant, error) {
+	if !validId.MatchString(id) {
+		return Tenant{}, errno.Param.Errorf("invalid tenant %q", id)
+	}
+	t, ok := conf.Tenants[id]
+	if !ok && len(conf.Tenants) > 0 {
+		return Tenant{}, errno.Forbidden.Errorf("unknown tenant %s", id)
+	}
+	if t.DbPrefix == "" {
+		t.DbPrefix = id + "_"
+	}
+	if t.RedisPrefix == "" {
+		t.RedisPrefix = id + ":"
+	}
+	return Tenant{Id: id, TenantConf: t}, nil
+}
+
+// Resolve 依次从 JWT 的 tenant 声明、请求头、子域名和默认值中解析租户 ID；
+// token 中已有租户时请求头必须与之一致。请求头和子域名不可信，未配置 Tenants 白名单时拒绝，
+// 避免调用方任意切换租户或为任意 ID 创建连接
+func Resolve(conf config.Tenant, c *gin.Context) (string, error) {
+	header := c.GetHeader(conf.Header)
+	if claims, ok := auth.FromContext(c.Request.Context()); ok && claims.Tenant != "" {
+		if header != "" && header != claims.Tenant {
+			return "", errno.Forbidden.Errorf("tenant %s does not match token", header)
+		}
+		return claims.Tenant, nil
+	}
+	id := header
+	if id == "" && conf.Subdomain {
+		host := c.Request.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if parts := strings.Split(host, "."); len(parts) > 2 && net.ParseIP(host) == nil {
+			id = parts[0]
+		}
+	}
+	if id != "" {
+		if len(conf.Tenants) == 0 {
+			return "", errno.Forbidden.Errorf("tenant %s is not in token and no tenant allow-list is configured", id)
+		}
+		return id, nil
+	}
+	if conf.Default != "" {
+		return conf.Default, nil
+	}
+	return "", errno.Param.Errorf("missing tenant")
+}
+
+// Middleware 解析租户并写入 gin.Context 和 request context，需放在认证中间件之后
+func Middleware(conf config.Tenant) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := Resolve(conf, c)
+		if err != nil {
+			middleware.Abort(c, err)
+			return
+		}
+		t, err := Lookup(conf, id)
+		if err != nil {
+			middleware.Abort(c, err)
+			return
+		}
+		c.Set(ContextKey, t.Id)
+		c.Request = c.Request.WithContext(WithTenant(c.Request.Context(), t))
+		c.Next()
+	}
+}
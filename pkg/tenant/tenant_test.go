@@ -0,0 +1,98 @@
+package tenant
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ilooky/go-layout/pkg/auth"
+	"github.com/ilooky/go-layout/pkg/config"
+)
+
+func serve(conf config.Tenant, host, header, claim string) (*httptest.ResponseRecorder, string) {
+	gin.SetMode(gin.TestMode)
+	h := gin.New()
+	if claim != "" {
+		h.Use(func(c *gin.Context) {
+			ctx := auth.WithClaims(c.Request.Context(), &auth.Claims{Tenant: claim})
+			c.Request = c.Request.WithContext(ctx)
+		})
+	}
+	h.Use(Middleware(conf))
+	var got string
+	h.GET("/", func(c *gin.Context) {
+		t, _ := FromContext(c.Request.Context())
+		got = t.Id
+	})
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Host = host
+	if header != "" {
+		r.Header.Set(conf.Header, header)
+	}
+	h.ServeHTTP(w, r)
+	return w, got
+}
+
+func TestMiddleware(t *testing.T) {
+	conf := config.Tenant{Header: "X-Tenant-Id", Subdomain: true,
+		Tenants: map[string]config.TenantConf{"t1": {}, "t2": {}, "t3": {}}}
+	tests := []struct {
+		name, host, header, claim string
+		status                    int
+		tenant                    string
+	}{
+		{"header", "localhost:8080", "t1", "", http.StatusOK, "t1"},
+		{"claim", "localhost", "", "t2", http.StatusOK, "t2"},
+		{"claim and header", "localhost", "t2", "t2", http.StatusOK, "t2"},
+		{"header mismatch", "localhost", "t1", "t2", http.StatusForbidden, ""},
+		{"subdomain", "t3.example.com:443", "", "", http.StatusOK, "t3"},
+		{"ip host", "10.0.0.1:80", "", "", http.StatusBadRequest, ""},
+		{"invalid id", "localhost", "t1;drop", "", http.StatusBadRequest, ""},
+	}
+	for _, tt := range tests {
+		w, got := serve(conf, tt.host, tt.header, tt.claim)
+		if w.Code != tt.status || got != tt.tenant {
+			t.Errorf("%s: status %d tenant %q, want %d %q", tt.name, w.Code, got, tt.status, tt.tenant)
+		}
+	}
+	open := config.Tenant{Header: "X-Tenant-Id", Subdomain: true}
+	if w, _ := serve(open, "localhost", "t1", ""); w.Code != http.StatusForbidden {
+		t.Errorf("header without allow-list: %d", w.Code)
+	}
+	if w, _ := serve(open, "t3.example.com", "", ""); w.Code != http.StatusForbidden {
+		t.Errorf("subdomain without allow-list: %d", w.Code)
+	}
+	if _, got := serve(open, "localhost", "", "t9"); got != "t9" {
+		t.Errorf("claim without allow-list = %q", got)
+	}
+	conf.Default = "public"
+	conf.Tenants = map[string]config.TenantConf{"public": {}}
+	if _, got := serve(conf, "localhost", "", ""); got != "public" {
+		t.Errorf("default tenant = %q", got)
+	}
+	if w, _ := serve(conf, "localhost", "t1", ""); w.Code != http.StatusForbidden {
+		t.Errorf("unknown tenant: %d", w.Code)
+	}
+}
+
+func TestPrefixes(t *testing.T) {
+	conf := &config.Config{Prefix: "dev_", Mq: config.Mq{VirtualHost: "dev_us"}}
+	tenants := config.Tenant{Tenants: map[string]config.TenantConf{"t1": {}, "t2": {VirtualHost: "shared"}}}
+	t1, _ := Lookup(tenants, "t1")
+	if t1.DbPrefix != "t1_" || t1.TablePrefix != "" || VirtualHost(conf, t1) != "t1_us" {
+		t.Errorf("t1 = %+v, vhost %s", t1, VirtualHost(conf, t1))
+	}
+	t2, _ := Lookup(tenants, "t2")
+	if VirtualHost(conf, t2) != "shared" {
+		t.Errorf("t2 vhost = %s", VirtualHost(conf, t2))
+	}
+	if k := Key(WithTenant(context.Background(), t1), "lock"); k != "t1:lock" {
+		t.Errorf("key = %s", k)
+	}
+	if k := Key(context.Background(), "lock"); k != "lock" {
+		t.Errorf("key without tenant = %s", k)
+	}
+}